@@ -0,0 +1,212 @@
+// Package emulatortest embeds the emulator stack in go test.
+//
+// Start runs the stack in-process (see package native), waits until every
+// service is ready, dials gRPC connections, and registers cleanup with
+// t.Cleanup. No Docker or fixed ports are needed, so tests can run in
+// parallel.
+//
+//	func TestAccess(t *testing.T) {
+//		stack := emulatortest.Start(t, emulatortest.Options{PolicyFile: "../policy.yaml"})
+//
+//		ctx := emulatortest.WithPrincipal(context.Background(), "user:alice@example.com")
+//		_, err := stack.SecretManager().GetSecret(ctx, &secretmanagerpb.GetSecretRequest{
+//			Name: "projects/test-project/secrets/db-password",
+//		})
+//		emulatortest.RequireDenied(t, err)
+//	}
+package emulatortest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	iampb "cloud.google.com/go/iam/apiv1/iampb"
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/emulator/authz"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/health"
+	"github.com/blackwell-systems/gcp-iam-control-plane/native"
+)
+
+// Options configures the embedded stack
+type Options struct {
+	// PolicyFile is the policy to enforce (default "policy.yaml")
+	PolicyFile string
+	// IAMMode is off, permissive or strict (default strict, like CI)
+	IAMMode string
+	// ReadyTimeout bounds readiness waiting (default 10s)
+	ReadyTimeout time.Duration
+}
+
+// Endpoints are the host:port addresses of the running services
+type Endpoints = native.Endpoints
+
+// Stack is a running stack bound to a test
+type Stack struct {
+	Endpoints Endpoints
+
+	// Pre-dialed connections; closed automatically when the test ends
+	IAMConn           *grpc.ClientConn
+	SecretManagerConn *grpc.ClientConn
+	KMSConn           *grpc.ClientConn
+
+	native *native.Stack
+}
+
+// Start starts the stack and blocks until it is ready. It fails the test
+// if the stack cannot start. Everything is torn down via t.Cleanup.
+func Start(t testing.TB, opts Options) *Stack {
+	t.Helper()
+
+	if opts.PolicyFile == "" {
+		opts.PolicyFile = "policy.yaml"
+	}
+	if opts.IAMMode == "" {
+		opts.IAMMode = authz.ModeStrict
+	}
+	if opts.ReadyTimeout == 0 {
+		opts.ReadyTimeout = 10 * time.Second
+	}
+
+	ns, err := native.Start(native.Options{
+		PolicyFile: opts.PolicyFile,
+		IAMMode:    opts.IAMMode,
+	})
+	if err != nil {
+		t.Fatalf("emulatortest: failed to start stack: %v", err)
+	}
+	t.Cleanup(ns.Stop)
+
+	s := &Stack{
+		Endpoints: ns.Endpoints(),
+		native:    ns,
+	}
+	s.IAMConn = dial(t, s.Endpoints.IAM)
+	s.SecretManagerConn = dial(t, s.Endpoints.SecretManager)
+	s.KMSConn = dial(t, s.Endpoints.KMS)
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.ReadyTimeout)
+	defer cancel()
+
+	if err := s.waitReady(ctx); err != nil {
+		t.Fatalf("emulatortest: %v", err)
+	}
+
+	return s
+}
+
+func (s *Stack) waitReady(ctx context.Context) error {
+	checks := []struct {
+		name      string
+		healthURL string
+		conn      *grpc.ClientConn
+	}{
+		{"iam", "http://" + s.Endpoints.IAMHealth + "/health", s.IAMConn},
+		{"secret-manager", "http://" + s.Endpoints.SecretManagerHTTP + "/health", s.SecretManagerConn},
+		{"kms", "http://" + s.Endpoints.KMSHTTP + "/health", s.KMSConn},
+	}
+
+	for _, check := range checks {
+		if err := health.Wait(ctx, health.Target{Name: check.name, HealthURL: check.healthURL}, 50*time.Millisecond); err != nil {
+			return err
+		}
+		if err := health.WaitGRPC(ctx, check.conn); err != nil {
+			return fmt.Errorf("%s not ready: %w", check.name, err)
+		}
+	}
+
+	return nil
+}
+
+// IAM returns an IAMPolicy client on the pre-dialed connection
+func (s *Stack) IAM() iampb.IAMPolicyClient {
+	return iampb.NewIAMPolicyClient(s.IAMConn)
+}
+
+// SecretManager returns a Secret Manager client on the pre-dialed connection
+func (s *Stack) SecretManager() secretmanagerpb.SecretManagerServiceClient {
+	return secretmanagerpb.NewSecretManagerServiceClient(s.SecretManagerConn)
+}
+
+// KMS returns a KMS client on the pre-dialed connection
+func (s *Stack) KMS() kmspb.KeyManagementServiceClient {
+	return kmspb.NewKeyManagementServiceClient(s.KMSConn)
+}
+
+// ReloadPolicy re-reads the policy file, e.g. after a test rewrites it
+func (s *Stack) ReloadPolicy(t testing.TB) {
+	t.Helper()
+
+	if err := s.native.ReloadPolicy(); err != nil {
+		t.Fatalf("emulatortest: failed to reload policy: %v", err)
+	}
+}
+
+// DialAs opens a connection to addr on which every call carries the
+// principal. It is closed when the test ends.
+func DialAs(t testing.TB, addr, principal string) *grpc.ClientConn {
+	t.Helper()
+
+	return dial(t, addr,
+		grpc.WithUnaryInterceptor(func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			return invoker(WithPrincipal(ctx, principal), method, req, reply, cc, opts...)
+		}),
+		grpc.WithStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return streamer(WithPrincipal(ctx, principal), desc, cc, method, opts...)
+		}),
+	)
+}
+
+// WithPrincipal returns a context whose outgoing calls carry the principal
+// (x-emulator-principal metadata)
+func WithPrincipal(ctx context.Context, principal string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, authz.PrincipalMetadataKey, principal)
+}
+
+// WithDelegates returns a context whose outgoing calls carry an
+// impersonation chain (x-emulator-delegates metadata): the principal
+// impersonates each service account in turn and the call is made as the
+// last one
+func WithDelegates(ctx context.Context, delegates ...string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, authz.DelegatesMetadataKey, strings.Join(delegates, ","))
+}
+
+// RequireAllowed fails the test unless err is nil
+func RequireAllowed(t testing.TB, err error) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatalf("expected request to be allowed, got: %v", err)
+	}
+}
+
+// RequireDenied fails the test unless err is a PermissionDenied status
+func RequireDenied(t testing.TB, err error) {
+	t.Helper()
+
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected PermissionDenied, got: %v", err)
+	}
+}
+
+func dial(t testing.TB, addr string, opts ...grpc.DialOption) *grpc.ClientConn {
+	t.Helper()
+
+	opts = append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, opts...)
+	conn, err := grpc.NewClient(addr, opts...)
+	if err != nil {
+		t.Fatalf("emulatortest: failed to dial %s: %v", addr, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
@@ -0,0 +1,76 @@
+package emulatortest
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStart(t *testing.T) {
+	stack := Start(t, Options{PolicyFile: "../policy.yaml"})
+
+	ctx := WithPrincipal(context.Background(), "user:admin@example.com")
+	_, err := stack.SecretManager().CreateSecret(ctx, &secretmanagerpb.CreateSecretRequest{
+		Parent:   "projects/test-project",
+		SecretId: "db-password",
+		Secret:   &secretmanagerpb.Secret{},
+	})
+	RequireAllowed(t, err)
+
+	ctx = WithPrincipal(context.Background(), "user:mallory@example.com")
+	_, err = stack.SecretManager().GetSecret(ctx, &secretmanagerpb.GetSecretRequest{
+		Name: "projects/test-project/secrets/db-password",
+	})
+	RequireDenied(t, err)
+}
+
+func TestDialAs(t *testing.T) {
+	stack := Start(t, Options{PolicyFile: "../policy.yaml"})
+
+	conn := DialAs(t, stack.Endpoints.SecretManager, "user:admin@example.com")
+	client := secretmanagerpb.NewSecretManagerServiceClient(conn)
+
+	_, err := client.ListSecrets(context.Background(), &secretmanagerpb.ListSecretsRequest{
+		Parent: "projects/test-project",
+	})
+	RequireAllowed(t, err)
+}
+
+func TestStacksAreIsolated(t *testing.T) {
+	t.Parallel()
+
+	a := Start(t, Options{PolicyFile: "../policy.yaml"})
+	b := Start(t, Options{PolicyFile: "../policy.yaml"})
+
+	if a.Endpoints.SecretManager == b.Endpoints.SecretManager {
+		t.Errorf("Expected distinct endpoints, both got %s", a.Endpoints.SecretManager)
+	}
+}
+
+func TestWithDelegates(t *testing.T) {
+	stack := Start(t, Options{PolicyFile: "../testdata/impersonation.yaml"})
+
+	get := func(ctx context.Context) error {
+		_, err := stack.SecretManager().GetSecret(ctx, &secretmanagerpb.GetSecretRequest{
+			Name: "projects/test-project/secrets/missing",
+		})
+		if status.Code(err) == codes.NotFound {
+			return nil
+		}
+		return err
+	}
+
+	ci := WithPrincipal(context.Background(), "serviceAccount:ci@test-project.iam.gserviceaccount.com")
+	RequireDenied(t, get(ci))
+	RequireAllowed(t, get(WithDelegates(ci, "deployer@test-project.iam.gserviceaccount.com")))
+
+	alice := WithPrincipal(context.Background(), "user:alice@example.com")
+	RequireDenied(t, get(WithDelegates(alice, "deployer@test-project.iam.gserviceaccount.com")))
+	RequireAllowed(t, get(WithDelegates(alice,
+		"builder@test-project.iam.gserviceaccount.com",
+		"deployer@test-project.iam.gserviceaccount.com",
+	)))
+}
@@ -0,0 +1,447 @@
+package audit
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+func loadPolicy(t *testing.T) *policy.Policy {
+	t.Helper()
+
+	pol, err := policy.Load("../../testdata/policy.yaml")
+	if err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	return pol
+}
+
+func TestFromDecision(t *testing.T) {
+	pol := loadPolicy(t)
+
+	allowed := FromDecision(policy.Evaluate(pol, policy.Request{
+		Principal:  "user:alice@example.com",
+		Resource:   "projects/test-project/secrets/db-password",
+		Permission: "secretmanager.secrets.get",
+	}), "secret-manager", 2*time.Millisecond)
+
+	if !allowed.Allowed() {
+		t.Fatalf("Expected ALLOW, got %s (%s)", allowed.Decision.Outcome, allowed.Decision.Reason)
+	}
+	if allowed.Decision.Binding == nil || allowed.Decision.Binding.Project != "test-project" {
+		t.Errorf("Expected granting binding in test-project, got %+v", allowed.Decision.Binding)
+	}
+	if allowed.Environment.Caller != "secret-manager" {
+		t.Errorf("Caller = %q, want secret-manager", allowed.Environment.Caller)
+	}
+	if allowed.Decision.LatencyMS != 2 {
+		t.Errorf("LatencyMS = %v, want 2", allowed.Decision.LatencyMS)
+	}
+
+	// Denied by a condition: the binding is reported with its condition result
+	denied := FromDecision(policy.Evaluate(pol, policy.Request{
+		Principal:  "serviceAccount:ci@test-project.iam.gserviceaccount.com",
+		Resource:   "projects/test-project/secrets/dev-api-key",
+		Permission: "secretmanager.versions.access",
+	}), "", 0)
+
+	if denied.Allowed() {
+		t.Fatal("Expected DENY")
+	}
+	if denied.Decision.Binding == nil || denied.Decision.Binding.Condition == "" {
+		t.Fatalf("Expected conditional binding, got %+v", denied.Decision.Binding)
+	}
+	if denied.Decision.ConditionResult == nil || *denied.Decision.ConditionResult {
+		t.Errorf("Expected condition result false, got %v", denied.Decision.ConditionResult)
+	}
+
+	// Denied by a deny policy: the rule is reported
+	denyPol, err := policy.Load("../../testdata/deny.yaml")
+	if err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	guarded := FromDecision(policy.Evaluate(denyPol, policy.Request{
+		Principal:  "user:alice@example.com",
+		Resource:   "projects/test-project/secrets/db-password/versions/1",
+		Permission: "secretmanager.versions.destroy",
+	}), "", 0)
+
+	if guarded.Allowed() || guarded.Decision.DenyRule != "protect-secret-versions[0]" {
+		t.Errorf("Expected DENY by protect-secret-versions[0], got %s %q", guarded.Decision.Outcome, guarded.Decision.DenyRule)
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "audit.jsonl")
+
+	w, err := NewWriter(path)
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+
+	pol := loadPolicy(t)
+	for _, principal := range []string{"user:alice@example.com", "user:mallory@example.com"} {
+		d := policy.Evaluate(pol, policy.Request{
+			Principal:  principal,
+			Resource:   "projects/test-project/secrets/db-password",
+			Permission: "secretmanager.secrets.get",
+		})
+		if err := w.Write(FromDecision(d, "secret-manager", time.Millisecond)); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	events, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	if events[1].Actor.Principal != "user:mallory@example.com" || events[1].Allowed() {
+		t.Errorf("Unexpected second event: %+v", events[1])
+	}
+}
+
+func TestReadTraceSchema(t *testing.T) {
+	// Event as written by the containerized IAM emulator (README trace schema)
+	input := `{"schema_version":"1.0","event_type":"authz_check","timestamp":"2026-01-28T10:15:23.483Z","actor":{"principal":"user:alice@example.com"},"target":{"resource":"projects/test/secrets/db-password"},"action":{"permission":"secretmanager.secrets.get"},"decision":{"outcome":"ALLOW","reason":"binding_match","evaluated_by":"gcp-iam-emulator","latency_ms":3}}
+
+{"schema_version":"1.0","event_type":"server_start"}
+`
+	events, err := Read(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	if events[0].Decision.LatencyMS != 3 || !events[0].Allowed() {
+		t.Errorf("Unexpected event: %+v", events[0])
+	}
+
+	if _, err := Read(bytes.NewBufferString("{not json\n")); err == nil {
+		t.Error("Expected error for malformed line")
+	}
+}
+
+func event(principal, permission, outcome string, at time.Time) Event {
+	return Event{
+		EventType: EventType,
+		Timestamp: at,
+		Actor:     Actor{Principal: principal},
+		Target:    Target{Resource: "projects/p/secrets/s"},
+		Action:    Action{Permission: permission},
+		Decision:  Decision{Outcome: outcome},
+	}
+}
+
+func TestQueryAndAggregate(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	events := []Event{
+		event("user:alice@example.com", "secretmanager.secrets.get", OutcomeAllow, now.Add(-time.Hour)),
+		event("user:bob@example.com", "secretmanager.versions.access", OutcomeDeny, now.Add(-5*time.Minute)),
+		event("user:bob@example.com", "secretmanager.versions.access", OutcomeDeny, now.Add(-4*time.Minute)),
+		event("user:carol@example.com", "cloudkms.cryptoKeys.encrypt", OutcomeDeny, now.Add(-3*time.Minute)),
+	}
+
+	since, err := ParseSince("10m", now)
+	if err != nil {
+		t.Fatalf("ParseSince() error = %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		query Query
+		want  int
+	}{
+		{"all", Query{}, 4},
+		{"since", Query{Since: since}, 3},
+		{"decision case-insensitive", Query{Decision: "deny"}, 3},
+		{"principal", Query{Principal: "user:bob@example.com"}, 2},
+		{"permission glob", Query{Permission: "secretmanager.*"}, 3},
+		{"resource prefix", Query{Resource: "projects/p/"}, 4},
+		{"combined", Query{Since: since, Decision: "DENY", Permission: "cloudkms.*"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := len(Filter(events, tt.query)); got != tt.want {
+				t.Errorf("Filter() matched %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	top, err := TopDenied(events, ByPermission, 1)
+	if err != nil {
+		t.Fatalf("TopDenied() error = %v", err)
+	}
+	if len(top) != 1 || top[0].Key != "secretmanager.versions.access" || top[0].Count != 2 {
+		t.Errorf("TopDenied() = %+v", top)
+	}
+
+	if _, err := CountBy(events, "bogus"); err == nil {
+		t.Error("Expected error for invalid group-by field")
+	}
+	if _, err := ParseSince("yesterday", now); err == nil {
+		t.Error("Expected error for invalid --since")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	events := []Event{
+		event("user:alice@example.com", "secretmanager.secrets.get", OutcomeAllow, at),
+		event("user:alice@example.com", "secretmanager.versions.access", OutcomeAllow, at),
+		event("user:alice@example.com", "secretmanager.secrets.get", OutcomeAllow, at),
+		event("user:bob@example.com", "secretmanager.secrets.delete", OutcomeDeny, at),
+		event("", "secretmanager.secrets.get", OutcomeAllow, at),
+	}
+	other := event("user:alice@example.com", "cloudkms.cryptoKeys.encrypt", OutcomeAllow, at)
+	other.Target.Resource = "projects/other/locations/global/keyRings/r/cryptoKeys/k"
+	events = append(events, other)
+
+	result := Generate(events, GenerateOptions{})
+
+	if result.Used != 4 || result.NoPrincipal != 1 {
+		t.Errorf("Used = %d, NoPrincipal = %d; want 4, 1", result.Used, result.NoPrincipal)
+	}
+
+	pol := result.Policy
+	if len(pol.Projects) != 2 {
+		t.Fatalf("got %d projects, want 2", len(pol.Projects))
+	}
+
+	role := GeneratedRoleName("p", "user:alice@example.com")
+	if role != "roles/custom.p.user.alice_example_com" {
+		t.Errorf("GeneratedRoleName() = %s", role)
+	}
+	want := []string{"secretmanager.secrets.get", "secretmanager.versions.access"}
+	if got := pol.Roles[role].Permissions; !reflect.DeepEqual(got, want) {
+		t.Errorf("role permissions = %v, want %v", got, want)
+	}
+
+	// The generated policy grants exactly what was used, per project
+	check := func(principal, resource, permission string) bool {
+		return policy.Evaluate(pol, policy.Request{Principal: principal, Resource: resource, Permission: permission}).Allowed
+	}
+	if !check("user:alice@example.com", "projects/p/secrets/s", "secretmanager.versions.access") {
+		t.Error("Expected used permission to be allowed")
+	}
+	if check("user:alice@example.com", "projects/p/keyRings/r", "cloudkms.cryptoKeys.encrypt") {
+		t.Error("Expected permission used in another project to be denied here")
+	}
+	if check("user:bob@example.com", "projects/p/secrets/s", "secretmanager.secrets.delete") {
+		t.Error("Expected denied check to stay denied")
+	}
+
+	if !policy.Validate(pol).Valid {
+		t.Errorf("Generated policy is invalid: %v", policy.Validate(pol).Errors)
+	}
+
+	withDenied := Generate(events, GenerateOptions{IncludeDenied: true})
+	if _, ok := withDenied.Policy.Roles[GeneratedRoleName("p", "user:bob@example.com")]; !ok {
+		t.Error("Expected role for denied principal with IncludeDenied")
+	}
+}
+
+func TestGenerateDelegated(t *testing.T) {
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	e := event("serviceAccount:ci@p.iam.gserviceaccount.com", "secretmanager.versions.access", OutcomeAllow, at)
+	e.Actor.Delegates = []string{"hop@p.iam.gserviceaccount.com", "serviceAccount:deployer@p.iam.gserviceaccount.com"}
+
+	pol := Generate([]Event{e}, GenerateOptions{}).Policy
+	if !policy.Validate(pol).Valid {
+		t.Fatalf("Generated policy is invalid: %v", policy.Validate(pol).Errors)
+	}
+
+	if _, ok := pol.Roles[GeneratedRoleName("p", "serviceAccount:ci@p.iam.gserviceaccount.com")]; ok {
+		t.Error("Expected no direct grant to the original caller")
+	}
+	if _, ok := pol.Roles[GeneratedRoleName("p", "serviceAccount:deployer@p.iam.gserviceaccount.com")]; !ok {
+		t.Error("Expected the permission granted to the last delegate")
+	}
+	hop := pol.Projects["p"].ServiceAccounts["hop@p.iam.gserviceaccount.com"].Bindings
+	if len(hop) != 1 || hop[0].Role != "roles/iam.serviceAccountTokenCreator" || hop[0].Members[0] != "serviceAccount:ci@p.iam.gserviceaccount.com" {
+		t.Errorf("hop bindings = %+v, want token creator for the caller", hop)
+	}
+
+	// The chain the event was recorded from is allowed when replayed
+	decision := policy.Evaluate(pol, policy.Request{
+		Principal:  e.Actor.Principal,
+		Resource:   e.Target.Resource,
+		Permission: e.Action.Permission,
+		Delegates:  e.Actor.Delegates,
+	})
+	if !decision.Allowed {
+		t.Errorf("replayed chain denied: %s", decision.Reason)
+	}
+	if policy.Evaluate(pol, policy.Request{Principal: e.Actor.Principal, Resource: e.Target.Resource, Permission: e.Action.Permission}).Allowed {
+		t.Error("Expected the caller to be denied without impersonation")
+	}
+}
+
+func TestAnalyzeUsage(t *testing.T) {
+	pol := &policy.Policy{
+		Roles: map[string]policy.Role{
+			"roles/custom.reader": {Permissions: []string{"secretmanager.secrets.get", "secretmanager.versions.access"}},
+			"roles/custom.unused": {Permissions: []string{"secretmanager.secrets.delete"}},
+		},
+		Groups: map[string]policy.Group{},
+		Projects: map[string]policy.Project{
+			"p": {Bindings: []policy.Binding{
+				{Role: "roles/custom.reader", Members: []string{"user:alice@example.com", "user:bob@example.com"}},
+				{Role: "roles/secretmanager.admin", Members: []string{"user:carol@example.com"}},
+			}},
+		},
+	}
+
+	first := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	events := []Event{
+		event("user:alice@example.com", "secretmanager.secrets.get", OutcomeAllow, first),
+		event("user:alice@example.com", "secretmanager.secrets.get", OutcomeAllow, first.Add(time.Hour)),
+		event("user:bob@example.com", "secretmanager.secrets.delete", OutcomeDeny, first.Add(2*time.Hour)),
+	}
+
+	report := AnalyzeUsage(pol, events)
+
+	if report.Events != 2 || !report.From.Equal(first) || !report.To.Equal(first.Add(time.Hour)) {
+		t.Errorf("window = %d events %v..%v", report.Events, report.From, report.To)
+	}
+
+	if len(report.Bindings) != 2 {
+		t.Fatalf("got %d bindings, want 2", len(report.Bindings))
+	}
+	if b := report.Bindings[0]; b.Grants != 2 || !reflect.DeepEqual(b.UnusedMembers, []string{"user:bob@example.com"}) {
+		t.Errorf("reader binding = %+v", b)
+	}
+	if unused := report.UnusedBindings(); len(unused) != 1 || unused[0].Role != "roles/secretmanager.admin" {
+		t.Errorf("UnusedBindings() = %+v", unused)
+	}
+
+	roles := make(map[string]RoleUsage)
+	for _, r := range report.Roles {
+		roles[r.Role] = r
+	}
+	reader := roles["roles/custom.reader"]
+	if !reflect.DeepEqual(reader.UsedPermissions, []string{"secretmanager.secrets.get"}) ||
+		!reflect.DeepEqual(reader.UnusedPermissions, []string{"secretmanager.versions.access"}) {
+		t.Errorf("reader role = %+v", reader)
+	}
+	if r := roles["roles/custom.unused"]; r.Bound || r.Used() {
+		t.Errorf("unused role = %+v", r)
+	}
+	if r := roles["roles/secretmanager.admin"]; r.Custom || !r.Bound || r.Used() {
+		t.Errorf("admin role = %+v", r)
+	}
+
+	trimmed := report.Trim(pol)
+	want := &policy.Policy{
+		Roles: map[string]policy.Role{
+			"roles/custom.reader": {Permissions: []string{"secretmanager.secrets.get"}},
+		},
+		Groups: map[string]policy.Group{},
+		Projects: map[string]policy.Project{
+			"p": {Bindings: []policy.Binding{
+				{Role: "roles/custom.reader", Members: []string{"user:alice@example.com"}},
+			}},
+		},
+	}
+	if !reflect.DeepEqual(trimmed, want) {
+		t.Errorf("Trim() = %+v, want %+v", trimmed, want)
+	}
+
+	// Trimming must not change the original policy
+	if len(pol.Projects["p"].Bindings[0].Members) != 2 || len(pol.Roles) != 2 {
+		t.Error("Trim() modified the original policy")
+	}
+
+	// Everything still used keeps working under the trimmed policy
+	for _, e := range events {
+		if !e.Allowed() {
+			continue
+		}
+		req := policy.Request{Principal: e.Actor.Principal, Resource: e.Target.Resource, Permission: e.Action.Permission}
+		if !policy.Evaluate(trimmed, req).Allowed {
+			t.Errorf("trimmed policy denies used check %+v", req)
+		}
+	}
+}
+
+func TestTrimKeepsProjectsGrantedByAncestors(t *testing.T) {
+	pol := &policy.Policy{
+		Roles:         map[string]policy.Role{},
+		Groups:        map[string]policy.Group{},
+		Organizations: map[string]policy.Organization{"123": {}},
+		Folders: map[string]policy.Folder{
+			"eng": {Parent: "organizations/123", Bindings: []policy.Binding{
+				{Role: "roles/secretmanager.secretAccessor", Members: []string{"user:alice@example.com"}},
+			}},
+		},
+		Projects: map[string]policy.Project{
+			"p": {Parent: "folders/eng", Etag: "BwYRbPL7uMc=", Bindings: []policy.Binding{
+				{Role: "roles/secretmanager.admin", Members: []string{"user:bob@example.com"}},
+			}},
+		},
+		DenyPolicies: map[string]policy.DenyPolicy{
+			"no-delete": {AttachmentPoint: "projects/p", Rules: []policy.DenyRule{{
+				DeniedPrincipals:  []string{"user:alice@example.com"},
+				DeniedPermissions: []string{"secretmanager.secrets.delete"},
+			}}},
+		},
+	}
+	events := []Event{event("user:alice@example.com", "secretmanager.versions.access", OutcomeAllow, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))}
+
+	trimmed := AnalyzeUsage(pol, events).Trim(pol)
+	if p, ok := trimmed.Projects["p"]; !ok || p.Parent != "folders/eng" || p.Etag != "BwYRbPL7uMc=" || len(p.Bindings) != 0 {
+		t.Errorf("project p = %+v, %v; want kept under folders/eng without its unused binding", p, ok)
+	}
+	if result := policy.Validate(trimmed); !result.Valid {
+		t.Errorf("trimmed policy is invalid: %v", result.Errors)
+	}
+	req := policy.Request{Principal: "user:alice@example.com", Resource: "projects/p/secrets/s", Permission: "secretmanager.versions.access"}
+	if decision := policy.Evaluate(trimmed, req); !decision.Allowed {
+		t.Errorf("trimmed policy denies the folder grant that was used: %s", decision.Reason)
+	}
+}
+
+func TestTrimKeepsIdentitiesAndEtag(t *testing.T) {
+	deployer := "principal://iam.googleapis.com/projects/1/locations/global/workloadIdentityPools/github/subject/repo:acme/api"
+	pol := &policy.Policy{
+		Roles:  map[string]policy.Role{},
+		Groups: map[string]policy.Group{},
+		Projects: map[string]policy.Project{
+			"p": {
+				Etag:    "BwYRbPL7uMc=",
+				Version: 3,
+				Bindings: []policy.Binding{{
+					Role:    "roles/secretmanager.secretAccessor",
+					Members: []string{"principalSet://iam.googleapis.com/projects/1/locations/global/workloadIdentityPools/github/group/deployers"},
+				}},
+			},
+		},
+		WorkloadIdentities: map[string]policy.WorkloadIdentity{
+			deployer: {Attributes: map[string]string{"repository": "acme/api"}, Groups: []string{"deployers"}},
+		},
+	}
+	events := []Event{event(deployer, "secretmanager.versions.access", OutcomeAllow, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))}
+
+	trimmed := AnalyzeUsage(pol, events).Trim(pol)
+	if !reflect.DeepEqual(trimmed.WorkloadIdentities, pol.WorkloadIdentities) {
+		t.Errorf("WorkloadIdentities = %+v, want %+v", trimmed.WorkloadIdentities, pol.WorkloadIdentities)
+	}
+	if p := trimmed.Projects["p"]; p.Etag != "BwYRbPL7uMc=" || p.Version != 3 {
+		t.Errorf("etag, version = %q, %d", p.Etag, p.Version)
+	}
+	req := policy.Request{Principal: deployer, Resource: "projects/p/secrets/s", Permission: "secretmanager.versions.access"}
+	if !policy.Evaluate(trimmed, req).Allowed {
+		t.Error("trimmed policy denies the principalSet grant that was used")
+	}
+}
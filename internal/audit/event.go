@@ -0,0 +1,166 @@
+// Package audit records IAM authorization decisions in a local JSONL store
+// and answers queries over it.
+//
+// Events follow the authorization trace schema v1.0 documented in the
+// README (event_type authz_check, actor/target/action/decision), extended
+// with the matching binding, the condition result and the calling service.
+// Trace files written by the containerized IAM emulator (IAM_TRACE_OUTPUT)
+// can therefore be read and queried the same way.
+package audit
+
+import (
+	"time"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+// SchemaVersion is the trace schema version written by this package
+const SchemaVersion = "1.0"
+
+// EventType identifies authorization decision events
+const EventType = "authz_check"
+
+// Component identifies the control plane as the evaluator
+const Component = "gcp-iam-emulator"
+
+// Decision outcomes
+const (
+	OutcomeAllow = "ALLOW"
+	OutcomeDeny  = "DENY"
+)
+
+// Event is one authorization decision
+type Event struct {
+	SchemaVersion string      `json:"schema_version"`
+	EventType     string      `json:"event_type"`
+	Timestamp     time.Time   `json:"timestamp"`
+	Actor         Actor       `json:"actor"`
+	Target        Target      `json:"target"`
+	Action        Action      `json:"action"`
+	Decision      Decision    `json:"decision"`
+	Environment   Environment `json:"environment"`
+}
+
+// Actor is who made the request
+type Actor struct {
+	Principal string `json:"principal"`
+	// Delegates is the impersonation chain the principal acted through
+	Delegates []string `json:"delegates,omitempty"`
+}
+
+// Target is the resource the request was made against
+type Target struct {
+	Resource string `json:"resource"`
+}
+
+// Action is the permission that was checked
+type Action struct {
+	Permission string `json:"permission"`
+}
+
+// Decision is the outcome of the check
+type Decision struct {
+	Outcome     string  `json:"outcome"`
+	Reason      string  `json:"reason"`
+	EvaluatedBy string  `json:"evaluated_by"`
+	LatencyMS   float64 `json:"latency_ms"`
+	// Binding is the binding that granted access, or for a denial the
+	// binding that would have granted it had its condition held
+	Binding *Binding `json:"binding,omitempty"`
+	// ConditionResult is the result of Binding's condition (nil if none)
+	ConditionResult *bool `json:"condition_result,omitempty"`
+	// DenyRule identifies the deny rule that denied the request as
+	// policy[index]
+	DenyRule string `json:"deny_rule,omitempty"`
+}
+
+// Binding identifies a policy binding
+type Binding struct {
+	Project string `json:"project"`
+	// Resource is set for bindings on a resource below the project
+	Resource  string `json:"resource,omitempty"`
+	Index     int    `json:"index"`
+	Role      string `json:"role"`
+	Member    string `json:"member,omitempty"`
+	Condition string `json:"condition,omitempty"`
+}
+
+// Label identifies the binding as project[index] or resource[index]
+func (b Binding) Label() string {
+	return policy.BindingLabel(b.Project, b.Resource, b.Index)
+}
+
+// Environment describes where the check happened
+type Environment struct {
+	Component string `json:"component"`
+	// Caller is the service that asked IAM (e.g. secret-manager)
+	Caller string `json:"caller,omitempty"`
+}
+
+// Allowed reports whether the event is an ALLOW decision
+func (e Event) Allowed() bool {
+	return e.Decision.Outcome == OutcomeAllow
+}
+
+// FromDecision builds an event from a policy decision
+func FromDecision(d *policy.Decision, caller string, latency time.Duration) Event {
+	e := Event{
+		SchemaVersion: SchemaVersion,
+		EventType:     EventType,
+		Timestamp:     d.Request.Time.UTC(),
+		Actor:         Actor{Principal: d.Request.Principal, Delegates: d.Request.Delegates},
+		Target:        Target{Resource: d.Request.Resource},
+		Action:        Action{Permission: d.Request.Permission},
+		Decision: Decision{
+			Outcome:     OutcomeDeny,
+			Reason:      d.Reason,
+			EvaluatedBy: Component,
+			LatencyMS:   float64(latency) / float64(time.Millisecond),
+		},
+		Environment: Environment{
+			Component: Component,
+			Caller:    caller,
+		},
+	}
+
+	if d.Allowed {
+		e.Decision.Outcome = OutcomeAllow
+	}
+	if d.Denial != nil {
+		e.Decision.DenyRule = d.Denial.Label()
+	}
+
+	if match := matchingBinding(d); match != nil {
+		e.Decision.Binding = &Binding{
+			Project:  match.Project,
+			Resource: match.Resource,
+			Index:    match.Index,
+			Role:     match.Binding.Role,
+			Member:   match.Member,
+		}
+		if match.Binding.Condition != nil {
+			e.Decision.Binding.Condition = match.Binding.Condition.Expression
+		}
+		e.Decision.ConditionResult = match.ConditionMet
+	}
+
+	return e
+}
+
+// matchingBinding returns the granting binding, or for a denial the first
+// binding that matched the principal and permission but whose condition
+// did not hold
+func matchingBinding(d *policy.Decision) *policy.BindingResult {
+	if d.Grant != nil {
+		return d.Grant
+	}
+
+	for i := range d.Checked {
+		b := &d.Checked[i]
+		if b.Member != "" && b.HasPermission {
+			return b
+		}
+	}
+
+	return nil
+}
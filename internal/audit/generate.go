@@ -0,0 +1,159 @@
+package audit
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+// GenerateOptions controls policy generation
+type GenerateOptions struct {
+	// IncludeDenied also grants permissions that were denied. By default
+	// only allowed checks are used, so requests the tests expect to be
+	// denied stay denied.
+	IncludeDenied bool
+}
+
+// GenerateResult is a generated policy plus what could not be attributed
+type GenerateResult struct {
+	Policy *policy.Policy
+	// Used is the number of events the policy was derived from
+	Used int
+	// NoPrincipal counts checks without a principal (permissive mode)
+	NoPrincipal int
+	// Unscoped lists resources outside any project
+	Unscoped []string
+}
+
+// Generate derives a least-privilege policy from observed checks: for
+// every principal and project, one custom role containing exactly the
+// permissions the principal used in that project, bound to that principal
+// in that project.
+//
+// A check made through an impersonation chain is granted to the last
+// delegate, as it is evaluated, and each caller in the chain is granted
+// roles/iam.serviceAccountTokenCreator on the service account it
+// impersonates.
+func Generate(events []Event, opts GenerateOptions) *GenerateResult {
+	result := &GenerateResult{
+		Policy: &policy.Policy{
+			Roles:    map[string]policy.Role{},
+			Groups:   map[string]policy.Group{},
+			Projects: map[string]policy.Project{},
+		},
+	}
+
+	// project -> principal -> permissions
+	used := make(map[string]map[string]map[string]bool)
+	// project -> service account email -> callers impersonating it
+	impersonated := make(map[string]map[string]map[string]bool)
+	unscoped := make(map[string]bool)
+
+	for _, e := range events {
+		if !e.Allowed() && !opts.IncludeDenied {
+			continue
+		}
+
+		principal := e.Actor.Principal
+		if principal == "" {
+			result.NoPrincipal++
+			continue
+		}
+
+		project := policy.ProjectFromResource(e.Target.Resource)
+		if project == "" {
+			unscoped[e.Target.Resource] = true
+			continue
+		}
+
+		for _, delegate := range e.Actor.Delegates {
+			email := strings.TrimPrefix(delegate, "serviceAccount:")
+			resource := policy.ServiceAccountResource(email)
+			saProject, _, ok := policy.ParseServiceAccountResource(resource)
+			if !ok {
+				unscoped[resource] = true
+			} else {
+				if impersonated[saProject] == nil {
+					impersonated[saProject] = make(map[string]map[string]bool)
+				}
+				if impersonated[saProject][email] == nil {
+					impersonated[saProject][email] = make(map[string]bool)
+				}
+				impersonated[saProject][email][principal] = true
+			}
+			principal = "serviceAccount:" + email
+		}
+
+		if used[project] == nil {
+			used[project] = make(map[string]map[string]bool)
+		}
+		if used[project][principal] == nil {
+			used[project][principal] = make(map[string]bool)
+		}
+		used[project][principal][e.Action.Permission] = true
+		result.Used++
+	}
+
+	for project, principals := range used {
+		var bindings []policy.Binding
+
+		for _, principal := range sortedKeys(principals) {
+			role := GeneratedRoleName(project, principal)
+			result.Policy.Roles[role] = policy.Role{Permissions: sortedKeys(principals[principal])}
+			bindings = append(bindings, policy.Binding{
+				Role:    role,
+				Members: []string{principal},
+			})
+		}
+
+		result.Policy.Projects[project] = policy.Project{Bindings: bindings}
+	}
+
+	for project, accounts := range impersonated {
+		p := result.Policy.Projects[project]
+		p.ServiceAccounts = make(map[string]policy.ServiceAccount)
+		for email, callers := range accounts {
+			p.ServiceAccounts[email] = policy.ServiceAccount{Bindings: []policy.Binding{{
+				Role:    "roles/iam.serviceAccountTokenCreator",
+				Members: sortedKeys(callers),
+			}}}
+		}
+		result.Policy.Projects[project] = p
+	}
+
+	result.Unscoped = sortedKeys(unscoped)
+
+	return result
+}
+
+// GeneratedRoleName names the role generated for principal in project,
+// e.g. roles/custom.test_project.user.alice_example_com
+func GeneratedRoleName(project, principal string) string {
+	principalType, identifier, ok := strings.Cut(principal, ":")
+	if !ok {
+		principalType, identifier = "member", principal
+	}
+
+	return "roles/custom." + slug(project) + "." + slug(principalType) + "." + slug(identifier)
+}
+
+// slug replaces characters not allowed in custom role IDs with '_'
+func slug(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
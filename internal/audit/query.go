@@ -0,0 +1,153 @@
+package audit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+// Query selects events. Zero-value fields match everything.
+type Query struct {
+	// Since drops events before this time
+	Since time.Time
+	// Decision is ALLOW or DENY (case-insensitive)
+	Decision string
+	// Principal matches exactly
+	Principal string
+	// Permission matches exactly, or as a glob when it contains '*'
+	Permission string
+	// Resource matches as a prefix
+	Resource string
+	// Caller matches the calling service exactly
+	Caller string
+}
+
+// Match reports whether e satisfies the query
+func (q Query) Match(e Event) bool {
+	if !q.Since.IsZero() && e.Timestamp.Before(q.Since) {
+		return false
+	}
+	if q.Decision != "" && !strings.EqualFold(e.Decision.Outcome, q.Decision) {
+		return false
+	}
+	if q.Principal != "" && e.Actor.Principal != q.Principal {
+		return false
+	}
+	if q.Permission != "" && !policy.MatchPermission(q.Permission, e.Action.Permission) {
+		return false
+	}
+	if q.Resource != "" && !strings.HasPrefix(e.Target.Resource, q.Resource) {
+		return false
+	}
+	if q.Caller != "" && e.Environment.Caller != q.Caller {
+		return false
+	}
+	return true
+}
+
+// Filter returns the events matching q, in their original order
+func Filter(events []Event, q Query) []Event {
+	var out []Event
+	for _, e := range events {
+		if q.Match(e) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// Group-by fields for aggregation
+const (
+	ByPermission = "permission"
+	ByPrincipal  = "principal"
+	ByResource   = "resource"
+	ByCaller     = "caller"
+	ByDecision   = "decision"
+)
+
+// GroupFields lists the fields CountBy accepts
+var GroupFields = []string{ByPermission, ByPrincipal, ByResource, ByCaller, ByDecision}
+
+// Count is the number of events sharing a key
+type Count struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+}
+
+// CountBy counts events by field, most frequent first (ties by key)
+func CountBy(events []Event, field string) ([]Count, error) {
+	key, err := fieldFunc(field)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	for _, e := range events {
+		counts[key(e)]++
+	}
+
+	out := make([]Count, 0, len(counts))
+	for k, n := range counts {
+		out = append(out, Count{Key: k, Count: n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Key < out[j].Key
+	})
+
+	return out, nil
+}
+
+// TopDenied returns the n most frequently denied values of field
+func TopDenied(events []Event, field string, n int) ([]Count, error) {
+	counts, err := CountBy(Filter(events, Query{Decision: OutcomeDeny}), field)
+	if err != nil {
+		return nil, err
+	}
+
+	if n > 0 && len(counts) > n {
+		counts = counts[:n]
+	}
+
+	return counts, nil
+}
+
+func fieldFunc(field string) (func(Event) string, error) {
+	switch field {
+	case ByPermission:
+		return func(e Event) string { return e.Action.Permission }, nil
+	case ByPrincipal:
+		return func(e Event) string { return e.Actor.Principal }, nil
+	case ByResource:
+		return func(e Event) string { return e.Target.Resource }, nil
+	case ByCaller:
+		return func(e Event) string { return e.Environment.Caller }, nil
+	case ByDecision:
+		return func(e Event) string { return e.Decision.Outcome }, nil
+	}
+
+	return nil, fmt.Errorf("invalid group-by field: %s (must be one of %s)", field, strings.Join(GroupFields, ", "))
+}
+
+// ParseSince parses a --since value: a duration before now (10m, 2h) or
+// an RFC 3339 timestamp
+func ParseSince(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid --since: %s (use a duration like 10m or an RFC 3339 timestamp)", s)
+}
@@ -0,0 +1,104 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Writer appends events to a JSONL file. It is safe for concurrent use.
+type Writer struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewWriter opens path for appending, creating it and its directory if needed
+func NewWriter(path string) (*Writer, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create audit directory: %w", err)
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	return &Writer{file: f}, nil
+}
+
+// Write appends one event as a single line
+func (w *Writer) Write(e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit event: %w", err)
+	}
+	data = append(data, '\n')
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, err := w.file.Write(data); err != nil {
+		return fmt.Errorf("failed to write audit event: %w", err)
+	}
+
+	return nil
+}
+
+// Close closes the underlying file
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.file.Close()
+}
+
+// ReadFile reads all authz_check events from a JSONL file
+func ReadFile(path string) ([]Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	return Read(f)
+}
+
+// Read reads authz_check events from JSONL. Blank lines and events of
+// other types are skipped; a malformed line is an error.
+func Read(r io.Reader) ([]Event, error) {
+	var events []Event
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := scanner.Bytes()
+		if len(data) == 0 {
+			continue
+		}
+
+		var e Event
+		if err := json.Unmarshal(data, &e); err != nil {
+			return nil, fmt.Errorf("audit log line %d: %w", line, err)
+		}
+		if e.EventType != "" && e.EventType != EventType {
+			continue
+		}
+
+		events = append(events, e)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,327 @@
+package audit
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+// UsageReport describes which grants in a policy were exercised by the
+// allowed checks in an audit log
+type UsageReport struct {
+	// From and To bound the events analyzed
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	// Events is the number of allowed checks analyzed
+	Events int `json:"events"`
+
+	Bindings []BindingUsage `json:"bindings"`
+	Roles    []RoleUsage    `json:"roles"`
+}
+
+// BindingUsage is the usage of one binding
+type BindingUsage struct {
+	Project string `json:"project"`
+	// Resource is set for bindings on a resource below the project
+	Resource string `json:"resource,omitempty"`
+	Index    int    `json:"index"`
+	Role     string `json:"role"`
+	// Grants is the number of checks this binding allowed
+	Grants int `json:"grants"`
+	// UnusedMembers are members that never matched an allowed check
+	UnusedMembers []string `json:"unused_members,omitempty"`
+}
+
+// Used reports whether the binding allowed at least one check
+func (b BindingUsage) Used() bool {
+	return b.Grants > 0
+}
+
+// Label identifies the binding as project[index] or resource[index]
+func (b BindingUsage) Label() string {
+	return policy.BindingLabel(b.Project, b.Resource, b.Index)
+}
+
+// RoleUsage is the usage of one role across all its bindings
+type RoleUsage struct {
+	Role string `json:"role"`
+	// Custom is true for roles defined in the policy (and so trimmable)
+	Custom            bool     `json:"custom"`
+	Bound             bool     `json:"bound"`
+	UsedPermissions   []string `json:"used_permissions"`
+	UnusedPermissions []string `json:"unused_permissions"`
+}
+
+// Used reports whether any permission of the role was exercised
+func (r RoleUsage) Used() bool {
+	return len(r.UsedPermissions) > 0
+}
+
+// AnalyzeUsage replays allowed checks against pol and records which
+// binding, member and role permission granted each one. A grant is only
+// credited to the first binding that allows a check, so redundant
+// bindings show up as unused.
+func AnalyzeUsage(pol *policy.Policy, events []Event) *UsageReport {
+	report := &UsageReport{}
+
+	grants := make(map[bindingKey]int)
+	usedMembers := make(map[bindingKey]map[string]bool)
+	usedPerms := make(map[string]map[string]bool)
+
+	for _, e := range events {
+		if !e.Allowed() || e.Actor.Principal == "" {
+			continue
+		}
+
+		report.Events++
+		if report.From.IsZero() || e.Timestamp.Before(report.From) {
+			report.From = e.Timestamp
+		}
+		if e.Timestamp.After(report.To) {
+			report.To = e.Timestamp
+		}
+
+		decision := policy.Evaluate(pol, policy.Request{
+			Principal:  e.Actor.Principal,
+			Resource:   e.Target.Resource,
+			Permission: e.Action.Permission,
+			Time:       e.Timestamp,
+			Delegates:  e.Actor.Delegates,
+		})
+		if decision.Grant == nil {
+			continue
+		}
+
+		// Impersonation steps are grants too
+		for _, d := range append(decision.Delegation, decision) {
+			key := bindingKeyFor(d.Grant.Ref())
+			grants[key]++
+			if usedMembers[key] == nil {
+				usedMembers[key] = make(map[string]bool)
+			}
+			usedMembers[key][d.Grant.Member] = true
+
+			role := d.Grant.Binding.Role
+			if usedPerms[role] == nil {
+				usedPerms[role] = make(map[string]bool)
+			}
+			usedPerms[role][d.Request.Permission] = true
+		}
+	}
+
+	bound := make(map[string]bool)
+	for _, ref := range policy.AllBindings(pol) {
+		key := bindingKeyFor(ref)
+		bound[ref.Binding.Role] = true
+
+		usage := BindingUsage{
+			Project:  ref.Project,
+			Resource: ref.Resource,
+			Index:    ref.Index,
+			Role:     ref.Binding.Role,
+			Grants:   grants[key],
+		}
+		for _, member := range ref.Binding.Members {
+			if !usedMembers[key][member] {
+				usage.UnusedMembers = append(usage.UnusedMembers, member)
+			}
+		}
+		report.Bindings = append(report.Bindings, usage)
+	}
+
+	roles := make(map[string]bool)
+	for role := range pol.Roles {
+		roles[role] = true
+	}
+	for role := range bound {
+		roles[role] = true
+	}
+
+	for _, role := range sortedKeys(roles) {
+		_, custom := pol.Roles[role]
+		usage := RoleUsage{Role: role, Custom: custom, Bound: bound[role]}
+
+		permissions, _ := policy.RolePermissions(pol, role)
+		for _, perm := range permissions {
+			if usedPerms[role][perm] {
+				usage.UsedPermissions = append(usage.UsedPermissions, perm)
+			} else {
+				usage.UnusedPermissions = append(usage.UnusedPermissions, perm)
+			}
+		}
+
+		report.Roles = append(report.Roles, usage)
+	}
+
+	return report
+}
+
+// Trim returns a copy of pol without the grants the report found unused:
+// unused bindings and members are removed, custom roles are reduced to
+// their used permissions, and custom roles left unbound are dropped.
+// Built-in roles cannot be trimmed and are kept as they are. Projects,
+// folders and organizations are kept even without bindings, projects with
+// their etag and version; deny policies and workload identities are kept
+// unchanged, and access boundaries keep their ceilings.
+func (r *UsageReport) Trim(pol *policy.Policy) *policy.Policy {
+	trimmed := &policy.Policy{
+		Roles:    make(map[string]policy.Role),
+		Groups:   make(map[string]policy.Group),
+		Projects: make(map[string]policy.Project),
+	}
+	for name, group := range pol.Groups {
+		trimmed.Groups[name] = policy.Group{Members: slices.Clone(group.Members)}
+	}
+
+	// Deny policies are guardrails, not grants, and are kept as they are
+	if len(pol.DenyPolicies) > 0 {
+		trimmed.DenyPolicies = make(map[string]policy.DenyPolicy)
+		for name, deny := range pol.DenyPolicies {
+			trimmed.DenyPolicies[name] = policy.DenyPolicy{AttachmentPoint: deny.AttachmentPoint, Rules: slices.Clone(deny.Rules)}
+		}
+	}
+
+	// Boundaries are kept with their roles expanded, so that trimming the
+	// roles does not change the ceilings
+	if len(pol.Boundaries) > 0 {
+		trimmed.Boundaries = make(map[string]policy.AccessBoundary)
+		for name, boundary := range pol.Boundaries {
+			perms := policy.BoundaryPermissions(pol, boundary)
+			slices.Sort(perms)
+			trimmed.Boundaries[name] = policy.AccessBoundary{
+				Principals:  slices.Clone(boundary.Principals),
+				Permissions: slices.Compact(perms),
+			}
+		}
+	}
+
+	// Workload identities describe principals, not grants; without them
+	// principalSet:// members stop matching
+	if len(pol.WorkloadIdentities) > 0 {
+		trimmed.WorkloadIdentities = make(map[string]policy.WorkloadIdentity)
+		for principal, identity := range pol.WorkloadIdentities {
+			trimmed.WorkloadIdentities[principal] = policy.WorkloadIdentity{
+				Attributes: maps.Clone(identity.Attributes),
+				Groups:     slices.Clone(identity.Groups),
+			}
+		}
+	}
+
+	// Keep the hierarchy so projects' parents stay valid
+	if len(pol.Organizations) > 0 {
+		trimmed.Organizations = make(map[string]policy.Organization)
+		for id := range pol.Organizations {
+			trimmed.Organizations[id] = policy.Organization{}
+		}
+	}
+	if len(pol.Folders) > 0 {
+		trimmed.Folders = make(map[string]policy.Folder)
+		for id, folder := range pol.Folders {
+			trimmed.Folders[id] = policy.Folder{Parent: folder.Parent}
+		}
+	}
+
+	// Every project is kept, so checks granted by folder and organization
+	// bindings still find it, and deny policies and resources on it stay
+	// valid
+	for id, project := range pol.Projects {
+		trimmed.Projects[id] = policy.Project{Parent: project.Parent, Etag: project.Etag, Version: project.Version}
+	}
+
+	usage := make(map[bindingKey]BindingUsage)
+	for _, b := range r.Bindings {
+		usage[bindingKey{b.Project, b.Resource, b.Index}] = b
+	}
+
+	keptRoles := make(map[string]bool)
+	for _, ref := range policy.AllBindings(pol) {
+		u := usage[bindingKeyFor(ref)]
+		if !u.Used() {
+			continue
+		}
+
+		binding := ref.Binding
+		kept := binding
+		kept.Members = nil
+		for _, member := range binding.Members {
+			if !slices.Contains(u.UnusedMembers, member) {
+				kept.Members = append(kept.Members, member)
+			}
+		}
+		if binding.Condition != nil {
+			condition := *binding.Condition
+			kept.Condition = &condition
+		}
+		keptRoles[binding.Role] = true
+
+		kind, id, _ := strings.Cut(ref.Resource, "/")
+		switch kind {
+		case "organizations":
+			org := trimmed.Organizations[id]
+			org.Bindings = append(org.Bindings, kept)
+			trimmed.Organizations[id] = org
+			continue
+		case "folders":
+			folder := trimmed.Folders[id]
+			folder.Bindings = append(folder.Bindings, kept)
+			trimmed.Folders[id] = folder
+			continue
+		}
+
+		project := trimmed.Projects[ref.Project]
+		_, email, isServiceAccount := policy.ParseServiceAccountResource(ref.Resource)
+		switch {
+		case ref.Resource == "":
+			project.Bindings = append(project.Bindings, kept)
+		case !isServiceAccount:
+			if trimmed.Resources == nil {
+				trimmed.Resources = make(map[string]policy.Resource)
+			}
+			resource := trimmed.Resources[ref.Resource]
+			resource.Bindings = append(resource.Bindings, kept)
+			trimmed.Resources[ref.Resource] = resource
+		default:
+			if project.ServiceAccounts == nil {
+				project.ServiceAccounts = make(map[string]policy.ServiceAccount)
+			}
+			sa := project.ServiceAccounts[email]
+			sa.Bindings = append(sa.Bindings, kept)
+			project.ServiceAccounts[email] = sa
+		}
+		trimmed.Projects[ref.Project] = project
+	}
+
+	for _, role := range r.Roles {
+		if !role.Custom || !keptRoles[role.Role] {
+			continue
+		}
+		trimmed.Roles[role.Role] = policy.Role{Permissions: slices.Clone(role.UsedPermissions)}
+	}
+
+	return trimmed
+}
+
+// UnusedBindings returns the bindings that never allowed a check
+func (r *UsageReport) UnusedBindings() []BindingUsage {
+	var out []BindingUsage
+	for _, b := range r.Bindings {
+		if !b.Used() {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// bindingKey identifies a binding by project, resource and position
+type bindingKey struct {
+	project  string
+	resource string
+	index    int
+}
+
+func bindingKeyFor(ref policy.BindingRef) bindingKey {
+	return bindingKey{ref.Project, ref.Resource, ref.Index}
+}
@@ -0,0 +1,165 @@
+// Package cassette records emulator traffic and replays it against a stack
+// to detect requests whose authorization outcome changed.
+//
+// A cassette is a JSONL file with one Interaction per line, in the order the
+// requests were made. Request bodies are stored verbatim so replay sends
+// exactly what the client sent; the resource and principal are extracted at
+// record time so cassettes can be read without decoding protobufs.
+package cassette
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Protocols an interaction can be recorded over
+const (
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http"
+)
+
+// Authorization outcomes. A request is DENY if it was rejected with
+// PERMISSION_DENIED (403 over HTTP) and ALLOW otherwise: a NOT_FOUND
+// still means the caller was authorized to look.
+const (
+	OutcomeAllow = "ALLOW"
+	OutcomeDeny  = "DENY"
+)
+
+// Interaction is one recorded request and its response code
+type Interaction struct {
+	Time     time.Time `json:"time"`
+	Service  string    `json:"service"`
+	Protocol string    `json:"protocol"`
+	// Method is the full gRPC method (/package.Service/Method) or the HTTP
+	// method and path (POST /v1/projects/p/secrets)
+	Method    string `json:"method"`
+	Resource  string `json:"resource,omitempty"`
+	Principal string `json:"principal,omitempty"`
+	// Delegates is the impersonation chain the request was made through,
+	// re-sent on replay
+	Delegates []string `json:"delegates,omitempty"`
+	// Code is the gRPC status code name or the HTTP status code
+	Code    string `json:"code"`
+	Outcome string `json:"outcome"`
+
+	// Request is the serialized request message or HTTP body
+	Request []byte `json:"request,omitempty"`
+	// ContentType is set for HTTP requests with a body
+	ContentType string `json:"content_type,omitempty"`
+}
+
+// GRPCOutcome maps a gRPC status code to an authorization outcome
+func GRPCOutcome(code codes.Code) string {
+	if code == codes.PermissionDenied {
+		return OutcomeDeny
+	}
+	return OutcomeAllow
+}
+
+// HTTPOutcome maps an HTTP status code to an authorization outcome
+func HTTPOutcome(status int) string {
+	if status == http.StatusForbidden {
+		return OutcomeDeny
+	}
+	return OutcomeAllow
+}
+
+// Writer appends interactions to a cassette. It is safe for concurrent use.
+type Writer struct {
+	mu    sync.Mutex
+	w     io.Writer
+	c     io.Closer
+	count int
+}
+
+// NewWriter writes interactions to w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Create creates (or truncates) a cassette file
+func Create(path string) (*Writer, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cassette: %w", err)
+	}
+
+	return &Writer{w: f, c: f}, nil
+}
+
+// Record appends an interaction
+func (w *Writer) Record(i Interaction) error {
+	data, err := json.Marshal(i)
+	if err != nil {
+		return fmt.Errorf("failed to encode interaction: %w", err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, err := w.w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	w.count++
+
+	return nil
+}
+
+// Count returns the number of interactions recorded
+func (w *Writer) Count() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.count
+}
+
+// Close closes the underlying file, if the writer owns one
+func (w *Writer) Close() error {
+	if w.c == nil {
+		return nil
+	}
+	return w.c.Close()
+}
+
+// Read parses a cassette
+func Read(r io.Reader) ([]Interaction, error) {
+	var interactions []Interaction
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var i Interaction
+		if err := json.Unmarshal(scanner.Bytes(), &i); err != nil {
+			return nil, fmt.Errorf("line %d: invalid interaction: %w", line, err)
+		}
+		interactions = append(interactions, i)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+
+	return interactions, nil
+}
+
+// ReadFile parses a cassette file
+func ReadFile(path string) ([]Interaction, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cassette: %w", err)
+	}
+	defer f.Close()
+
+	return Read(f)
+}
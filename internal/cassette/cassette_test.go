@@ -0,0 +1,288 @@
+package cassette
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/native"
+)
+
+func TestGRPCResource(t *testing.T) {
+	req, err := proto.Marshal(&secretmanagerpb.AccessSecretVersionRequest{Name: "projects/p/secrets/s/versions/1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := GRPCResource("/google.cloud.secretmanager.v1.SecretManagerService/AccessSecretVersion", req); got != "projects/p/secrets/s/versions/1" {
+		t.Errorf("GRPCResource(name) = %q", got)
+	}
+
+	req, _ = proto.Marshal(&secretmanagerpb.CreateSecretRequest{Parent: "projects/p", SecretId: "s"})
+	if got := GRPCResource("/google.cloud.secretmanager.v1.SecretManagerService/CreateSecret", req); got != "projects/p" {
+		t.Errorf("GRPCResource(parent) = %q", got)
+	}
+
+	req, _ = proto.Marshal(&secretmanagerpb.UpdateSecretRequest{
+		Secret:     &secretmanagerpb.Secret{Name: "projects/p/secrets/s"},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"labels"}},
+	})
+	if got := GRPCResource("/google.cloud.secretmanager.v1.SecretManagerService/UpdateSecret", req); got != "projects/p/secrets/s" {
+		t.Errorf("GRPCResource(secret.name) = %q", got)
+	}
+
+	req, _ = proto.Marshal(&kmspb.UpdateCryptoKeyRequest{CryptoKey: &kmspb.CryptoKey{Name: "projects/p/locations/global/keyRings/r/cryptoKeys/k"}})
+	if got := GRPCResource("/google.cloud.kms.v1.KeyManagementService/UpdateCryptoKey", req); got != "projects/p/locations/global/keyRings/r/cryptoKeys/k" {
+		t.Errorf("GRPCResource(crypto_key.name) = %q", got)
+	}
+
+	if got := GRPCResource("/unknown.Service/Method", req); got != "" {
+		t.Errorf("GRPCResource(unknown) = %q, want empty", got)
+	}
+}
+
+func TestHTTPResource(t *testing.T) {
+	tests := map[string]string{
+		"/v1/projects/p/secrets/s:addVersion":        "projects/p/secrets/s",
+		"/v1/projects/p/secrets/s/versions/1:access": "projects/p/secrets/s/versions/1",
+		"/v1/projects/p/secrets":                     "projects/p/secrets",
+		"/health":                                    "",
+	}
+	for path, want := range tests {
+		if got := HTTPResource(path); got != want {
+			t.Errorf("HTTPResource(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+
+	want := Interaction{Service: "kms", Protocol: ProtocolGRPC, Method: "/m", Code: "OK", Outcome: OutcomeAllow, Request: []byte{0, 1, 2}}
+	if err := w.Record(want); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	if w.Count() != 1 {
+		t.Errorf("Count() = %d, want 1", w.Count())
+	}
+
+	got, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(got) != 1 || got[0].Method != want.Method || !bytes.Equal(got[0].Request, want.Request) {
+		t.Errorf("Read() = %+v", got)
+	}
+}
+
+// recording collects interactions from a proxy
+type recording struct {
+	mu           sync.Mutex
+	interactions []Interaction
+}
+
+func (r *recording) record(i Interaction) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.interactions = append(r.interactions, i)
+}
+
+func startStack(t *testing.T, policyFile string) *native.Stack {
+	t.Helper()
+
+	stack, err := native.Start(native.Options{PolicyFile: policyFile, IAMMode: "strict"})
+	if err != nil {
+		t.Fatalf("Failed to start stack: %v", err)
+	}
+	t.Cleanup(stack.Stop)
+
+	return stack
+}
+
+func dial(t *testing.T, addr string) *grpc.ClientConn {
+	t.Helper()
+
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to connect to %s: %v", addr, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func as(principal string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "x-emulator-principal", principal)
+}
+
+func TestRecordAndReplayGRPC(t *testing.T) {
+	// Record against the example policy, where admin@example.com is an admin
+	recorded := startStack(t, "../../policy.yaml")
+
+	rec := &recording{}
+	proxy := NewGRPCProxy("secret-manager", dial(t, recorded.Endpoints().SecretManager), rec.record)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go proxy.Serve(lis)
+	t.Cleanup(proxy.Stop)
+
+	client := secretmanagerpb.NewSecretManagerServiceClient(dial(t, lis.Addr().String()))
+
+	secret, err := client.CreateSecret(as("user:admin@example.com"), &secretmanagerpb.CreateSecretRequest{
+		Parent:   "projects/test-project",
+		SecretId: "db-password",
+		Secret:   &secretmanagerpb.Secret{},
+	})
+	if err != nil {
+		t.Fatalf("CreateSecret through proxy failed: %v", err)
+	}
+	if secret.Name != "projects/test-project/secrets/db-password" {
+		t.Errorf("unexpected secret name %q", secret.Name)
+	}
+
+	ctx := metadata.AppendToOutgoingContext(as("user:mallory@example.com"), "x-emulator-delegates", "a@p.iam.gserviceaccount.com,b@p.iam.gserviceaccount.com")
+	_, err = client.GetSecret(ctx, &secretmanagerpb.GetSecretRequest{Name: secret.Name})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("Expected PermissionDenied through proxy, got %v", err)
+	}
+
+	if len(rec.interactions) != 2 {
+		t.Fatalf("recorded %d interactions, want 2", len(rec.interactions))
+	}
+	create := rec.interactions[0]
+	if create.Principal != "user:admin@example.com" || create.Resource != "projects/test-project" || create.Outcome != OutcomeAllow {
+		t.Errorf("CreateSecret interaction = %+v", create)
+	}
+	if get := rec.interactions[1]; get.Outcome != OutcomeDeny || get.Code != "PermissionDenied" || len(get.Delegates) != 2 {
+		t.Errorf("GetSecret interaction = %+v", rec.interactions[1])
+	}
+
+	// Replay against a policy where admin@example.com is not granted anything
+	replayed := startStack(t, "../../testdata/policy.yaml")
+	results := Replay(context.Background(), rec.interactions, map[string]Target{
+		"secret-manager": {GRPC: dial(t, replayed.Endpoints().SecretManager)},
+	}, nil)
+
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	if !results[0].Changed() || results[0].Outcome != OutcomeDeny {
+		t.Errorf("Expected CreateSecret to change to DENY, got %+v", results[0])
+	}
+	if results[1].Changed() {
+		t.Errorf("Expected GetSecret to stay denied, got %+v", results[1])
+	}
+
+	missing := Replay(context.Background(), rec.interactions[:1], map[string]Target{}, nil)
+	if missing[0].Error == "" || missing[0].Changed() {
+		t.Errorf("Expected error for service without target, got %+v", missing[0])
+	}
+}
+
+func TestRecordAndReplayHTTP(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Emulator-Principal") != "user:alice@example.com" || r.Header.Get("X-Emulator-Delegates") != "ci@p.iam.gserviceaccount.com" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	target, _ := url.Parse(upstream.URL)
+	rec := &recording{}
+	proxy := httptest.NewServer(NewHTTPProxy("secret-manager", target, rec.record))
+	defer proxy.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, proxy.URL+"/v1/projects/p/secrets/s:addVersion", bytes.NewBufferString(`{"payload":{}}`))
+	req.Header.Set("X-Emulator-Principal", "user:alice@example.com")
+	req.Header.Set("X-Emulator-Delegates", "ci@p.iam.gserviceaccount.com")
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if len(rec.interactions) != 1 {
+		t.Fatalf("recorded %d interactions, want 1", len(rec.interactions))
+	}
+	i := rec.interactions[0]
+	if i.Method != "POST /v1/projects/p/secrets/s:addVersion" || i.Resource != "projects/p/secrets/s" ||
+		i.Code != "200" || string(i.Request) != `{"payload":{}}` || len(i.Delegates) != 1 {
+		t.Errorf("HTTP interaction = %+v", i)
+	}
+
+	// Replay re-sends the principal and impersonation chain
+	results := Replay(context.Background(), []Interaction{i}, map[string]Target{
+		"secret-manager": {HTTP: upstream.URL},
+	}, nil)
+	if results[0].Changed() || results[0].Code != "200" {
+		t.Errorf("Expected replay to stay 200, got %+v", results[0])
+	}
+
+	// Same request as a different principal is now denied
+	i.Principal = "user:bob@example.com"
+	results = Replay(context.Background(), []Interaction{i}, map[string]Target{
+		"secret-manager": {HTTP: upstream.URL},
+	}, nil)
+	if !results[0].Changed() || results[0].Code != "403" {
+		t.Errorf("Expected replay to change to 403, got %+v", results[0])
+	}
+}
+
+func TestReplayTimeoutPerRequest(t *testing.T) {
+	// A hung first request must not use up the time of the next
+	server := grpc.NewServer(grpc.UnknownServiceHandler(func(_ any, stream grpc.ServerStream) error {
+		method, _ := grpc.MethodFromServerStream(stream)
+		if method == "/test.Service/Hang" {
+			<-stream.Context().Done()
+			return stream.Context().Err()
+		}
+		time.Sleep(60 * time.Millisecond)
+		if err := stream.RecvMsg(&emptypb.Empty{}); err != nil {
+			return err
+		}
+		return stream.SendMsg(&emptypb.Empty{})
+	}))
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	interactions := []Interaction{
+		{Service: "svc", Protocol: ProtocolGRPC, Method: "/test.Service/Hang", Code: "OK", Outcome: OutcomeAllow},
+		{Service: "svc", Protocol: ProtocolGRPC, Method: "/test.Service/Slow", Code: "OK", Outcome: OutcomeAllow},
+	}
+	results := Replay(context.Background(), interactions, map[string]Target{
+		"svc": {GRPC: dial(t, lis.Addr().String()), Timeout: 100 * time.Millisecond},
+	}, nil)
+
+	if results[0].Error == "" || results[0].Changed() {
+		t.Errorf("Expected the hung request to time out, got %+v", results[0])
+	}
+	if results[1].Error != "" || results[1].Code != "OK" {
+		t.Errorf("Expected the next request to get its own timeout, got %+v", results[1])
+	}
+}
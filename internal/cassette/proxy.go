@@ -0,0 +1,90 @@
+package cassette
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/emulator/authz"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/proxy"
+)
+
+// NewGRPCProxy returns a gRPC server that forwards every unary call to
+// target and passes it to record. Metadata (including the principal) is
+// forwarded both ways. Health checks are forwarded but not recorded.
+func NewGRPCProxy(service string, target *grpc.ClientConn, record func(Interaction)) *grpc.Server {
+	return proxy.NewGRPC(target, proxy.GRPCOptions{
+		Observe: func(call proxy.Call) {
+			if strings.HasPrefix(call.Method, "/"+healthpb.Health_ServiceDesc.ServiceName+"/") {
+				return
+			}
+
+			code := status.Code(call.Err)
+			record(Interaction{
+				Time:      call.Start.UTC(),
+				Service:   service,
+				Protocol:  ProtocolGRPC,
+				Method:    call.Method,
+				Resource:  GRPCResource(call.Method, call.Request),
+				Principal: first(call.Metadata.Get(authz.PrincipalMetadataKey)),
+				Delegates: splitDelegates(call.Metadata.Get(authz.DelegatesMetadataKey)),
+				Code:      code.String(),
+				Outcome:   GRPCOutcome(code),
+				Request:   call.Request,
+			})
+		},
+	})
+}
+
+// NewHTTPProxy returns a reverse proxy to target that passes every request
+// to record. The /health endpoint is forwarded but not recorded.
+func NewHTTPProxy(service string, target *url.URL, record func(Interaction)) http.Handler {
+	return proxy.NewHTTP(target, proxy.HTTPOptions{
+		Observe: func(e proxy.Exchange) {
+			r := e.Request
+			if r.URL.Path == "/health" {
+				return
+			}
+
+			record(Interaction{
+				Time:        e.Start.UTC(),
+				Service:     service,
+				Protocol:    ProtocolHTTP,
+				Method:      r.Method + " " + r.URL.RequestURI(),
+				Resource:    HTTPResource(r.URL.Path),
+				Principal:   r.Header.Get(authz.PrincipalHeader),
+				Delegates:   splitDelegates(r.Header.Values(authz.DelegatesHeader)),
+				Code:        strconv.Itoa(e.Status),
+				Outcome:     HTTPOutcome(e.Status),
+				Request:     e.Body,
+				ContentType: r.Header.Get("Content-Type"),
+			})
+		},
+	})
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// splitDelegates parses the comma-separated impersonation chain in the
+// values of the delegates metadata key or header
+func splitDelegates(values []string) []string {
+	var delegates []string
+	for _, value := range values {
+		for _, delegate := range strings.Split(value, ",") {
+			if delegate = strings.TrimSpace(delegate); delegate != "" {
+				delegates = append(delegates, delegate)
+			}
+		}
+	}
+	return delegates
+}
@@ -0,0 +1,166 @@
+package cassette
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/emulator/authz"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/proxy"
+)
+
+// Target is where replayed requests for one service are sent
+type Target struct {
+	GRPC *grpc.ClientConn
+	// HTTP is the base URL of the service's HTTP port, e.g. http://localhost:8081
+	HTTP string
+	// Timeout bounds each replayed request; zero leaves only ctx's deadline
+	Timeout time.Duration
+}
+
+// Result is the outcome of replaying one interaction
+type Result struct {
+	Service   string `json:"service"`
+	Method    string `json:"method"`
+	Resource  string `json:"resource,omitempty"`
+	Principal string `json:"principal,omitempty"`
+
+	RecordedCode    string `json:"recorded_code"`
+	RecordedOutcome string `json:"recorded_outcome"`
+	Code            string `json:"code,omitempty"`
+	Outcome         string `json:"outcome,omitempty"`
+
+	// Error is set if the request could not be replayed at all
+	Error string `json:"error,omitempty"`
+}
+
+// Changed reports whether the authorization outcome differs from the
+// recording
+func (r Result) Changed() bool {
+	return r.Error == "" && r.Outcome != r.RecordedOutcome
+}
+
+// CodeChanged reports whether the response code differs from the recording
+func (r Result) CodeChanged() bool {
+	return r.Error == "" && r.Code != r.RecordedCode
+}
+
+// Replay sends the interactions to targets (keyed by service) in recorded
+// order, so requests that create resources run before requests that use
+// them. client is used for HTTP interactions; nil means
+// http.DefaultClient.
+func Replay(ctx context.Context, interactions []Interaction, targets map[string]Target, client *http.Client) []Result {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	results := make([]Result, 0, len(interactions))
+	for _, i := range interactions {
+		result := Result{
+			Service:         i.Service,
+			Method:          i.Method,
+			Resource:        i.Resource,
+			Principal:       i.Principal,
+			RecordedCode:    i.Code,
+			RecordedOutcome: i.Outcome,
+		}
+
+		target, ok := targets[i.Service]
+		switch {
+		case !ok:
+			result.Error = fmt.Sprintf("no target for service %q", i.Service)
+		case i.Protocol == ProtocolGRPC && target.GRPC != nil:
+			err := replayGRPC(ctx, target, i)
+			code := status.Code(err)
+			// A stack that is down or hung would otherwise look like nothing
+			// changed
+			if code == codes.Unavailable && i.Code != code.String() {
+				result.Error = fmt.Sprintf("service unavailable: %s", status.Convert(err).Message())
+				break
+			}
+			if code == codes.DeadlineExceeded && i.Code != code.String() {
+				result.Error = fmt.Sprintf("request timed out: %s", status.Convert(err).Message())
+				break
+			}
+			result.Code = code.String()
+			result.Outcome = GRPCOutcome(code)
+		case i.Protocol == ProtocolHTTP && target.HTTP != "":
+			code, err := replayHTTP(ctx, client, target, i)
+			if err != nil {
+				result.Error = err.Error()
+				break
+			}
+			result.Code = strconv.Itoa(code)
+			result.Outcome = HTTPOutcome(code)
+		default:
+			result.Error = fmt.Sprintf("no %s target for service %q", i.Protocol, i.Service)
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
+
+func replayGRPC(ctx context.Context, target Target, i Interaction) error {
+	if target.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, target.Timeout)
+		defer cancel()
+	}
+
+	if i.Principal != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, authz.PrincipalMetadataKey, i.Principal)
+	}
+	if len(i.Delegates) > 0 {
+		ctx = metadata.AppendToOutgoingContext(ctx, authz.DelegatesMetadataKey, strings.Join(i.Delegates, ","))
+	}
+
+	req := i.Request
+	var resp []byte
+	return target.GRPC.Invoke(ctx, i.Method, &req, &resp, grpc.ForceCodec(proxy.RawCodec{}))
+}
+
+func replayHTTP(ctx context.Context, client *http.Client, target Target, i Interaction) (int, error) {
+	if target.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, target.Timeout)
+		defer cancel()
+	}
+
+	method, uri, ok := strings.Cut(i.Method, " ")
+	if !ok {
+		return 0, fmt.Errorf("invalid HTTP interaction method %q", i.Method)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(target.HTTP, "/")+uri, bytes.NewReader(i.Request))
+	if err != nil {
+		return 0, fmt.Errorf("failed to build request: %w", err)
+	}
+	if i.Principal != "" {
+		req.Header.Set(authz.PrincipalHeader, i.Principal)
+	}
+	if len(i.Delegates) > 0 {
+		req.Header.Set(authz.DelegatesHeader, strings.Join(i.Delegates, ","))
+	}
+	if i.ContentType != "" {
+		req.Header.Set("Content-Type", i.ContentType)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	resp.Body.Close()
+
+	return resp.StatusCode, nil
+}
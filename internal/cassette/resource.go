@@ -0,0 +1,84 @@
+package cassette
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	// Register the descriptors of the emulated APIs
+	_ "cloud.google.com/go/iam/apiv1/iampb"
+	_ "cloud.google.com/go/kms/apiv1/kmspb"
+	_ "cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+)
+
+// resourceFields are the request fields holding the target resource, in
+// the order they are looked up
+var resourceFields = []protoreflect.Name{"name", "parent", "resource"}
+
+// GRPCResource extracts the target resource from a serialized request for
+// the full method name (/package.Service/Method), falling back to the name
+// of the message an update request carries (UpdateSecret's secret.name). It
+// returns "" if the method is unknown or the request has no resource field.
+func GRPCResource(fullMethod string, request []byte) string {
+	name := strings.ReplaceAll(strings.TrimPrefix(fullMethod, "/"), "/", ".")
+
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return ""
+	}
+	method, ok := desc.(protoreflect.MethodDescriptor)
+	if !ok {
+		return ""
+	}
+
+	msg := dynamicpb.NewMessage(method.Input())
+	if err := proto.Unmarshal(request, msg); err != nil {
+		return ""
+	}
+
+	fields := method.Input().Fields()
+	for _, fieldName := range resourceFields {
+		field := fields.ByName(fieldName)
+		if field == nil || field.Kind() != protoreflect.StringKind || field.IsList() {
+			continue
+		}
+		if value := msg.Get(field).String(); value != "" {
+			return value
+		}
+	}
+
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if field.Kind() != protoreflect.MessageKind || field.IsList() || field.IsMap() || !msg.Has(field) {
+			continue
+		}
+		nested := field.Message().Fields().ByName("name")
+		if nested == nil || nested.Kind() != protoreflect.StringKind || nested.IsList() {
+			continue
+		}
+		if value := msg.Get(field).Message().Get(nested).String(); value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
+
+// HTTPResource extracts the resource from a REST path such as
+// /v1/projects/p/secrets/s:access
+func HTTPResource(path string) string {
+	path = strings.TrimPrefix(path, "/")
+	if version, rest, ok := strings.Cut(path, "/"); ok && strings.HasPrefix(version, "v") {
+		path = rest
+	}
+	if i := strings.LastIndex(path, ":"); i > strings.LastIndex(path, "/") {
+		path = path[:i]
+	}
+	if !strings.HasPrefix(path, "projects/") {
+		return ""
+	}
+	return path
+}
@@ -0,0 +1,278 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/audit"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Query the authorization audit log",
+	Long: `Query the audit log of IAM authorization decisions.
+
+When the trace config key is enabled (gcp-emulator config set trace true),
+the control plane records every decision to the audit log (audit-file,
+default ./audit.jsonl): principal, resource, permission, decision,
+matching binding, condition result, calling service, and latency.`,
+}
+
+var auditQueryCmd = &cobra.Command{
+	Use:   "query",
+	Short: "Search and aggregate audit events",
+	Long: `Search audit events, or count them with --group-by.
+
+Filters combine with AND. --permission accepts globs such as
+'secretmanager.*' and --resource matches as a prefix.`,
+	Example: `  # Denied requests in the last 10 minutes
+  gcp-emulator audit query --since 10m --decision deny
+
+  # What one principal did
+  gcp-emulator audit query --principal user:alice@example.com
+
+  # Top denied permissions
+  gcp-emulator audit query --decision deny --group-by permission`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		q, err := auditQueryFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		events, err := loadAuditEvents(cmd)
+		if err != nil {
+			return err
+		}
+
+		matched := audit.Filter(events, q)
+		limit, _ := cmd.Flags().GetInt("limit")
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		if groupBy, _ := cmd.Flags().GetString("group-by"); groupBy != "" {
+			counts, err := audit.CountBy(matched, groupBy)
+			if err != nil {
+				return err
+			}
+			if limit > 0 && len(counts) > limit {
+				counts = counts[:limit]
+			}
+
+			if asJSON {
+				return printJSON(counts)
+			}
+			printCounts(groupBy, counts)
+			return nil
+		}
+
+		// Show the most recent events
+		if limit > 0 && len(matched) > limit {
+			matched = matched[len(matched)-limit:]
+		}
+
+		if asJSON {
+			for _, e := range matched {
+				data, err := json.Marshal(e)
+				if err != nil {
+					return fmt.Errorf("failed to encode audit event: %w", err)
+				}
+				fmt.Println(string(data))
+			}
+			return nil
+		}
+
+		printAuditEvents(matched)
+		return nil
+	},
+}
+
+var auditSummaryCmd = &cobra.Command{
+	Use:   "summary",
+	Short: "Summarize decisions and top denials",
+	Long: `Show decision totals and the most frequently denied permissions,
+principals, and resources. Useful for spotting missing grants after a
+test run.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		q, err := auditQueryFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		events, err := loadAuditEvents(cmd)
+		if err != nil {
+			return err
+		}
+
+		matched := audit.Filter(events, q)
+		top, _ := cmd.Flags().GetInt("top")
+
+		allowed := 0
+		for _, e := range matched {
+			if e.Allowed() {
+				allowed++
+			}
+		}
+
+		color.Cyan("Audit Summary")
+		fmt.Printf("  %d decisions: %s, %s\n", len(matched),
+			color.GreenString("%d allowed", allowed),
+			color.RedString("%d denied", len(matched)-allowed))
+
+		for _, field := range []string{audit.ByPermission, audit.ByPrincipal, audit.ByResource} {
+			counts, err := audit.TopDenied(matched, field, top)
+			if err != nil {
+				return err
+			}
+			if len(counts) == 0 {
+				continue
+			}
+
+			fmt.Println()
+			color.Cyan("Top denied %ss:", field)
+			for _, c := range counts {
+				fmt.Printf("  %5d  %s\n", c.Count, c.Key)
+			}
+		}
+
+		return nil
+	},
+}
+
+func auditQueryFromFlags(cmd *cobra.Command) (audit.Query, error) {
+	sinceFlag, _ := cmd.Flags().GetString("since")
+	since, err := audit.ParseSince(sinceFlag, time.Now())
+	if err != nil {
+		return audit.Query{}, err
+	}
+
+	decision, _ := cmd.Flags().GetString("decision")
+	switch strings.ToLower(decision) {
+	case "", "allow", "deny":
+	default:
+		return audit.Query{}, fmt.Errorf("invalid --decision: %s (must be allow or deny)", decision)
+	}
+
+	q := audit.Query{Since: since, Decision: decision}
+	q.Principal, _ = cmd.Flags().GetString("principal")
+	q.Permission, _ = cmd.Flags().GetString("permission")
+	q.Resource, _ = cmd.Flags().GetString("resource")
+	q.Caller, _ = cmd.Flags().GetString("caller")
+
+	return q, nil
+}
+
+// loadAuditEvents reads --file, defaulting to the configured audit file
+func loadAuditEvents(cmd *cobra.Command) ([]audit.Event, error) {
+	path, _ := cmd.Flags().GetString("file")
+	if path == "" {
+		cfg, err := config.Load()
+		if err != nil {
+			return nil, err
+		}
+		path = cfg.AuditPath()
+		if path == "" {
+			path = cfg.AuditFile
+		}
+	}
+
+	events, err := audit.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w (is tracing enabled? gcp-emulator config set trace true)", err)
+	}
+
+	return events, nil
+}
+
+func printAuditEvents(events []audit.Event) {
+	if len(events) == 0 {
+		color.Yellow("No matching audit events")
+		return
+	}
+
+	for _, e := range events {
+		decision := color.GreenString("%-5s", e.Decision.Outcome)
+		if !e.Allowed() {
+			decision = color.RedString("%-5s", e.Decision.Outcome)
+		}
+
+		fmt.Printf("%s %s %s → %s on %s\n",
+			e.Timestamp.Local().Format("2006-01-02 15:04:05.000"),
+			decision, e.Actor.Principal, e.Action.Permission, e.Target.Resource)
+
+		var details []string
+		if e.Environment.Caller != "" {
+			details = append(details, "caller: "+e.Environment.Caller)
+		}
+		if len(e.Actor.Delegates) > 0 {
+			details = append(details, "delegates: "+strings.Join(e.Actor.Delegates, ", "))
+		}
+		if b := e.Decision.Binding; b != nil {
+			details = append(details, fmt.Sprintf("binding: %s %s", b.Label(), b.Role))
+			if e.Decision.ConditionResult != nil {
+				details = append(details, fmt.Sprintf("condition: %t", *e.Decision.ConditionResult))
+			}
+		}
+		details = append(details, fmt.Sprintf("latency: %.3fms", e.Decision.LatencyMS))
+		if e.Decision.Reason != "" {
+			details = append(details, e.Decision.Reason)
+		}
+
+		fmt.Printf("    %s\n", strings.Join(details, " | "))
+	}
+
+	fmt.Printf("\n%d events\n", len(events))
+}
+
+func printCounts(field string, counts []audit.Count) {
+	if len(counts) == 0 {
+		color.Yellow("No matching audit events")
+		return
+	}
+
+	color.Cyan("%7s  %s", "Count", field)
+	for _, c := range counts {
+		key := c.Key
+		if key == "" {
+			key = "(none)"
+		}
+		fmt.Printf("%7d  %s\n", c.Count, key)
+	}
+}
+
+func printJSON(v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode output: %w", err)
+	}
+
+	fmt.Println(string(data))
+	return nil
+}
+
+func addAuditFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().String("file", "", "Audit log to read (default: configured audit-file)")
+	cmd.Flags().String("since", "", "Only events since a duration ago (10m, 2h) or RFC 3339 time")
+	cmd.Flags().String("decision", "", "Only allow or deny decisions")
+	cmd.Flags().String("principal", "", "Only events for this principal")
+	cmd.Flags().String("permission", "", "Only events for this permission (supports globs like 'secretmanager.*')")
+	cmd.Flags().String("resource", "", "Only events on resources with this prefix")
+	cmd.Flags().String("caller", "", "Only events from this calling service")
+}
+
+func init() {
+	auditCmd.AddCommand(auditQueryCmd)
+	auditCmd.AddCommand(auditSummaryCmd)
+
+	addAuditFilterFlags(auditQueryCmd)
+	auditQueryCmd.Flags().String("group-by", "", "Count events by field ("+strings.Join(audit.GroupFields, "|")+")")
+	auditQueryCmd.Flags().Int("limit", 0, "Maximum number of events (most recent) or groups to show")
+	auditQueryCmd.Flags().Bool("json", false, "Output as JSON")
+
+	addAuditFilterFlags(auditSummaryCmd)
+	auditSummaryCmd.Flags().Int("top", 10, "Number of entries per top-denied list")
+}
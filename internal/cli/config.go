@@ -40,7 +40,8 @@ var configSetCmd = &cobra.Command{
 
 Available keys:
   iam-mode         IAM mode (off|permissive|strict)
-  trace            Enable trace logging (true|false)
+  trace            Record IAM decisions to the audit log (true|false)
+  audit-file       Path of the audit log (default ./audit.jsonl)
   pull-on-start    Pull images before starting (true|false)
   policy-file      Path to policy.yaml`,
 	Args: cobra.ExactArgs(2),
@@ -63,6 +64,8 @@ Available keys:
 			cfg.IAMMode = value
 		case "trace":
 			cfg.Trace = value == "true"
+		case "audit-file":
+			cfg.AuditFile = value
 		case "pull-on-start":
 			cfg.PullOnStart = value == "true"
 		case "policy-file":
@@ -93,6 +96,7 @@ var configResetCmd = &cobra.Command{
 		cfg := &config.Config{
 			IAMMode:     "permissive",
 			Trace:       false,
+			AuditFile:   "./audit.jsonl",
 			PullOnStart: false,
 			PolicyFile:  "./policy.yaml",
 			Ports: config.PortConfig{
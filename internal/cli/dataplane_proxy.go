@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/fatih/color"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
+)
+
+// dataPlaneProxies are proxies listening in front of Secret Manager and KMS
+type dataPlaneProxies struct {
+	errs    chan error
+	closers []func()
+}
+
+// startDataPlaneProxies starts a gRPC and an HTTP proxy for each data plane
+// service, on its ports plus offset. IAM is skipped: clients talk to the
+// data planes, which call IAM themselves.
+func startDataPlaneProxies(
+	cfg *config.Config,
+	offset int,
+	newGRPC func(svc docker.Service, target *grpc.ClientConn) *grpc.Server,
+	newHTTP func(svc docker.Service, target *url.URL) http.Handler,
+) (*dataPlaneProxies, error) {
+	p := &dataPlaneProxies{errs: make(chan error, 4)}
+
+	for _, svc := range docker.Services(cfg) {
+		if svc.Name == "iam" {
+			continue
+		}
+
+		conn, err := grpc.NewClient(svc.GRPCAddr(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			p.Close()
+			return nil, fmt.Errorf("failed to connect to %s: %w", svc.DisplayName, err)
+		}
+		p.closers = append(p.closers, func() { conn.Close() })
+
+		grpcLis, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", svc.GRPCPort+offset))
+		if err != nil {
+			p.Close()
+			return nil, fmt.Errorf("failed to listen for %s gRPC: %w", svc.DisplayName, err)
+		}
+		grpcProxy := newGRPC(svc, conn)
+		go func() { p.errs <- grpcProxy.Serve(grpcLis) }()
+		p.closers = append(p.closers, grpcProxy.Stop)
+
+		httpLis, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", svc.HTTPPort+offset))
+		if err != nil {
+			p.Close()
+			return nil, fmt.Errorf("failed to listen for %s HTTP: %w", svc.DisplayName, err)
+		}
+		target, _ := url.Parse(fmt.Sprintf("http://localhost:%d", svc.HTTPPort))
+		httpProxy := &http.Server{Handler: newHTTP(svc, target)}
+		go func() { p.errs <- httpProxy.Serve(httpLis) }()
+		p.closers = append(p.closers, func() { httpProxy.Close() })
+
+		color.Cyan("  %-15s grpc://%s → %s, http://%s → %d",
+			svc.DisplayName+":", grpcLis.Addr(), svc.GRPCAddr(), httpLis.Addr(), svc.HTTPPort)
+	}
+
+	return p, nil
+}
+
+// Errors receives an error if any proxy stops serving
+func (p *dataPlaneProxies) Errors() <-chan error {
+	return p.errs
+}
+
+// Close stops all proxies and upstream connections
+func (p *dataPlaneProxies) Close() {
+	for i := len(p.closers) - 1; i >= 0; i-- {
+		p.closers[i]()
+	}
+}
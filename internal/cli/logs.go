@@ -1,65 +1,361 @@
 package cli
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
 
+	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/logs"
 )
 
 var (
-	logsFollow bool
-	logsTail   int
-	logsSince  string
+	logsFollow     bool
+	logsTail       int
+	logsSince      string
+	logsLevel      string
+	logsPrincipal  string
+	logsPermission string
+	logsDecision   string
+	logsGrep       string
+	logsJSON       bool
+	logsFiles      []string
 )
 
+var logsServices = []string{"iam", "secret-manager", "kms"}
+
 var logsCmd = &cobra.Command{
-	Use:   "logs [service]",
+	Use:   "logs [service...]",
 	Short: "Show logs from services",
 	Long: `Show logs from emulator services.
 
 Without arguments, shows logs from all services.
-Specify a service name to show logs from that service only.
+Specify service names to show logs from those services only.
+
+Log lines are parsed into structured records (authorization decisions,
+slog output, plain text) and interleaved across services by timestamp.
+Filters narrow the output to the lines you care about; when any filter is
+set and --tail is not given, the full log is searched.
+
+Use --file to read saved logs instead of the running stack, e.g. the
+output of 'start --native' or an IAM_TRACE_OUTPUT trace file.
 
 Services: iam, secret-manager, kms`,
-	ValidArgs: []string{"iam", "secret-manager", "kms"},
+	ValidArgs: logsServices,
+	Example: `  # Find denied requests
+  gcp-emulator logs --decision=deny
+
+  # Everything one principal did with secrets
+  gcp-emulator logs --principal=alice@example.com --permission='secretmanager.*'
+
+  # Warnings and errors from KMS as JSON
+  gcp-emulator logs kms --level=warn --json`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		args = buildLogsArgs(args)
+		filter, err := buildLogsFilter()
+		if err != nil {
+			return err
+		}
+
+		services := args
+		if len(services) == 0 {
+			services = logsServices
+		}
+
+		tail := logsTail
+		if filterActive(filter) && !cmd.Flags().Changed("tail") {
+			tail = 0
+		}
 
-		dcCmd := exec.Command("docker-compose", args...)
-		dcCmd.Stdout = os.Stdout
-		dcCmd.Stderr = os.Stderr
+		if len(logsFiles) > 0 {
+			return showLogFiles(logsFiles, args, filter)
+		}
 
-		return dcCmd.Run()
+		opts := docker.LogsOptions{Follow: logsFollow, Tail: tail, Since: logsSince}
+		if logsFollow {
+			return followLogs(services, opts, filter)
+		}
+
+		return showLogs(services, opts, filter)
 	},
 }
 
-func buildLogsArgs(services []string) []string {
-	args := []string{"logs"}
+func buildLogsFilter() (logs.Filter, error) {
+	filter := logs.Filter{
+		Principal:  logsPrincipal,
+		Permission: logsPermission,
+	}
+
+	level, ok := logs.ParseLevel(logsLevel)
+	if !ok {
+		return filter, fmt.Errorf("invalid --level: %s (must be debug, info, warn, or error)", logsLevel)
+	}
+	filter.MinLevel = level
+	if logsLevel == "" {
+		filter.MinLevel = logs.LevelDebug
+	}
+
+	switch strings.ToLower(logsDecision) {
+	case "":
+	case "allow", "deny":
+		filter.Decision = strings.ToUpper(logsDecision)
+	default:
+		return filter, fmt.Errorf("invalid --decision: %s (must be allow or deny)", logsDecision)
+	}
+
+	if logsGrep != "" {
+		re, err := regexp.Compile(logsGrep)
+		if err != nil {
+			return filter, fmt.Errorf("invalid --grep pattern: %w", err)
+		}
+		filter.Grep = re
+	}
+
+	return filter, nil
+}
+
+func filterActive(f logs.Filter) bool {
+	return f.MinLevel > logs.LevelDebug || f.Principal != "" || f.Permission != "" || f.Decision != "" || f.Grep != nil
+}
+
+// showLogs reads each service's logs, then prints them merged by timestamp
+func showLogs(services []string, opts docker.LogsOptions, filter logs.Filter) error {
+	streams := make([][]logs.Record, len(services))
+	errs := make([]error, len(services))
+
+	var wg sync.WaitGroup
+	for i, service := range services {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
 
-	if logsFollow {
-		args = append(args, "--follow")
+			output, err := docker.LogsCommand(service, opts).Output()
+			if err != nil {
+				var exitErr *exec.ExitError
+				if errors.As(err, &exitErr) {
+					err = fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+				}
+				errs[i] = fmt.Errorf("failed to read %s logs: %w", service, err)
+				return
+			}
+			streams[i] = parseLines(service, bytes.NewReader(output))
+		}()
 	}
+	wg.Wait()
 
-	if logsTail > 0 {
-		args = append(args, "--tail", fmt.Sprintf("%d", logsTail))
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
 	}
 
-	if logsSince != "" {
-		args = append(args, "--since", logsSince)
+	return printRecords(logs.Merge(streams...), filter)
+}
+
+// showLogFiles reads saved logs; the service defaults to the file name.
+// If services are given, only their records are shown.
+func showLogFiles(paths, services []string, filter logs.Filter) error {
+	var streams [][]logs.Record
+
+	for _, path := range paths {
+		if path == "-" {
+			streams = append(streams, parseLines("", os.Stdin))
+			continue
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open log file: %w", err)
+		}
+		service := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		streams = append(streams, parseLines(service, f))
+		f.Close()
 	}
 
-	// Add service names if specified
+	records := logs.Merge(streams...)
 	if len(services) > 0 {
-		args = append(args, services...)
+		kept := records[:0]
+		for _, r := range records {
+			if slices.Contains(services, r.Service) {
+				kept = append(kept, r)
+			}
+		}
+		records = kept
+	}
+
+	return printRecords(records, filter)
+}
+
+// followLogs streams all services and prints records as they arrive
+func followLogs(services []string, opts docker.LogsOptions, filter logs.Filter) error {
+	records := make(chan logs.Record)
+	var cmds []*exec.Cmd
+	var wg sync.WaitGroup
+
+	for _, service := range services {
+		cmd := docker.LogsCommand(service, opts)
+		cmd.Stderr = os.Stderr
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return err
+		}
+		if err := cmd.Start(); err != nil {
+			return fmt.Errorf("failed to follow %s logs: %w", service, err)
+		}
+		cmds = append(cmds, cmd)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			scanner := newLineScanner(stdout)
+			for scanner.Scan() {
+				records <- logs.ParseLine(service, scanner.Text())
+			}
+			_ = cmd.Wait()
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(records)
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case r, ok := <-records:
+			if !ok {
+				return nil
+			}
+			if filter.Match(r) {
+				if err := printRecord(r); err != nil {
+					return err
+				}
+			}
+		case <-signals:
+			for _, cmd := range cmds {
+				_ = cmd.Process.Kill()
+			}
+			return nil
+		}
+	}
+}
+
+func parseLines(service string, r io.Reader) []logs.Record {
+	var records []logs.Record
+
+	scanner := newLineScanner(r)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		records = append(records, logs.ParseLine(service, scanner.Text()))
+	}
+
+	return records
+}
+
+func newLineScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	return scanner
+}
+
+func printRecords(records []logs.Record, filter logs.Filter) error {
+	for _, r := range records {
+		if !filter.Match(r) {
+			continue
+		}
+		if err := printRecord(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func printRecord(r logs.Record) error {
+	if logsJSON {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return fmt.Errorf("failed to encode log record: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	ts := "            "
+	if !r.Time.IsZero() {
+		ts = r.Time.Local().Format("15:04:05.000")
+	}
+	prefix := fmt.Sprintf("%s %s", ts, color.CyanString("%-14s", r.Service))
+
+	if r.IsAuthz() {
+		decision := color.GreenString("%-5s", r.Decision)
+		if r.Decision == logs.DecisionDeny {
+			decision = color.RedString("%-5s", r.Decision)
+		}
+
+		line := fmt.Sprintf("%s %s %s → %s on %s", prefix, decision, r.Principal, r.Permission, r.Resource)
+		if r.Reason != "" {
+			line += fmt.Sprintf(" (%s)", r.Reason)
+		}
+		fmt.Println(line)
+		return nil
+	}
+
+	var level string
+	switch {
+	case r.Level >= logs.LevelError:
+		level = color.RedString("%-5s", r.Level)
+	case r.Level == logs.LevelWarn:
+		level = color.YellowString("%-5s", r.Level)
+	default:
+		level = fmt.Sprintf("%-5s", r.Level)
+	}
+
+	line := fmt.Sprintf("%s %s %s", prefix, level, r.Message)
+	if len(r.Fields) > 0 {
+		keys := make([]string, 0, len(r.Fields))
+		for k := range r.Fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			line += fmt.Sprintf(" %s=%s", k, r.Fields[k])
+		}
 	}
+	fmt.Println(line)
 
-	return args
+	return nil
 }
 
 func init() {
 	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Follow log output")
-	logsCmd.Flags().IntVar(&logsTail, "tail", 50, "Number of lines to show from end of logs")
+	logsCmd.Flags().IntVar(&logsTail, "tail", 50, "Number of lines to show from end of each service's logs (0 for all)")
 	logsCmd.Flags().StringVar(&logsSince, "since", "", "Show logs since timestamp (e.g. 2m, 1h)")
+	logsCmd.Flags().StringVar(&logsLevel, "level", "", "Minimum level: debug, info, warn, error")
+	logsCmd.Flags().StringVar(&logsPrincipal, "principal", "", "Only authorization records whose principal contains this")
+	logsCmd.Flags().StringVar(&logsPermission, "permission", "", "Only records for this permission (supports globs like 'secretmanager.*')")
+	logsCmd.Flags().StringVar(&logsDecision, "decision", "", "Only authorization decisions: allow or deny")
+	logsCmd.Flags().StringVar(&logsGrep, "grep", "", "Only lines matching this regular expression")
+	logsCmd.Flags().BoolVar(&logsJSON, "json", false, "Output records as JSON lines")
+	logsCmd.Flags().StringArrayVar(&logsFiles, "file", nil, "Read logs from a file instead of the stack ('-' for stdin, repeatable)")
 }
@@ -3,6 +3,7 @@ package cli
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
@@ -23,8 +24,21 @@ var policyValidateCmd = &cobra.Command{
 	Long: `Validate policy file syntax and structure.
 
 Without arguments, validates ./policy.yaml
-Specify a file path to validate a different file.`,
+Specify a file path to validate a different file.
+
+With --env, validates the policy rendered with that environment's overlay
+(see policy render). Errors point to the file and line they come from.`,
+	Example: `  gcp-emulator policy validate
+  gcp-emulator policy validate policy.yaml --env ci`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		env, _ := cmd.Flags().GetString("env")
+		varFlags, _ := cmd.Flags().GetStringArray("var")
+
+		vars, err := parseVariables(varFlags)
+		if err != nil {
+			return err
+		}
+
 		cfg, err := config.Load()
 		if err != nil {
 			return err
@@ -35,23 +49,36 @@ Specify a file path to validate a different file.`,
 			policyFile = args[0]
 		}
 
-		color.Cyan("Validating %s...", policyFile)
-
-		// Load policy
-		pol, err := policy.Load(policyFile)
+		// Load and render policy
+		rendered, err := policy.Render(policyFile, env, vars)
 		if err != nil {
 			color.Red("✗ Failed to load policy: %v", err)
 			return err
 		}
+		pol := rendered.Policy
+
+		color.Cyan("Validating %s...", strings.Join(rendered.Files, " + "))
 
 		// Validate
-		result := policy.Validate(pol)
+		result := rendered.Validate()
 
 		if result.Valid {
 			color.Green("✓ Policy is valid")
 			fmt.Printf("\n%d roles defined\n", len(pol.Roles))
 			fmt.Printf("%d groups defined\n", len(pol.Groups))
+			if len(pol.Organizations) > 0 || len(pol.Folders) > 0 {
+				fmt.Printf("%d organizations, %d folders\n", len(pol.Organizations), len(pol.Folders))
+			}
 			fmt.Printf("%d projects configured\n", len(pol.Projects))
+			if len(pol.Resources) > 0 {
+				fmt.Printf("%d resources with bindings\n", len(pol.Resources))
+			}
+			if len(pol.DenyPolicies) > 0 {
+				fmt.Printf("%d deny policies\n", len(pol.DenyPolicies))
+			}
+			if len(pol.Boundaries) > 0 {
+				fmt.Printf("%d access boundaries\n", len(pol.Boundaries))
+			}
 
 			// Show warnings if any
 			for _, err := range result.Errors {
@@ -253,6 +280,9 @@ func init() {
 	policyCmd.AddCommand(policyValidateCmd)
 	policyCmd.AddCommand(policyInitCmd)
 
+	policyValidateCmd.Flags().String("env", "", "Environment overlay to merge onto the policy (e.g. ci loads policy.ci.yaml)")
+	policyValidateCmd.Flags().StringArray("var", nil, "Set a variable, as name=value (overrides the policy files)")
+
 	policyInitCmd.Flags().String("template", "basic", "Template to use (basic|advanced|ci)")
 	policyInitCmd.Flags().BoolP("force", "f", false, "Overwrite existing policy.yaml")
 	policyInitCmd.Flags().String("output", "policy.yaml", "Output file path")
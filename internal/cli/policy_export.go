@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+var policyExportCmd = &cobra.Command{
+	Use:   "export [file]",
+	Short: "Export a policy as Terraform to promote it to real projects",
+	Long: `Export the policy in a format other tools consume, so that the policy
+tested against the emulator is the one applied to real projects.
+
+--format terraform renders Terraform configuration for the Google provider:
+
+  - each custom role becomes a google_project_iam_custom_role in every
+    project that binds it (google_organization_iam_custom_role for
+    organization and folder bindings), and bindings refer to it by name
+  - bindings become google_project_iam_binding resources, merged by role
+    and condition, with their condition blocks; secret, key ring, crypto
+    key, service account, folder and organization bindings use the
+    matching google_*_iam_binding resource
+  - groups defined in the policy are expanded to their members, nested
+    groups included; other groups are kept if they are named by email
+
+Binding resources are authoritative for their role: applying them removes
+members of the role that the policy does not list. --iam-resource member
+renders one non-authoritative google_*_iam_member per member instead.
+
+Deny policies, access boundaries and custom roles that nothing binds are
+not exported; they are reported as warnings.
+
+--format yaml and json write the rendered policy in that format, whatever
+the extension of --output. Variables and --env are
+resolved as by policy render. The export is printed to stdout, or written
+to --output, which is overwritten.`,
+	Example: `  gcp-emulator policy export --format terraform --output iam.tf
+  gcp-emulator policy export policy.yaml --env prod --format terraform --iam-resource member
+  gcp-emulator policy export --format json --output policy.json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		iamResource, _ := cmd.Flags().GetString("iam-resource")
+		env, _ := cmd.Flags().GetString("env")
+		varFlags, _ := cmd.Flags().GetStringArray("var")
+		output, _ := cmd.Flags().GetString("output")
+
+		if format != "terraform" && format != "yaml" && format != "json" {
+			return fmt.Errorf("invalid --format: %s (must be terraform, yaml or json)", format)
+		}
+		if iamResource != "binding" && iamResource != "member" {
+			return fmt.Errorf("invalid --iam-resource: %s (must be binding or member)", iamResource)
+		}
+
+		vars, err := parseVariables(varFlags)
+		if err != nil {
+			return err
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		policyFile := cfg.PolicyFile
+		if len(args) > 0 {
+			policyFile = args[0]
+		}
+
+		rendered, err := policy.Render(policyFile, env, vars)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, color.RedString("✗ Failed to load policy: %v", err))
+			return err
+		}
+
+		if !printRenderValidation(rendered.Validate()) {
+			return fmt.Errorf("policy is invalid")
+		}
+
+		if format != "terraform" {
+			// Written in the requested format whatever the file extension,
+			// unlike policy.Save
+			var data []byte
+			if format == "json" {
+				data, err = json.MarshalIndent(rendered.Policy, "", "  ")
+				data = append(data, '\n')
+			} else {
+				data, err = yaml.Marshal(rendered.Policy)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to encode %s: %w", strings.ToUpper(format), err)
+			}
+
+			if output == "" {
+				os.Stdout.Write(data)
+				return nil
+			}
+			if err := os.WriteFile(output, data, 0644); err != nil {
+				color.Red("✗ Failed to write policy: %v", err)
+				return err
+			}
+			color.Green("✓ Exported %s to %s", strings.Join(rendered.Files, " + "), output)
+			return nil
+		}
+
+		export := policy.ExportTerraform(rendered.Policy, policy.TerraformOptions{Members: iamResource == "member"})
+		for _, msg := range export.Warnings {
+			fmt.Fprintln(os.Stderr, color.YellowString("⚠ %s", msg))
+		}
+
+		if output == "" {
+			os.Stdout.Write(export.Config)
+			return nil
+		}
+
+		if err := os.WriteFile(output, export.Config, 0644); err != nil {
+			color.Red("✗ Failed to write Terraform configuration: %v", err)
+			return err
+		}
+
+		color.Green("✓ Exported %s to %s", strings.Join(rendered.Files, " + "), output)
+		fmt.Printf("\n%d custom roles\n", export.Roles)
+		fmt.Printf("%d IAM %s resources\n", export.Bindings, iamResource)
+
+		color.Cyan("\nNext steps:")
+		color.Cyan("  1. Review the plan against the real project:")
+		color.Cyan("     terraform init && terraform plan")
+		color.Cyan("  2. Apply it once the plan only changes what the policy does")
+
+		return nil
+	},
+}
+
+func init() {
+	policyCmd.AddCommand(policyExportCmd)
+
+	policyExportCmd.Flags().String("format", "terraform", "Export format: terraform, yaml or json")
+	policyExportCmd.Flags().String("iam-resource", "binding", "Terraform IAM resources: binding (authoritative per role) or member")
+	policyExportCmd.Flags().String("env", "", "Environment overlay to merge onto the policy (e.g. prod loads policy.prod.yaml)")
+	policyExportCmd.Flags().StringArray("var", nil, "Set a variable, as name=value (overrides the policy files)")
+	policyExportCmd.Flags().StringP("output", "o", "", "Write the export to a file instead of stdout")
+}
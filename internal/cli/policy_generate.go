@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/audit"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+var policyGenerateCmd = &cobra.Command{
+	Use:   "generate --from-audit <file>",
+	Short: "Generate a least-privilege policy from an audit log",
+	Long: `Generate a minimal policy from observed permission checks.
+
+For every principal and project in the audit log, a custom role is created
+containing exactly the permissions the principal used in that project, and
+bound to that principal in that project. A check made through an
+impersonation chain is granted to the last service account in the chain,
+and each caller gets roles/iam.serviceAccountTokenCreator on the service
+account it impersonates.
+
+Typical workflow: run the test suite once in permissive mode with tracing
+enabled, then generate a strict policy for CI.
+
+Only allowed checks are used unless --include-denied is set, so requests
+the tests expect to be denied stay denied. Checks without a principal
+cannot be attributed and are skipped.`,
+	Example: `  gcp-emulator config set trace true
+  gcp-emulator start --mode=permissive
+  go test ./...
+  gcp-emulator policy generate --from-audit audit.jsonl --output policy.ci.yaml`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from, _ := cmd.Flags().GetString("from-audit")
+		output, _ := cmd.Flags().GetString("output")
+		force, _ := cmd.Flags().GetBool("force")
+		includeDenied, _ := cmd.Flags().GetBool("include-denied")
+		sinceFlag, _ := cmd.Flags().GetString("since")
+
+		if from == "" {
+			return fmt.Errorf("--from-audit is required")
+		}
+
+		if _, err := os.Stat(output); err == nil && !force {
+			color.Red("✗ %s already exists", output)
+			color.Yellow("  Use --force to overwrite")
+			return fmt.Errorf("file exists")
+		}
+
+		since, err := audit.ParseSince(sinceFlag, time.Now())
+		if err != nil {
+			return err
+		}
+
+		events, err := audit.ReadFile(from)
+		if err != nil {
+			return err
+		}
+		events = audit.Filter(events, audit.Query{Since: since})
+
+		result := audit.Generate(events, audit.GenerateOptions{IncludeDenied: includeDenied})
+		if result.Used == 0 {
+			color.Red("✗ No attributable permission checks in %s", from)
+			return fmt.Errorf("nothing to generate")
+		}
+
+		if err := policy.Save(result.Policy, output); err != nil {
+			color.Red("✗ Failed to write policy: %v", err)
+			return err
+		}
+
+		color.Green("✓ Generated %s from %d checks", output, result.Used)
+		fmt.Printf("\n%d roles defined\n", len(result.Policy.Roles))
+		fmt.Printf("%d projects configured\n", len(result.Policy.Projects))
+
+		if result.NoPrincipal > 0 {
+			color.Yellow("\n⚠ Skipped %d checks without a principal", result.NoPrincipal)
+		}
+		for _, resource := range result.Unscoped {
+			color.Yellow("⚠ Skipped resource outside any project: %s", resource)
+		}
+
+		validation := policy.Validate(result.Policy)
+		if !validation.Valid {
+			color.Yellow("\n⚠ Generated policy has validation errors:")
+			for _, msg := range validation.Errors {
+				color.Yellow("  %s", msg)
+			}
+		}
+
+		color.Cyan("\nNext steps:")
+		color.Cyan("  1. Review %s", output)
+		color.Cyan("  2. Run your tests in strict mode against it:")
+		color.Cyan("     gcp-emulator config set policy-file %s", output)
+		color.Cyan("     gcp-emulator start --mode=strict")
+
+		return nil
+	},
+}
+
+func init() {
+	policyCmd.AddCommand(policyGenerateCmd)
+
+	policyGenerateCmd.Flags().String("from-audit", "", "Audit log to derive the policy from (required)")
+	policyGenerateCmd.Flags().String("output", "policy.generated.yaml", "Output file path (.yaml, .yml or .json)")
+	policyGenerateCmd.Flags().BoolP("force", "f", false, "Overwrite an existing output file")
+	policyGenerateCmd.Flags().Bool("include-denied", false, "Also grant permissions that were denied")
+	policyGenerateCmd.Flags().String("since", "", "Only use checks since a duration ago (10m, 2h) or RFC 3339 time")
+}
@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+var policyImportCmd = &cobra.Command{
+	Use:   "import [--iam-policy <target>=<file>...] [--role <file>...] [--terraform <file>...]",
+	Short: "Import production IAM policies from gcloud exports or Terraform",
+	Long: `Convert gcloud exports or Terraform state of production IAM into a
+control plane policy, to mirror production access locally.
+
+--iam-policy takes the output of get-iam-policy --format=json (bindings,
+etag, version and conditions) for a target: a project ID, or the name of a
+folder, organization, service account or resource (a secret, key ring or
+crypto key). The export does not name its target, so it is given before
+the file:
+
+  gcloud projects get-iam-policy my-prod --format=json > my-prod.json
+  --iam-policy my-prod=my-prod.json
+
+--role takes the output of gcloud iam roles describe --format=json, or a
+JSON array of such roles. Custom roles are renamed:
+projects/my-prod/roles/appDeployer becomes roles/custom.appDeployer, in
+bindings too.
+
+--terraform takes the output of terraform show -json, for the state or a
+saved plan (terraform plan -out=tfplan; terraform show -json tfplan). It
+imports google_project_iam_custom_role and
+google_organization_iam_custom_role, and the google_*_iam_binding, _member
+and _policy resources of projects, folders, organizations, service
+accounts, secrets, key rings and crypto keys. Other IAM resources, and
+values a plan does not know until apply, are reported and skipped.
+
+What the emulator cannot represent is reported and skipped: permissions of
+services it does not emulate, unsupported member types, and audit configs.
+Groups are created empty, since exports do not list their members.`,
+	Example: `  gcp-emulator policy import --iam-policy my-prod=my-prod-iam.json
+  gcp-emulator policy import \
+    --iam-policy my-prod=my-prod-iam.json \
+    --iam-policy projects/my-prod/secrets/db-password=db-password-iam.json \
+    --role app-deployer-role.json --output policy.prod.yaml
+  terraform show -json > state.json
+  gcp-emulator policy import --terraform state.json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		iamPolicies, _ := cmd.Flags().GetStringArray("iam-policy")
+		roleFiles, _ := cmd.Flags().GetStringArray("role")
+		terraformFiles, _ := cmd.Flags().GetStringArray("terraform")
+		output, _ := cmd.Flags().GetString("output")
+		force, _ := cmd.Flags().GetBool("force")
+
+		if len(iamPolicies) == 0 && len(roleFiles) == 0 && len(terraformFiles) == 0 {
+			return fmt.Errorf("--iam-policy, --role or --terraform is required")
+		}
+
+		if _, err := os.Stat(output); err == nil && !force {
+			color.Red("✗ %s already exists", output)
+			color.Yellow("  Use --force to overwrite")
+			return fmt.Errorf("file exists")
+		}
+
+		importer := policy.NewImporter()
+		for _, file := range roleFiles {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("failed to read role export: %w", err)
+			}
+			if err := importer.ImportRoles(file, data); err != nil {
+				color.Red("✗ %v", err)
+				return err
+			}
+		}
+		for _, flag := range iamPolicies {
+			target, file, ok := strings.Cut(flag, "=")
+			if !ok || target == "" || file == "" {
+				return fmt.Errorf("invalid --iam-policy %q (expected target=file, e.g. my-project=policy.json)", flag)
+			}
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("failed to read IAM policy export: %w", err)
+			}
+			if err := importer.ImportIAMPolicy(file, target, data); err != nil {
+				color.Red("✗ %v", err)
+				return err
+			}
+		}
+
+		for _, file := range terraformFiles {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("failed to read Terraform output: %w", err)
+			}
+			if err := importer.ImportTerraform(file, data); err != nil {
+				color.Red("✗ %v", err)
+				return err
+			}
+		}
+
+		pol := importer.Finish()
+		if err := policy.Save(pol, output); err != nil {
+			color.Red("✗ Failed to write policy: %v", err)
+			return err
+		}
+
+		color.Green("✓ Imported %d bindings into %s", len(policy.AllBindings(pol)), output)
+		fmt.Printf("\n%d roles defined\n", len(pol.Roles))
+		fmt.Printf("%d groups defined\n", len(pol.Groups))
+		fmt.Printf("%d projects configured\n", len(pol.Projects))
+		if len(pol.Resources) > 0 {
+			fmt.Printf("%d resources with bindings\n", len(pol.Resources))
+		}
+
+		if len(importer.Warnings) > 0 {
+			fmt.Println()
+			for _, msg := range importer.Warnings {
+				color.Yellow("⚠ %s", msg)
+			}
+		}
+
+		validation := policy.Validate(pol)
+		if !validation.Valid {
+			color.Yellow("\n⚠ Imported policy has validation errors:")
+			for _, msg := range validation.Errors {
+				color.Yellow("  %s", msg)
+			}
+		}
+
+		color.Cyan("\nNext steps:")
+		color.Cyan("  1. Review %s and add members to the imported groups", output)
+		color.Cyan("  2. Reproduce a permission check against it:")
+		color.Cyan("     gcp-emulator test permission <principal> <resource> <permission> --policy %s -v", output)
+
+		return nil
+	},
+}
+
+func init() {
+	policyCmd.AddCommand(policyImportCmd)
+
+	policyImportCmd.Flags().StringArray("iam-policy", nil, "IAM policy export for a target, as target=file (repeatable)")
+	policyImportCmd.Flags().StringArray("role", nil, "Custom role export from gcloud iam roles describe, or an array of them (repeatable)")
+	policyImportCmd.Flags().StringArray("terraform", nil, "terraform show -json output of a state or saved plan (repeatable)")
+	policyImportCmd.Flags().StringP("output", "o", "policy.imported.yaml", "Output file path (.yaml, .yml or .json)")
+	policyImportCmd.Flags().BoolP("force", "f", false, "Overwrite an existing output file")
+}
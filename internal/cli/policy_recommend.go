@@ -0,0 +1,208 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/audit"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+var policyRecommendCmd = &cobra.Command{
+	Use:   "recommend --from-audit <file> [policy-file]",
+	Short: "Report grants never exercised in an audit log",
+	Long: `Compare a policy against an audit log of actual usage.
+
+Every allowed check in the audit log is re-evaluated against the policy to
+find the binding, member and role permission that granted it. Bindings,
+members and permissions that never granted anything over the window are
+reported as unused, and a trimmed policy without them is suggested:
+
+  - unused bindings and members are removed
+  - custom roles are reduced to the permissions actually used
+  - custom roles no longer bound are removed
+
+Built-in roles cannot be trimmed; their unused permissions are reported
+so you can replace them with a narrower custom role.
+
+Without a policy file argument, the configured policy file is used.`,
+	Example: `  gcp-emulator policy recommend --from-audit audit.jsonl
+  gcp-emulator policy recommend --from-audit audit.jsonl --since 24h --diff
+  gcp-emulator policy recommend --from-audit audit.jsonl --output policy.trimmed.yaml`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from, _ := cmd.Flags().GetString("from-audit")
+		output, _ := cmd.Flags().GetString("output")
+		force, _ := cmd.Flags().GetBool("force")
+		showDiff, _ := cmd.Flags().GetBool("diff")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		sinceFlag, _ := cmd.Flags().GetString("since")
+
+		if from == "" {
+			return fmt.Errorf("--from-audit is required")
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		policyFile := cfg.PolicyFile
+		if len(args) > 0 {
+			policyFile = args[0]
+		}
+
+		if output != "" && !force {
+			if _, err := os.Stat(output); err == nil {
+				color.Red("✗ %s already exists", output)
+				color.Yellow("  Use --force to overwrite")
+				return fmt.Errorf("file exists")
+			}
+		}
+
+		pol, err := policy.Load(policyFile)
+		if err != nil {
+			color.Red("✗ Failed to load policy: %v", err)
+			return err
+		}
+
+		since, err := audit.ParseSince(sinceFlag, time.Now())
+		if err != nil {
+			return err
+		}
+
+		events, err := audit.ReadFile(from)
+		if err != nil {
+			return err
+		}
+		events = audit.Filter(events, audit.Query{Since: since})
+
+		report := audit.AnalyzeUsage(pol, events)
+		trimmed := report.Trim(pol)
+
+		diff, err := policy.Diff(policyFile, pol, "trimmed", trimmed)
+		if err != nil {
+			return err
+		}
+
+		if output != "" {
+			if err := policy.Save(trimmed, output); err != nil {
+				color.Red("✗ Failed to write policy: %v", err)
+				return err
+			}
+		}
+
+		if asJSON {
+			return printJSON(struct {
+				*audit.UsageReport
+				Diff string `json:"diff,omitempty"`
+			}{report, diff})
+		}
+
+		printUsageReport(report, policyFile, from)
+
+		if showDiff {
+			fmt.Println()
+			if diff == "" {
+				color.Green("✓ Trimmed policy is identical")
+			} else {
+				printDiff(diff)
+			}
+		}
+
+		if output != "" {
+			color.Green("\n✓ Wrote trimmed policy to %s", output)
+		} else if diff != "" && !showDiff {
+			color.Cyan("\nUse --diff to see the suggested changes, or --output to write the trimmed policy")
+		}
+
+		return nil
+	},
+}
+
+func printUsageReport(report *audit.UsageReport, policyFile, auditFile string) {
+	color.Cyan("Usage of %s in %s", policyFile, auditFile)
+	if report.Events == 0 {
+		color.Yellow("⚠ No allowed checks in the window; everything is reported as unused")
+	} else {
+		fmt.Printf("%d allowed checks from %s to %s\n",
+			report.Events,
+			report.From.Local().Format("2006-01-02 15:04:05"),
+			report.To.Local().Format("2006-01-02 15:04:05"))
+	}
+
+	fmt.Println()
+	color.Cyan("Bindings:")
+	for _, b := range report.Bindings {
+		label := b.Label() + " " + b.Role
+		switch {
+		case !b.Used():
+			color.Red("  ✗ %s: never used", label)
+		case len(b.UnusedMembers) > 0:
+			color.Yellow("  ⚠ %s: %d grants, unused members: %s", label, b.Grants, strings.Join(b.UnusedMembers, ", "))
+		default:
+			color.Green("  ✓ %s: %d grants", label, b.Grants)
+		}
+	}
+
+	fmt.Println()
+	color.Cyan("Roles:")
+	for _, r := range report.Roles {
+		kind := "built-in"
+		if r.Custom {
+			kind = "custom"
+		}
+		label := fmt.Sprintf("%s (%s)", r.Role, kind)
+
+		switch {
+		case !r.Bound:
+			color.Red("  ✗ %s: not bound anywhere", label)
+		case !r.Used():
+			color.Red("  ✗ %s: no permissions used", label)
+		case len(r.UnusedPermissions) > 0:
+			color.Yellow("  ⚠ %s: %d of %d permissions used", label,
+				len(r.UsedPermissions), len(r.UsedPermissions)+len(r.UnusedPermissions))
+		default:
+			color.Green("  ✓ %s: all %d permissions used", label, len(r.UsedPermissions))
+			continue
+		}
+
+		for _, perm := range r.UnusedPermissions {
+			fmt.Printf("      unused: %s\n", perm)
+		}
+	}
+}
+
+func printDiff(diff string) {
+	for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			color.New(color.Bold).Println(line)
+		case strings.HasPrefix(line, "+"):
+			color.Green("%s", line)
+		case strings.HasPrefix(line, "-"):
+			color.Red("%s", line)
+		case strings.HasPrefix(line, "@@"):
+			color.Cyan("%s", line)
+		default:
+			fmt.Println(line)
+		}
+	}
+}
+
+func init() {
+	policyCmd.AddCommand(policyRecommendCmd)
+
+	policyRecommendCmd.Flags().String("from-audit", "", "Audit log of actual usage (required)")
+	policyRecommendCmd.Flags().String("since", "", "Only use checks since a duration ago (10m, 2h) or RFC 3339 time")
+	policyRecommendCmd.Flags().StringP("output", "o", "", "Write the trimmed policy to a file (.yaml, .yml or .json)")
+	policyRecommendCmd.Flags().BoolP("force", "f", false, "Overwrite an existing output file")
+	policyRecommendCmd.Flags().Bool("diff", false, "Show a diff between the policy and the trimmed policy")
+	policyRecommendCmd.Flags().Bool("json", false, "Output the report as JSON")
+}
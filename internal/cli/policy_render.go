@@ -0,0 +1,143 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+var policyRenderCmd = &cobra.Command{
+	Use:   "render [file]",
+	Short: "Resolve a policy's variables and environment overlay",
+	Long: `Render a policy template to a plain policy.
+
+Variables are declared under a top-level variables key and referenced as
+${name} anywhere in the policy:
+
+  variables:
+    project: test-project
+    ci_sa: ci@test-project.iam.gserviceaccount.com
+  projects:
+    ${project}:
+      bindings:
+        - role: roles/custom.ciRunner
+          members:
+            - serviceAccount:${ci_sa}
+
+References are resolved in templates only: files that declare variables,
+or renders with --env or --var. There, $${ is a literal ${.
+
+With --env, the overlay file for that environment (policy.ci.yaml for
+policy.yaml and --env ci) is merged onto the base: its variables override
+the base's, mappings merge key by key, other values (lists included)
+replace the base's, and null removes a key. --var overrides both files.
+
+The rendered policy is validated; errors point to the file and line they
+come from. It is printed to stdout, or written to --output (.yaml, .yml or
+.json), which is overwritten.
+
+Every command that loads a policy resolves its variables, and policy
+validate also takes --env. Render to review the result, or for consumers
+that need a plain policy, such as the IAM emulator container in Docker
+mode.`,
+	Example: `  gcp-emulator policy render --env ci
+  gcp-emulator policy render policy.yaml --env staging --output policy.staging.rendered.yaml
+  gcp-emulator policy render --env ci --var project=ci-1234 --format json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		env, _ := cmd.Flags().GetString("env")
+		varFlags, _ := cmd.Flags().GetStringArray("var")
+		output, _ := cmd.Flags().GetString("output")
+		format, _ := cmd.Flags().GetString("format")
+
+		if format != "yaml" && format != "json" {
+			return fmt.Errorf("invalid --format: %s (must be yaml or json)", format)
+		}
+
+		vars, err := parseVariables(varFlags)
+		if err != nil {
+			return err
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		policyFile := cfg.PolicyFile
+		if len(args) > 0 {
+			policyFile = args[0]
+		}
+
+		rendered, err := policy.Render(policyFile, env, vars)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, color.RedString("✗ Failed to render policy: %v", err))
+			return err
+		}
+
+		if !printRenderValidation(rendered.Validate()) {
+			return fmt.Errorf("rendered policy is invalid")
+		}
+
+		if output != "" {
+			if err := policy.Save(rendered.Policy, output); err != nil {
+				color.Red("✗ Failed to write policy: %v", err)
+				return err
+			}
+			color.Green("✓ Rendered %s to %s", strings.Join(rendered.Files, " + "), output)
+			return nil
+		}
+
+		if format == "json" {
+			return printJSON(rendered.Policy)
+		}
+		data, err := yaml.Marshal(rendered.Policy)
+		if err != nil {
+			return fmt.Errorf("failed to encode YAML: %w", err)
+		}
+		os.Stdout.Write(data)
+		return nil
+	},
+}
+
+// printRenderValidation prints validation errors and warnings to stderr,
+// keeping stdout for the rendered policy, and reports whether it is valid
+func printRenderValidation(result *policy.ValidationResult) bool {
+	for _, msg := range result.Errors {
+		if strings.HasPrefix(msg, "WARNING: ") {
+			fmt.Fprintln(os.Stderr, color.YellowString("⚠ %s", strings.TrimPrefix(msg, "WARNING: ")))
+		} else {
+			fmt.Fprintln(os.Stderr, color.RedString("✗ %s", msg))
+		}
+	}
+	return result.Valid
+}
+
+// parseVariables parses --var name=value flags
+func parseVariables(flags []string) (map[string]string, error) {
+	vars := make(map[string]string, len(flags))
+	for _, flag := range flags {
+		name, value, ok := strings.Cut(flag, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid --var %q (expected name=value)", flag)
+		}
+		vars[name] = value
+	}
+	return vars, nil
+}
+
+func init() {
+	policyCmd.AddCommand(policyRenderCmd)
+
+	policyRenderCmd.Flags().String("env", "", "Environment overlay to merge onto the policy (e.g. ci loads policy.ci.yaml)")
+	policyRenderCmd.Flags().StringArray("var", nil, "Set a variable, as name=value (overrides the policy files)")
+	policyRenderCmd.Flags().StringP("output", "o", "", "Write the rendered policy to a file (.yaml, .yml or .json)")
+	policyRenderCmd.Flags().String("format", "yaml", "Output format for stdout: yaml or json")
+}
@@ -0,0 +1,316 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+var policyShowCmd = &cobra.Command{
+	Use:   "show [file]",
+	Short: "Display current policy",
+	Long: `Display a policy in human-readable form.
+
+Shows roles, groups, the organization and folder hierarchy, project
+bindings and resource bindings. With --project, shows one project: its
+direct bindings (on the project, its service accounts and its resources)
+and the bindings it inherits from its folders and organization, nearest
+first.
+
+With --format yaml or json, prints the policy, or with --project the
+project's direct and inherited bindings.`,
+	Example: `  gcp-emulator policy show
+  gcp-emulator policy show --project test-project
+  gcp-emulator policy show --project test-project --format json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		projectID, _ := cmd.Flags().GetString("project")
+
+		if format != "text" && format != "yaml" && format != "json" {
+			return fmt.Errorf("invalid --format: %s (must be text, yaml, or json)", format)
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		policyFile := cfg.PolicyFile
+		if len(args) > 0 {
+			policyFile = args[0]
+		}
+
+		pol, err := policy.Load(policyFile)
+		if err != nil {
+			color.Red("✗ Failed to load policy: %v", err)
+			return err
+		}
+
+		var out any = pol
+		if projectID != "" {
+			view, err := newProjectView(pol, projectID)
+			if err != nil {
+				color.Red("✗ %v", err)
+				return err
+			}
+			if format == "text" {
+				printProjectView(view)
+				return nil
+			}
+			out = view
+		}
+
+		switch format {
+		case "json":
+			printJSON(out)
+		case "yaml":
+			data, err := yaml.Marshal(out)
+			if err != nil {
+				return fmt.Errorf("failed to encode YAML: %w", err)
+			}
+			os.Stdout.Write(data)
+		default:
+			printPolicy(pol)
+		}
+		return nil
+	},
+}
+
+// projectView is a project's direct and inherited bindings
+type projectView struct {
+	Project string `json:"project" yaml:"project"`
+	// Ancestors are the folders and organization above the project,
+	// nearest first
+	Ancestors       []string                         `json:"ancestors,omitempty" yaml:"ancestors,omitempty"`
+	Bindings        []policy.Binding                 `json:"bindings" yaml:"bindings"`
+	ServiceAccounts map[string]policy.ServiceAccount `json:"serviceAccounts,omitempty" yaml:"serviceAccounts,omitempty"`
+	Resources       map[string]policy.Resource       `json:"resources,omitempty" yaml:"resources,omitempty"`
+	Inherited       []inheritedBindings              `json:"inherited,omitempty" yaml:"inherited,omitempty"`
+}
+
+// inheritedBindings are the bindings on one folder or organization
+type inheritedBindings struct {
+	From     string           `json:"from" yaml:"from"`
+	Bindings []policy.Binding `json:"bindings" yaml:"bindings"`
+}
+
+func newProjectView(pol *policy.Policy, projectID string) (*projectView, error) {
+	project, ok := pol.Projects[projectID]
+	if !ok {
+		return nil, fmt.Errorf("project %s not found in policy", projectID)
+	}
+
+	ancestors, err := policy.Ancestors(pol, project.Parent)
+	if err != nil {
+		return nil, fmt.Errorf("project %s: %w", projectID, err)
+	}
+
+	view := &projectView{
+		Project:         projectID,
+		Ancestors:       ancestors,
+		Bindings:        project.Bindings,
+		ServiceAccounts: project.ServiceAccounts,
+	}
+
+	for name, resource := range pol.Resources {
+		if policy.ProjectFromResource(name) != projectID {
+			continue
+		}
+		if view.Resources == nil {
+			view.Resources = make(map[string]policy.Resource)
+		}
+		view.Resources[name] = resource
+	}
+
+	for _, name := range ancestors {
+		kind, id, _ := strings.Cut(name, "/")
+		bindings := pol.Folders[id].Bindings
+		if kind == "organizations" {
+			bindings = pol.Organizations[id].Bindings
+		}
+		if len(bindings) > 0 {
+			view.Inherited = append(view.Inherited, inheritedBindings{From: name, Bindings: bindings})
+		}
+	}
+
+	return view, nil
+}
+
+func printProjectView(view *projectView) {
+	color.Cyan("Project %s", view.Project)
+	if len(view.Ancestors) > 0 {
+		fmt.Printf("  Hierarchy: %s\n", strings.Join(view.Ancestors, " → "))
+	}
+
+	fmt.Println()
+	color.Cyan("Direct bindings:")
+	if len(view.Bindings) == 0 {
+		fmt.Println("  (none)")
+	}
+	printBindings("  ", view.Bindings)
+
+	for _, email := range sortedNames(view.ServiceAccounts) {
+		fmt.Printf("  Service account %s:\n", email)
+		printBindings("    ", view.ServiceAccounts[email].Bindings)
+	}
+
+	for _, name := range sortedNames(view.Resources) {
+		fmt.Printf("  Resource %s:\n", name)
+		printBindings("    ", view.Resources[name].Bindings)
+	}
+
+	fmt.Println()
+	color.Cyan("Inherited bindings:")
+	if len(view.Inherited) == 0 {
+		fmt.Println("  (none)")
+	}
+	for _, inherited := range view.Inherited {
+		fmt.Printf("  From %s:\n", inherited.From)
+		printBindings("    ", inherited.Bindings)
+	}
+}
+
+func printPolicy(pol *policy.Policy) {
+	color.Cyan("Roles:")
+	for _, name := range sortedNames(pol.Roles) {
+		fmt.Printf("  %s\n", name)
+		for _, perm := range pol.Roles[name].Permissions {
+			fmt.Printf("    - %s\n", perm)
+		}
+	}
+
+	fmt.Println()
+	color.Cyan("Groups:")
+	for _, name := range sortedNames(pol.Groups) {
+		fmt.Printf("  %s\n", name)
+		for _, member := range pol.Groups[name].Members {
+			fmt.Printf("    - %s\n", member)
+		}
+	}
+
+	if len(pol.Organizations) > 0 {
+		fmt.Println()
+		color.Cyan("Organizations:")
+		for _, id := range sortedNames(pol.Organizations) {
+			fmt.Printf("  organizations/%s\n", id)
+			printBindings("    ", pol.Organizations[id].Bindings)
+		}
+	}
+
+	if len(pol.Folders) > 0 {
+		fmt.Println()
+		color.Cyan("Folders:")
+		for _, id := range sortedNames(pol.Folders) {
+			folder := pol.Folders[id]
+			fmt.Printf("  folders/%s (parent: %s)\n", id, folder.Parent)
+			printBindings("    ", folder.Bindings)
+		}
+	}
+
+	fmt.Println()
+	color.Cyan("Projects:")
+	for _, id := range sortedNames(pol.Projects) {
+		project := pol.Projects[id]
+		if project.Parent != "" {
+			fmt.Printf("  %s (parent: %s)\n", id, project.Parent)
+		} else {
+			fmt.Printf("  %s\n", id)
+		}
+		printBindings("    ", project.Bindings)
+
+		for _, email := range sortedNames(project.ServiceAccounts) {
+			fmt.Printf("    Service account %s:\n", email)
+			printBindings("      ", project.ServiceAccounts[email].Bindings)
+		}
+	}
+
+	if len(pol.Resources) > 0 {
+		fmt.Println()
+		color.Cyan("Resources:")
+		for _, name := range sortedNames(pol.Resources) {
+			fmt.Printf("  %s\n", name)
+			printBindings("    ", pol.Resources[name].Bindings)
+		}
+	}
+
+	if len(pol.DenyPolicies) > 0 {
+		fmt.Println()
+		color.Cyan("Deny policies:")
+		for _, name := range sortedNames(pol.DenyPolicies) {
+			deny := pol.DenyPolicies[name]
+			fmt.Printf("  %s (attached to %s)\n", name, deny.AttachmentPoint)
+			for i, rule := range deny.Rules {
+				fmt.Printf("    Rule %d:\n", i+1)
+				fmt.Printf("      %-22s %s\n", "Denied principals:", strings.Join(rule.DeniedPrincipals, ", "))
+				if len(rule.ExceptionPrincipals) > 0 {
+					fmt.Printf("      %-22s %s\n", "Exception principals:", strings.Join(rule.ExceptionPrincipals, ", "))
+				}
+				fmt.Printf("      %-22s %s\n", "Denied permissions:", strings.Join(rule.DeniedPermissions, ", "))
+				if len(rule.ExceptionPermissions) > 0 {
+					fmt.Printf("      %-22s %s\n", "Exception permissions:", strings.Join(rule.ExceptionPermissions, ", "))
+				}
+				if c := rule.DenialCondition; c != nil {
+					fmt.Printf("      %-22s %s\n", "Condition:", c.Expression)
+				}
+			}
+		}
+	}
+
+	if len(pol.Boundaries) > 0 {
+		fmt.Println()
+		color.Cyan("Access boundaries:")
+		for _, name := range sortedNames(pol.Boundaries) {
+			boundary := pol.Boundaries[name]
+			fmt.Printf("  %s\n", name)
+			fmt.Printf("    Principals:  %s\n", strings.Join(boundary.Principals, ", "))
+			if len(boundary.Roles) > 0 {
+				fmt.Printf("    Roles:       %s\n", strings.Join(boundary.Roles, ", "))
+			}
+			if len(boundary.Permissions) > 0 {
+				fmt.Printf("    Permissions: %s\n", strings.Join(boundary.Permissions, ", "))
+			}
+		}
+	}
+}
+
+func printBindings(indent string, bindings []policy.Binding) {
+	for i, binding := range bindings {
+		condition := "none"
+		if c := binding.Condition; c != nil {
+			condition = c.Expression
+			if c.Title != "" {
+				condition = fmt.Sprintf("%s (%s)", c.Title, c.Expression)
+			}
+		}
+
+		fmt.Printf("%sBinding %d:\n", indent, i+1)
+		fmt.Printf("%s  Role:      %s\n", indent, binding.Role)
+		fmt.Printf("%s  Members:   %s\n", indent, strings.Join(binding.Members, ", "))
+		fmt.Printf("%s  Condition: %s\n", indent, condition)
+	}
+}
+
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	policyCmd.AddCommand(policyShowCmd)
+
+	policyShowCmd.Flags().String("format", "text", "Output format: text, yaml, or json")
+	policyShowCmd.Flags().String("project", "", "Show one project with its direct and inherited bindings")
+}
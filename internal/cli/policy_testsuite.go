@@ -0,0 +1,296 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policytest"
+)
+
+var policyTestCmd = &cobra.Command{
+	Use:   "test <suite.yaml>...",
+	Short: "Run policy test suites against a policy",
+	Long: `Run policy-as-code test suites using local evaluation.
+
+A suite is a YAML file listing permission checks and the expected outcome:
+
+  policy: ../policy.yaml   # optional, relative to the suite file
+  tests:
+    - name: developers can read secrets
+      principal: user:alice@example.com
+      resource: projects/test-project/secrets/db-password
+      permission: secretmanager.versions.access
+      expect: allow
+    - name: CI cannot read non-prod secrets
+      principal: serviceAccount:ci@test-project.iam.gserviceaccount.com
+      resource: projects/test-project/secrets/db-password
+      permission: secretmanager.versions.access
+      expect: deny
+      request:
+        time: 2026-01-01T00:00:00Z
+
+Conditions see request.time, resource.name, resource.type,
+resource.service and api.getAttribute(). Cases without a request time are
+evaluated at --time, or at the current time.
+
+The policy is --policy if set, else the suite's policy key, else the
+configured policy file. No emulator needs to be running.
+
+With --coverage, reports which bindings, roles, permissions and condition
+branches (true and false) were exercised by at least one test case. A
+binding is exercised when a case's principal matches a member and the
+role has the case's permission.
+
+Exits non-zero if any test fails, or if total coverage is below
+--coverage-min.`,
+	Example: `  gcp-emulator policy test policy_test.yaml
+  gcp-emulator policy test tests/*.yaml --policy policy.ci.yaml
+  gcp-emulator policy test policy_test.yaml --time 2027-01-01T00:00:00Z
+  gcp-emulator policy test policy_test.yaml --format junit --output policy-tests.xml
+  gcp-emulator policy test policy_test.yaml --coverage-format html --coverage-output coverage.html --coverage-min 80`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		policyFlag, _ := cmd.Flags().GetString("policy")
+		format, _ := cmd.Flags().GetString("format")
+		output, _ := cmd.Flags().GetString("output")
+		coverage, _ := cmd.Flags().GetBool("coverage")
+		coverageFormat, _ := cmd.Flags().GetString("coverage-format")
+		coverageOutput, _ := cmd.Flags().GetString("coverage-output")
+		coverageMin, _ := cmd.Flags().GetFloat64("coverage-min")
+		timeFlag, _ := cmd.Flags().GetString("time")
+
+		if format != "text" && format != "junit" && format != "tap" {
+			return fmt.Errorf("invalid --format: %s (must be text, junit, or tap)", format)
+		}
+		if coverageFormat != "text" && coverageFormat != "json" && coverageFormat != "html" {
+			return fmt.Errorf("invalid --coverage-format: %s (must be text, json, or html)", coverageFormat)
+		}
+		coverage = coverage || cmd.Flags().Changed("coverage-format") || coverageOutput != "" || coverageMin > 0
+
+		now, err := parseTimeFlag(timeFlag)
+		if err != nil {
+			return err
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		results, policies, err := runPolicySuites(args, policyFlag, cfg.PolicyFile, now)
+		if err != nil {
+			color.Red("✗ %v", err)
+			return err
+		}
+
+		var buf bytes.Buffer
+		switch format {
+		case "junit":
+			err = policytest.WriteJUnit(&buf, results)
+		case "tap":
+			err = policytest.WriteTAP(&buf, results)
+		}
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case format == "text":
+			printPolicyTestResults(results)
+		case output != "":
+			if err := os.WriteFile(output, buf.Bytes(), 0644); err != nil {
+				return fmt.Errorf("failed to write %s: %w", output, err)
+			}
+		default:
+			fmt.Print(buf.String())
+		}
+
+		total, failed := 0, 0
+		for _, sr := range results {
+			total += len(sr.Results)
+			failed += sr.Failures()
+		}
+
+		// Keep machine-readable stdout clean
+		if format == "text" || output != "" {
+			if failed > 0 {
+				color.Red("\n✗ %d of %d policy tests failed", failed, total)
+			} else {
+				color.Green("\n✓ All %d policy tests passed", total)
+			}
+			if output != "" {
+				fmt.Printf("Report written to %s\n", output)
+			}
+		}
+
+		var belowMin []*policytest.Coverage
+		if coverage {
+			// Machine-readable test reports on stdout get the coverage on stderr
+			coverageOut := os.Stdout
+			if format != "text" && output == "" {
+				coverageOut = os.Stderr
+			}
+
+			coverages := policyCoverage(results, policies)
+			if err := writeCoverage(coverages, coverageFormat, coverageOutput, coverageOut); err != nil {
+				return err
+			}
+
+			for _, c := range coverages {
+				if c.Total().Percent < coverageMin {
+					belowMin = append(belowMin, c)
+				}
+			}
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("%d policy tests failed", failed)
+		}
+		if len(belowMin) > 0 {
+			for _, c := range belowMin {
+				color.Red("✗ Coverage of %s is %.1f%%, below the required %.1f%%", c.PolicyFile, c.Total().Percent, coverageMin)
+			}
+			return fmt.Errorf("policy test coverage below %.1f%%", coverageMin)
+		}
+		return nil
+	},
+}
+
+// policyCoverage computes coverage per policy file, combining suites that
+// test the same policy
+func policyCoverage(results []*policytest.SuiteResult, policies map[string]*policy.Policy) []*policytest.Coverage {
+	var order []string
+	byPolicy := make(map[string][]policytest.Result)
+	for _, sr := range results {
+		if _, ok := byPolicy[sr.PolicyFile]; !ok {
+			order = append(order, sr.PolicyFile)
+		}
+		byPolicy[sr.PolicyFile] = append(byPolicy[sr.PolicyFile], sr.Results...)
+	}
+
+	coverages := make([]*policytest.Coverage, 0, len(order))
+	for _, policyFile := range order {
+		c := policytest.CoverageFor(policies[policyFile], byPolicy[policyFile])
+		c.PolicyFile = policyFile
+		coverages = append(coverages, c)
+	}
+	return coverages
+}
+
+func writeCoverage(coverages []*policytest.Coverage, format, output string, stdout io.Writer) error {
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "json":
+		err = policytest.WriteCoverageJSON(&buf, coverages)
+	case "html":
+		err = policytest.WriteCoverageHTML(&buf, coverages)
+	default:
+		for _, c := range coverages {
+			buf.WriteString("\n")
+			if err = policytest.WriteCoverageText(&buf, c); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		_, err := stdout.Write(buf.Bytes())
+		return err
+	}
+
+	if err := os.WriteFile(output, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", output, err)
+	}
+	fmt.Fprintf(stdout, "Coverage report written to %s\n", output)
+	return nil
+}
+
+// runPolicySuites loads every suite and runs it against its policy at now.
+// Policies are loaded once per file.
+func runPolicySuites(paths []string, policyFlag, defaultPolicy string, now time.Time) ([]*policytest.SuiteResult, map[string]*policy.Policy, error) {
+	policies := make(map[string]*policy.Policy)
+
+	var results []*policytest.SuiteResult
+	for _, path := range paths {
+		suite, err := policytest.Load(path)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		policyFile := policyFlag
+		if policyFile == "" {
+			policyFile = suite.PolicyPath()
+		}
+		if policyFile == "" {
+			policyFile = defaultPolicy
+		}
+
+		pol, ok := policies[policyFile]
+		if !ok {
+			pol, err = policy.Load(policyFile)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", path, err)
+			}
+			if result := policy.Validate(pol); !result.Valid {
+				return nil, nil, fmt.Errorf("%s: policy %s is invalid: %v", path, policyFile, result.Errors)
+			}
+			policies[policyFile] = pol
+		}
+
+		result := policytest.Run(pol, suite, now)
+		result.PolicyFile = policyFile
+		results = append(results, result)
+	}
+
+	return results, policies, nil
+}
+
+func printPolicyTestResults(results []*policytest.SuiteResult) {
+	for i, sr := range results {
+		if i > 0 {
+			fmt.Println()
+		}
+		color.Cyan("%s (policy: %s)", sr.Suite.Path, sr.PolicyFile)
+
+		for _, r := range sr.Results {
+			if r.Passed() {
+				color.Green("  ✓ %s", r.Case.DisplayName())
+				continue
+			}
+
+			color.Red("  ✗ %s", r.Case.DisplayName())
+			fmt.Printf("      %s\n", r.FailureMessage())
+			fmt.Printf("      %s → %s on %s\n", r.Case.Principal, r.Case.Permission, r.Case.Resource)
+			if len(r.Case.Delegates) > 0 {
+				fmt.Printf("      delegates: %s\n", strings.Join(r.Case.Delegates, " → "))
+			}
+			fmt.Printf("      reason: %s\n", r.Decision.Reason)
+		}
+	}
+}
+
+func init() {
+	policyCmd.AddCommand(policyTestCmd)
+
+	policyTestCmd.Flags().String("policy", "", "Policy file to test (overrides the suite's policy key)")
+	policyTestCmd.Flags().String("format", "text", "Output format: text, junit, or tap")
+	policyTestCmd.Flags().String("time", "", "Evaluate cases without a request time at this RFC 3339 time (default: now)")
+	policyTestCmd.Flags().StringP("output", "o", "", "Write the junit or tap report to a file")
+	policyTestCmd.Flags().Bool("coverage", false, "Report bindings, roles, permissions and condition branches exercised by the tests")
+	policyTestCmd.Flags().String("coverage-format", "text", "Coverage format: text, json, or html (implies --coverage)")
+	policyTestCmd.Flags().String("coverage-output", "", "Write the coverage report to a file (implies --coverage)")
+	policyTestCmd.Flags().Float64("coverage-min", 0, "Fail if total coverage is below this percentage (implies --coverage)")
+}
@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/token"
+)
+
+var (
+	proxyPortOffset   int
+	proxyRequireToken bool
+	proxyQuiet        bool
+)
+
+var proxyCmd = &cobra.Command{
+	Use:   "proxy",
+	Short: "Authenticate bearer tokens in front of the data planes",
+	Long: `Run an authenticating proxy in front of Secret Manager and KMS.
+
+Applications using Google client libraries send OAuth bearer tokens rather
+than an X-Emulator-Principal header. The proxy listens on each data plane's
+gRPC and HTTP ports plus --port-offset, maps the bearer token of every
+request to a principal, injects it as x-emulator-principal (replacing any
+principal the client sent) and forwards the request to the running stack.
+
+Accepted tokens:
+  - emulator access tokens from 'gcp-emulator token <principal>'
+  - JWTs such as ID tokens, using the "principal", "email" or "sub" claim
+    (signatures are not verified)
+
+Invalid or expired tokens are rejected with UNAUTHENTICATED (HTTP 401).
+Requests without a token are forwarded unchanged unless --require-token
+is set.`,
+	Example: `  gcp-emulator start --mode=strict
+  gcp-emulator proxy
+  # point the app at localhost:19090 (Secret Manager) and localhost:19091 (KMS)
+  curl -H "Authorization: Bearer $(gcp-emulator token user:alice@example.com)" \
+    http://localhost:18081/v1/projects/test-project/secrets`,
+	RunE: runProxy,
+}
+
+func init() {
+	proxyCmd.Flags().IntVar(&proxyPortOffset, "port-offset", 10000, "Proxy ports are the service ports plus this offset")
+	proxyCmd.Flags().BoolVar(&proxyRequireToken, "require-token", false, "Reject requests without a bearer token")
+	proxyCmd.Flags().BoolVarP(&proxyQuiet, "quiet", "q", false, "Do not print each authenticated request")
+}
+
+func runProxy(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	opts := token.ProxyOptions{RequireToken: proxyRequireToken}
+	if !proxyQuiet {
+		opts.OnRequest = printProxyRequest
+	}
+
+	color.Cyan("Starting authenticating proxy\n")
+
+	proxies, err := startDataPlaneProxies(cfg, proxyPortOffset,
+		func(svc docker.Service, target *grpc.ClientConn) *grpc.Server {
+			return token.NewGRPCProxy(svc.Name, target, opts)
+		},
+		func(svc docker.Service, target *url.URL) http.Handler {
+			return token.NewHTTPProxy(svc.Name, target, opts)
+		},
+	)
+	if err != nil {
+		return err
+	}
+	defer proxies.Close()
+
+	if proxyRequireToken {
+		color.Cyan("\nRequests without a bearer token are rejected")
+	}
+	color.Cyan("\nPress Ctrl+C to stop\n")
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	select {
+	case err := <-proxies.Errors():
+		if !errors.Is(err, http.ErrServerClosed) {
+			color.Red("✗ Proxy failed: %v", err)
+			return err
+		}
+	case <-signals:
+	}
+
+	return nil
+}
+
+func printProxyRequest(r token.ProxyRequest) {
+	switch {
+	case r.Err != nil:
+		color.Red("  ✗ %s %s: %s", r.Service, r.Method, status.Convert(r.Err).Message())
+	case r.Source == "":
+		color.Yellow("  ⚠ %s %s: no token, forwarded as-is", r.Service, r.Method)
+	default:
+		color.Green("  ✓ %s %s → %s (%s token)", r.Service, r.Method, r.Principal, r.Source)
+	}
+}
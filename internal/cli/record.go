@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/cassette"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
+)
+
+var (
+	recordOutput     string
+	recordPortOffset int
+	recordForce      bool
+	recordQuiet      bool
+)
+
+var recordCmd = &cobra.Command{
+	Use:   "record",
+	Short: "Record traffic to the data planes into a cassette",
+	Long: `Record gRPC and HTTP requests made to Secret Manager and KMS.
+
+Starts a recording proxy in front of each data plane service, on the
+service's ports plus --port-offset. Point your tests at the proxy ports;
+every request is forwarded to the running stack and recorded (method,
+resource, principal, response code and request body) until Ctrl+C.
+
+Replay the cassette with 'gcp-emulator replay' after editing the policy to
+see which requests would now be allowed or denied differently.`,
+	Example: `  gcp-emulator start --mode=strict
+  gcp-emulator record --output cassette.jsonl
+  # in another terminal, with clients pointed at localhost:19090 / localhost:19091
+  go test ./...`,
+	RunE: runRecord,
+}
+
+func init() {
+	recordCmd.Flags().StringVarP(&recordOutput, "output", "o", "cassette.jsonl", "Cassette file to write")
+	recordCmd.Flags().IntVar(&recordPortOffset, "port-offset", 10000, "Proxy ports are the service ports plus this offset")
+	recordCmd.Flags().BoolVarP(&recordForce, "force", "f", false, "Overwrite an existing cassette")
+	recordCmd.Flags().BoolVarP(&recordQuiet, "quiet", "q", false, "Do not print each recorded request")
+}
+
+func runRecord(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(recordOutput); err == nil && !recordForce {
+		color.Red("✗ %s already exists", recordOutput)
+		color.Yellow("  Use --force to overwrite")
+		return fmt.Errorf("file exists")
+	}
+
+	writer, err := cassette.Create(recordOutput)
+	if err != nil {
+		return err
+	}
+	defer writer.Close()
+
+	record := func(i cassette.Interaction) {
+		if err := writer.Record(i); err != nil {
+			color.Red("✗ %v", err)
+			return
+		}
+		if !recordQuiet {
+			printInteraction(i)
+		}
+	}
+
+	color.Cyan("Recording to %s\n", recordOutput)
+
+	proxies, err := startDataPlaneProxies(cfg, recordPortOffset,
+		func(svc docker.Service, target *grpc.ClientConn) *grpc.Server {
+			return cassette.NewGRPCProxy(svc.Name, target, record)
+		},
+		func(svc docker.Service, target *url.URL) http.Handler {
+			return cassette.NewHTTPProxy(svc.Name, target, record)
+		},
+	)
+	if err != nil {
+		return err
+	}
+	defer proxies.Close()
+
+	color.Cyan("\nPress Ctrl+C to stop recording\n")
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	select {
+	case err := <-proxies.Errors():
+		if !errors.Is(err, http.ErrServerClosed) {
+			color.Red("✗ Proxy failed: %v", err)
+			return err
+		}
+	case <-signals:
+	}
+
+	color.Green("\n✓ Recorded %d requests to %s", writer.Count(), recordOutput)
+	return nil
+}
+
+func printInteraction(i cassette.Interaction) {
+	line := fmt.Sprintf("%s %s %s %s", i.Method, i.Resource, principalOrNone(i.Principal), i.Code)
+	if i.Outcome == cassette.OutcomeDeny {
+		color.Red("  ✗ %s", line)
+	} else {
+		color.Green("  ✓ %s", line)
+	}
+}
+
+func principalOrNone(principal string) string {
+	if principal == "" {
+		return "(no principal)"
+	}
+	return principal
+}
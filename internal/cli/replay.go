@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/cassette"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
+)
+
+var (
+	replayJSON    bool
+	replayAll     bool
+	replayTimeout time.Duration
+)
+
+var replayCmd = &cobra.Command{
+	Use:   "replay <cassette>",
+	Short: "Replay a cassette and report changed authorization outcomes",
+	Long: `Replay recorded requests against the running stack.
+
+Requests are sent in recorded order, as the recorded principal, to the
+configured service ports. Each response is compared with the recording and
+requests whose authorization outcome changed (ALLOW → DENY or DENY → ALLOW)
+are reported. A request is DENY if it was rejected with PERMISSION_DENIED
+(HTTP 403) and ALLOW otherwise.
+
+Replay against a freshly started stack so that requests which create
+resources run before the requests that use them.
+
+Each request gets its own --timeout; a request that times out or finds
+the service unavailable is reported as not replayed.
+
+Exits non-zero if any outcome changed or a request could not be replayed.`,
+	Example: `  # after editing policy.yaml
+  gcp-emulator restart
+  gcp-emulator replay cassette.jsonl`,
+	Args: cobra.ExactArgs(1),
+	RunE: runReplay,
+}
+
+func init() {
+	replayCmd.Flags().BoolVar(&replayJSON, "json", false, "Output results as JSON")
+	replayCmd.Flags().BoolVar(&replayAll, "all", false, "Show every request, not only changed ones")
+	replayCmd.Flags().DurationVar(&replayTimeout, "timeout", 5*time.Second, "Timeout per request")
+}
+
+func runReplay(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	interactions, err := cassette.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	targets := make(map[string]cassette.Target)
+	for _, svc := range docker.Services(cfg) {
+		conn, err := grpc.NewClient(svc.GRPCAddr(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			return fmt.Errorf("failed to connect to %s: %w", svc.DisplayName, err)
+		}
+		defer conn.Close()
+
+		targets[svc.Name] = cassette.Target{
+			GRPC:    conn,
+			HTTP:    fmt.Sprintf("http://localhost:%d", svc.HTTPPort),
+			Timeout: replayTimeout,
+		}
+	}
+
+	results := cassette.Replay(cmd.Context(), interactions, targets, nil)
+
+	changed, failed := 0, 0
+	for _, r := range results {
+		if r.Changed() {
+			changed++
+		}
+		if r.Error != "" {
+			failed++
+		}
+	}
+
+	if replayJSON {
+		if err := printJSON(results); err != nil {
+			return err
+		}
+	} else {
+		printReplayResults(results)
+
+		fmt.Printf("\n%d requests replayed, %d outcomes changed", len(results), changed)
+		if failed > 0 {
+			fmt.Printf(", %d failed", failed)
+		}
+		fmt.Println()
+	}
+
+	switch {
+	case failed > 0:
+		return fmt.Errorf("%d requests could not be replayed", failed)
+	case changed > 0:
+		return fmt.Errorf("%d authorization outcomes changed", changed)
+	}
+
+	if !replayJSON {
+		color.Green("✓ No authorization outcomes changed")
+	}
+	return nil
+}
+
+func printReplayResults(results []cassette.Result) {
+	for _, r := range results {
+		line := fmt.Sprintf("%s %s %s", r.Method, r.Resource, principalOrNone(r.Principal))
+
+		switch {
+		case r.Error != "":
+			color.Red("  ✗ %s: %s", line, r.Error)
+		case r.Changed():
+			color.Red("  ✗ %s: %s → %s (%s → %s)", line, r.RecordedOutcome, r.Outcome, r.RecordedCode, r.Code)
+		case replayAll && r.CodeChanged():
+			color.Yellow("  ⚠ %s: %s (%s → %s)", line, r.Outcome, r.RecordedCode, r.Code)
+		case replayAll:
+			color.Green("  ✓ %s: %s", line, r.Outcome)
+		}
+	}
+}
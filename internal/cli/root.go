@@ -30,8 +30,16 @@ func init() {
 	rootCmd.AddCommand(stopCmd)
 	rootCmd.AddCommand(restartCmd)
 	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(waitCmd)
 	rootCmd.AddCommand(logsCmd)
 	rootCmd.AddCommand(policyCmd)
+	rootCmd.AddCommand(auditCmd)
+	rootCmd.AddCommand(testCmd)
+	rootCmd.AddCommand(recordCmd)
+	rootCmd.AddCommand(replayCmd)
+	rootCmd.AddCommand(proxyCmd)
+	rootCmd.AddCommand(tokenCmd)
+	rootCmd.AddCommand(metadataCmd)
 	rootCmd.AddCommand(configCmd)
 	rootCmd.AddCommand(versionCmd)
 }
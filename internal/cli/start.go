@@ -1,12 +1,19 @@
 package cli
 
 import (
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
 	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
 	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
+	"github.com/blackwell-systems/gcp-iam-control-plane/native"
 )
 
 var startCmd = &cobra.Command{
@@ -15,7 +22,11 @@ var startCmd = &cobra.Command{
 	Long: `Start the GCP emulator stack using docker-compose.
 
 This starts IAM, Secret Manager, and KMS emulators with the
-configured IAM mode and policy.`,
+configured IAM mode and policy.
+
+With --native, the stack runs in-process as Go servers instead of
+containers and stays in the foreground until interrupted. No Docker
+is required.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Load configuration (Viper resolves behind the scenes)
 		cfg, err := config.Load()
@@ -23,6 +34,10 @@ configured IAM mode and policy.`,
 			return err
 		}
 
+		if nativeMode, _ := cmd.Flags().GetBool("native"); nativeMode {
+			return runNative(cfg)
+		}
+
 		color.Cyan("Starting GCP Emulator Control Plane...")
 		color.Cyan("IAM Mode: %s", cfg.IAMMode)
 
@@ -41,21 +56,96 @@ configured IAM mode and policy.`,
 		}
 
 		color.Green("✓ Stack started successfully")
+
+		if wait, _ := cmd.Flags().GetBool("wait"); wait {
+			timeout, _ := cmd.Flags().GetDuration("timeout")
+			if err := waitForStack(cfg, timeout); err != nil {
+				return err
+			}
+		}
+
 		color.Cyan("\nServices:")
 		color.Cyan("  IAM:            http://localhost:%d", cfg.Ports.IAM)
 		color.Cyan("  Secret Manager: grpc://localhost:%d, http://localhost:%d", cfg.Ports.SecretManager, cfg.Ports.SecretManager+1)
 		color.Cyan("  KMS:            grpc://localhost:%d, http://localhost:%d", cfg.Ports.KMS, cfg.Ports.KMS+1)
+		if path := cfg.AuditPath(); path != "" {
+			color.Cyan("  Audit log:      %s", path)
+		}
 		color.Cyan("\nRun 'gcp-emulator status' to check health")
 
 		return nil
 	},
 }
 
+// runNative runs the in-process stack until SIGINT/SIGTERM
+func runNative(cfg *config.Config) error {
+	color.Cyan("Starting GCP Emulator Control Plane (native)...")
+	color.Cyan("IAM Mode: %s", cfg.IAMMode)
+
+	stack, err := native.Start(native.Options{
+		PolicyFile:            cfg.PolicyFile,
+		IAMMode:               cfg.IAMMode,
+		IAMPort:               cfg.Ports.IAM,
+		IAMHealthPort:         cfg.Ports.IAM + 1000,
+		SecretManagerPort:     cfg.Ports.SecretManager,
+		SecretManagerHTTPPort: 8081,
+		KMSPort:               cfg.Ports.KMS,
+		KMSHTTPPort:           8082,
+		Logger:                slog.New(slog.NewTextHandler(os.Stderr, nil)),
+		AuditFile:             cfg.AuditPath(),
+	})
+	if err != nil {
+		color.Red("✗ Failed to start stack: %v", err)
+		return err
+	}
+	defer stack.Stop()
+
+	endpoints := stack.Endpoints()
+	color.Green("✓ Stack started successfully")
+	color.Cyan("\nServices:")
+	color.Cyan("  IAM:            grpc://%s, http://%s", endpoints.IAM, endpoints.IAMHealth)
+	color.Cyan("  Secret Manager: grpc://%s, http://%s", endpoints.SecretManager, endpoints.SecretManagerHTTP)
+	color.Cyan("  KMS:            grpc://%s, http://%s", endpoints.KMS, endpoints.KMSHTTP)
+	if path := cfg.AuditPath(); path != "" {
+		color.Cyan("  Audit log:      %s", path)
+	}
+	color.Cyan("\nPress Ctrl+C to stop (send SIGHUP to reload policy)")
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	failed := make(chan error, 1)
+	go func() { failed <- stack.Wait() }()
+
+	for {
+		select {
+		case err := <-failed:
+			color.Red("✗ Stack failed: %v", err)
+			return err
+		case sig := <-signals:
+			if sig == syscall.SIGHUP {
+				if err := stack.ReloadPolicy(); err != nil {
+					color.Yellow("⚠ Failed to reload policy: %v", err)
+				} else {
+					color.Green("✓ Policy reloaded")
+				}
+				continue
+			}
+			color.Cyan("\nStopping...")
+			return nil
+		}
+	}
+}
+
 func init() {
 	// Define flags
 	startCmd.Flags().String("mode", "", "IAM mode (off|permissive|strict)")
 	startCmd.Flags().Bool("pull", false, "Pull latest images before starting")
 	startCmd.Flags().BoolP("detach", "d", true, "Run in background")
+	startCmd.Flags().Bool("native", false, "Run the stack in-process without Docker (foreground)")
+	startCmd.Flags().Bool("wait", false, "Wait until all services are ready before returning")
+	startCmd.Flags().Duration("timeout", 60*time.Second, "Maximum time to wait with --wait")
 
 	// Bind flags to viper (errors only happen if flag doesn't exist, which can't happen here)
 	_ = viper.BindPFlag("iam-mode", startCmd.Flags().Lookup("mode"))
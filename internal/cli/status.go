@@ -1,53 +1,254 @@
 package cli
 
 import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 
 	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
 	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/health"
+)
+
+var (
+	statusJSON  bool
+	statusWatch bool
 )
 
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show status of all services",
-	Long:  `Display health status of IAM, Secret Manager, and KMS emulators.`,
+	Long: `Display health and container status of IAM, Secret Manager, and KMS emulators.
+
+For each service shows container state, image digest, uptime, restart
+count, IAM mode, and health check latency. Each gRPC port is probed with
+grpc.health.v1 and a real API call made as a probe principal, so a broken
+gRPC server or a data plane that cannot reach IAM shows up as DEGRADED.
+
+Exit code is 0 when every service is up and 1 otherwise, so status can
+be used in scripts.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cfg, err := config.Load()
 		if err != nil {
 			return err
 		}
 
+		if statusWatch {
+			return watchStatus(cfg)
+		}
+
 		status, err := docker.Status(cfg)
 		if err != nil {
-			color.Red("✗ Failed to get status: %v", err)
+			if !statusJSON {
+				color.Red("✗ Failed to get status: %v", err)
+			}
 			return err
 		}
 
-		// Print status
-		color.Cyan("Service          Status    Ports")
-		color.Cyan("────────────────────────────────────────")
+		if statusJSON {
+			if err := printStatusJSON(status); err != nil {
+				return err
+			}
+		} else {
+			printStatusTable(status)
+		}
 
-		printServiceStatus("IAM Emulator", status.IAM, cfg.Ports.IAM)
-		printServiceStatus("Secret Manager", status.SecretManager, cfg.Ports.SecretManager)
-		printServiceStatus("KMS", status.KMS, cfg.Ports.KMS)
+		if !status.Healthy {
+			return fmt.Errorf("stack is not healthy")
+		}
 
 		return nil
 	},
 }
 
-func printServiceStatus(name string, status docker.ServiceStatus, port int) {
+// watchStatus redraws the status table every 2s until interrupted
+func watchStatus(cfg *config.Config) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		status, err := docker.Status(cfg)
+
+		if statusJSON {
+			if err != nil {
+				return err
+			}
+			if err := printStatusJSON(status); err != nil {
+				return err
+			}
+		} else {
+			// Clear screen and move cursor home
+			fmt.Print("\033[H\033[2J")
+			fmt.Printf("Every 2s: gcp-emulator status    %s\n\n", time.Now().Format(time.TimeOnly))
+			if err != nil {
+				color.Red("✗ Failed to get status: %v", err)
+			} else {
+				printStatusTable(status)
+			}
+		}
+
+		select {
+		case <-signals:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func printStatusJSON(status *docker.StackStatus) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return fmt.Errorf("failed to encode status: %w", err)
+	}
+
+	fmt.Println(string(data))
+	return nil
+}
+
+func printStatusTable(status *docker.StackStatus) {
+	color.Cyan("%-16s %-12s %-12s %-10s %-9s %s", "Service", "Status", "Mode", "Uptime", "Restarts", "Ports")
+	color.Cyan(strings.Repeat("─", 76))
+
+	for _, svc := range status.Services {
+		printServiceStatus(svc)
+	}
+
+	fmt.Println()
+	color.Cyan("Containers:")
+	for _, svc := range status.Services {
+		c := svc.Container
+		if c == nil {
+			fmt.Printf("  %-16s (no container)\n", svc.DisplayName+":")
+			continue
+		}
+
+		state := c.State
+		if c.Health != "" {
+			state = fmt.Sprintf("%s, %s", c.State, c.Health)
+		}
+		fmt.Printf("  %-16s %s %s  %s (%s)\n", svc.DisplayName+":", c.ID, c.Image, shortDigest(c.ImageDigest), state)
+	}
+	if status.ContainerError != "" {
+		color.Yellow("  ⚠ %s", status.ContainerError)
+	}
+
+	fmt.Println()
+	color.Cyan("Health Checks:")
+	for _, svc := range status.Services {
+		printHealthChecks(svc)
+	}
+
+	var problems []docker.ServiceInfo
+	for _, svc := range status.Services {
+		if svc.Problem != "" {
+			problems = append(problems, svc)
+		}
+	}
+	if len(problems) > 0 {
+		fmt.Println()
+		for _, svc := range problems {
+			color.Yellow("⚠ %s: %s", svc.DisplayName, svc.Problem)
+		}
+	}
+}
+
+// printHealthChecks prints the HTTP, gRPC health and probe RPC results
+func printHealthChecks(svc docker.ServiceInfo) {
+	label := fmt.Sprintf("  %-16s", svc.DisplayName+":")
+	indent := strings.Repeat(" ", len(label))
+
+	if svc.HealthError != "" {
+		fmt.Printf("%s %s HTTP %s (%s)\n", label, color.RedString("✗"), svc.HealthURL, svc.HealthError)
+	} else {
+		fmt.Printf("%s %s HTTP %s (200 OK, %s)\n", label, color.GreenString("✓"), svc.HealthURL, svc.HealthLatency.Round(time.Millisecond))
+	}
+
+	switch svc.GRPCHealth {
+	case health.GRPCServing:
+		fmt.Printf("%s %s gRPC %s grpc.health.v1 %s (%s)\n", indent, color.GreenString("✓"), svc.GRPCAddr, svc.GRPCHealth, svc.GRPCLatency.Round(time.Millisecond))
+	case health.GRPCUnimplemented, health.GRPCServiceUnknown:
+		fmt.Printf("%s %s gRPC %s grpc.health.v1 not supported\n", indent, color.YellowString("⚠"), svc.GRPCAddr)
+	case health.GRPCUnreachable:
+		fmt.Printf("%s %s gRPC %s (%s)\n", indent, color.RedString("✗"), svc.GRPCAddr, svc.GRPCError)
+		return
+	default:
+		fmt.Printf("%s %s gRPC %s grpc.health.v1 %s\n", indent, color.RedString("✗"), svc.GRPCAddr, svc.GRPCHealth)
+	}
+
+	rpc := svc.RPC
+	if rpc == nil {
+		return
+	}
+
+	latency := svc.RPCLatency.Round(time.Millisecond)
+	switch {
+	case !rpc.Serving:
+		fmt.Printf("%s %s RPC  %s (%s: %s)\n", indent, color.RedString("✗"), rpc.Method, rpc.Code, rpc.Detail)
+	case rpc.IAM == health.IAMReachable:
+		fmt.Printf("%s %s RPC  %s → IAM reachable (%s, %s)\n", indent, color.GreenString("✓"), rpc.Method, rpc.Code, latency)
+	case rpc.IAM == health.IAMUnreachable:
+		fmt.Printf("%s %s RPC  %s → IAM unreachable (%s)\n", indent, color.RedString("✗"), rpc.Method, rpc.Detail)
+	default:
+		fmt.Printf("%s %s RPC  %s (%s, %s)\n", indent, color.GreenString("✓"), rpc.Method, rpc.Code, latency)
+	}
+}
+
+func printServiceStatus(svc docker.ServiceInfo) {
+	// Pad before coloring so ANSI codes don't break alignment
 	var statusText string
-	switch status {
+	switch svc.Status {
 	case docker.ServiceUp:
-		statusText = color.GreenString("✓ UP")
+		statusText = color.GreenString("%-12s", "✓ UP")
 	case docker.ServiceDown:
-		statusText = color.RedString("✗ DOWN")
+		statusText = color.RedString("%-12s", "✗ DOWN")
 	case docker.ServiceStarting:
-		statusText = color.YellowString("⚠ STARTING")
+		statusText = color.YellowString("%-12s", "⚠ STARTING")
+	case docker.ServiceDegraded:
+		statusText = color.YellowString("%-12s", "⚠ DEGRADED")
 	default:
-		statusText = color.RedString("✗ UNKNOWN")
+		statusText = color.RedString("%-12s", "✗ UNKNOWN")
+	}
+
+	mode := svc.IAMMode
+	if mode == "" {
+		mode = "-"
+	}
+
+	uptime, restarts := "-", "-"
+	if svc.Container != nil {
+		if svc.Container.Uptime != "" {
+			uptime = svc.Container.Uptime
+		}
+		restarts = fmt.Sprintf("%d", svc.Container.RestartCount)
+	}
+
+	ports := make([]string, 0, len(svc.Ports))
+	for _, p := range svc.Ports {
+		ports = append(ports, fmt.Sprintf("%d", p))
+	}
+
+	fmt.Printf("%-16s %s %-12s %-10s %-9s %s\n", svc.DisplayName, statusText, mode, uptime, restarts, strings.Join(ports, ", "))
+}
+
+func shortDigest(digest string) string {
+	if len(digest) > 19 {
+		return digest[:19]
 	}
+	return digest
+}
 
-	color.New().Printf("%-16s %s       %d\n", name, statusText, port)
+func init() {
+	statusCmd.Flags().BoolVar(&statusJSON, "json", false, "Output as JSON")
+	statusCmd.Flags().BoolVarP(&statusWatch, "watch", "w", false, "Watch status (refresh every 2s)")
 }
@@ -0,0 +1,13 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var testCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Test permissions against the policy",
+	Long: `Evaluate permission checks against the policy locally.
+
+No emulator needs to be running.`,
+}
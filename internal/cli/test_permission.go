@@ -0,0 +1,266 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+var testPermissionCmd = &cobra.Command{
+	Use:   "permission <principal> <resource> <permission>",
+	Short: "Test if a principal has a permission on a resource",
+	Long: `Test if a principal has a permission on a resource.
+
+The check is evaluated locally against the policy file, the same way the
+emulators evaluate it. The explanation shows the binding that granted the
+permission or the bindings that were checked, any deny rule that matched,
+and the access boundaries the principal is subject to.
+
+Conditions are evaluated at the current time, or at --time to simulate
+another one (for example, after a temporary grant expires). API attributes
+for api.getAttribute() are set with --api-attribute name=value; repeating
+a name makes its value a list. List-valued attributes such as
+iam.googleapis.com/modifiedGrantsByRole are lists even when given once.
+
+Exits non-zero if the permission is denied.`,
+	Example: `  gcp-emulator test permission user:alice@example.com \
+    projects/test-project/secrets/db-password secretmanager.secrets.get
+
+  gcp-emulator test permission \
+    serviceAccount:ci@test-project.iam.gserviceaccount.com \
+    projects/test-project/secrets/prod-api-key secretmanager.versions.access --verbose
+
+  gcp-emulator test permission user:oncall@example.com \
+    projects/test-project/secrets/db-password secretmanager.versions.access \
+    --time 2027-01-01T00:00:00Z`,
+	Args: cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		policyFile, _ := cmd.Flags().GetString("policy")
+		verbose, _ := cmd.Flags().GetBool("verbose")
+		delegates, _ := cmd.Flags().GetStringSlice("delegates")
+		timeFlag, _ := cmd.Flags().GetString("time")
+		apiFlags, _ := cmd.Flags().GetStringArray("api-attribute")
+
+		at, err := parseTimeFlag(timeFlag)
+		if err != nil {
+			return err
+		}
+		api, err := parseAPIAttributes(apiFlags)
+		if err != nil {
+			return err
+		}
+
+		if policyFile == "" {
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			policyFile = cfg.PolicyFile
+		}
+
+		pol, err := policy.Load(policyFile)
+		if err != nil {
+			color.Red("✗ Failed to load policy: %v", err)
+			return err
+		}
+
+		decision := policy.Evaluate(pol, policy.Request{
+			Principal:     args[0],
+			Resource:      args[1],
+			Permission:    args[2],
+			Time:          at,
+			APIAttributes: api,
+			Delegates:     delegates,
+		})
+
+		if verbose {
+			printEvaluationTrace(pol, decision)
+		} else {
+			printDecision(decision)
+		}
+
+		if !decision.Allowed {
+			return fmt.Errorf("permission denied")
+		}
+		return nil
+	},
+}
+
+func printDecision(d *policy.Decision) {
+	if d.Allowed {
+		color.Green("✓ ALLOWED")
+	} else {
+		color.Red("✗ DENIED")
+	}
+
+	fmt.Printf("\nPrincipal:  %s\n", d.Request.Principal)
+	if len(d.Request.Delegates) > 0 {
+		fmt.Printf("Delegates:  %s\n", strings.Join(d.Request.Delegates, " → "))
+	}
+	fmt.Printf("Resource:   %s\n", d.Request.Resource)
+	fmt.Printf("Permission: %s\n", d.Request.Permission)
+
+	if d.Grant != nil {
+		fmt.Println("\nGranted by:")
+		fmt.Printf("  Role:    %s\n", d.Grant.Binding.Role)
+		fmt.Printf("  Binding: %s (via %s)\n", d.Grant.Ref().Label(), d.Grant.Member)
+	} else {
+		fmt.Printf("\nReason: %s\n", d.Reason)
+	}
+
+	if d.Denial != nil {
+		fmt.Println("\nDenied by:")
+		fmt.Printf("  Deny rule: %s (via %s)\n", d.Denial.Label(), d.Denial.Member)
+		if d.Denial.ConditionError != nil {
+			fmt.Printf("  Condition: %v\n", d.Denial.ConditionError)
+		}
+	}
+
+	if !d.Allowed && len(d.Checked) > 0 {
+		fmt.Println("\nChecked bindings:")
+		for _, b := range d.Checked {
+			outcome := b.Outcome()
+			if b.Granted {
+				// Deny rules stop evaluation, so a denied grant was capped by a boundary
+				outcome = "granted, but outside the access boundary"
+			}
+			fmt.Printf("  ✗ %s %s (%s)\n", b.Binding.Role, b.Ref().Label(), outcome)
+		}
+	}
+
+	printBoundary(d)
+}
+
+func printBoundary(d *policy.Decision) {
+	if d.Boundary == nil {
+		return
+	}
+
+	boundaries := strings.Join(d.Boundary.Boundaries, ", ")
+	fmt.Println("\nAccess boundary:")
+	if d.Boundary.Within {
+		fmt.Printf("  ✓ %s is within %s\n", d.Request.Permission, boundaries)
+	} else {
+		fmt.Printf("  ✗ %s is outside %s\n", d.Request.Permission, boundaries)
+	}
+}
+
+func printEvaluationTrace(pol *policy.Policy, d *policy.Decision) {
+	if d.Allowed {
+		color.Green("✓ ALLOWED")
+	} else {
+		color.Red("✗ DENIED")
+	}
+
+	fmt.Println("\nEvaluation trace:")
+	fmt.Printf("  Principal:  %s\n", d.Request.Principal)
+	fmt.Printf("  Resource:   %s\n", d.Request.Resource)
+	fmt.Printf("  Permission: %s\n", d.Request.Permission)
+
+	for i, step := range d.Delegation {
+		fmt.Printf("  Delegate %d: %s → %s (%s)\n", i+1, step.Request.Principal, step.Request.Resource, step.Reason)
+	}
+
+	if d.Denial != nil {
+		fmt.Printf("  Deny rule %s:\n", d.Denial.Label())
+		fmt.Printf("    → Denied principal: %s\n", d.Denial.Member)
+		if c := d.Denial.Rule.DenialCondition; c != nil {
+			fmt.Printf("    → Condition: %s\n", c.Expression)
+		}
+		fmt.Println("    → Result: DENY")
+	}
+
+	for _, b := range d.Checked {
+		fmt.Printf("  Binding %s: %s\n", b.Ref().Label(), b.Binding.Role)
+		fmt.Printf("    → Members: %s\n", strings.Join(b.Binding.Members, ", "))
+		if b.Member == "" {
+			fmt.Println("    → Match: none")
+		} else {
+			fmt.Printf("    → Match: %s\n", b.Member)
+		}
+
+		perms, _ := policy.RolePermissions(pol, b.Binding.Role)
+		switch {
+		case !b.RoleFound:
+			fmt.Println("    → Role: not defined")
+		case b.HasPermission:
+			fmt.Printf("    → Permission match: %s (%d permissions in role)\n", d.Request.Permission, len(perms))
+		default:
+			fmt.Printf("    → Permission match: none (%d permissions in role)\n", len(perms))
+		}
+
+		switch {
+		case b.Binding.Condition == nil:
+			fmt.Println("    → Condition: none")
+		case b.ConditionMet == nil:
+			fmt.Printf("    → Condition: %s (not evaluated)\n", b.Binding.Condition.Expression)
+		case b.ConditionError != nil:
+			fmt.Printf("    → Condition: %s (error: %v)\n", b.Binding.Condition.Expression, b.ConditionError)
+		default:
+			fmt.Printf("    → Condition: %s (%v)\n", b.Binding.Condition.Expression, *b.ConditionMet)
+		}
+
+		result := "no grant"
+		if b.Granted {
+			result = "GRANT"
+		}
+		fmt.Printf("    → Result: %s\n", result)
+	}
+
+	printBoundary(d)
+
+	fmt.Printf("\nFinal decision: %s\n", d.Reason)
+}
+
+// parseTimeFlag parses an RFC 3339 --time flag; empty means now
+func parseTimeFlag(value string) (time.Time, error) {
+	if value == "" {
+		return time.Now(), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --time %q (expected RFC 3339, e.g. 2026-12-31T00:00:00Z): %w", value, err)
+	}
+	return t, nil
+}
+
+// parseAPIAttributes parses --api-attribute name=value flags; a name given
+// more than once, or a list-valued attribute, has a list value
+func parseAPIAttributes(flags []string) (map[string]any, error) {
+	api := make(map[string]any)
+	for _, flag := range flags {
+		name, value, ok := strings.Cut(flag, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid --api-attribute %q (expected name=value)", flag)
+		}
+		switch existing := api[name].(type) {
+		case nil:
+			if policy.IsListAPIAttribute(name) {
+				api[name] = []any{value}
+			} else {
+				api[name] = value
+			}
+		case string:
+			api[name] = []any{existing, value}
+		case []any:
+			api[name] = append(existing, value)
+		}
+	}
+	return api, nil
+}
+
+func init() {
+	testCmd.AddCommand(testPermissionCmd)
+
+	testPermissionCmd.Flags().String("policy", "", "Policy file to evaluate (default: the configured policy file)")
+	testPermissionCmd.Flags().BoolP("verbose", "v", false, "Show detailed evaluation trace")
+	testPermissionCmd.Flags().String("time", "", "Evaluate at this RFC 3339 time (default: now)")
+	testPermissionCmd.Flags().StringArray("api-attribute", nil, "API attribute for api.getAttribute(), as name=value (repeat a name for a list)")
+	testPermissionCmd.Flags().StringSlice("delegates", nil, "Impersonation chain of service account emails")
+}
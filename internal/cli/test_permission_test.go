@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+func TestParseAPIAttributes(t *testing.T) {
+	const grants = "iam.googleapis.com/modifiedGrantsByRole"
+
+	api, err := parseAPIAttributes([]string{
+		grants + "=roles/secretmanager.secretAccessor",
+		"example.googleapis.com/mode=a",
+		"example.googleapis.com/tags=x",
+		"example.googleapis.com/tags=y",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		grants:                        []any{"roles/secretmanager.secretAccessor"},
+		"example.googleapis.com/mode": "a",
+		"example.googleapis.com/tags": []any{"x", "y"},
+	}
+	if !reflect.DeepEqual(api, want) {
+		t.Errorf("parseAPIAttributes() = %v, want %v", api, want)
+	}
+
+	if _, err := parseAPIAttributes([]string{"no-value"}); err == nil {
+		t.Error("Expected an error for a flag without =")
+	}
+
+	// Granting a single role is allowed by the hasOnly() condition
+	pol, err := policy.Load("../../testdata/conditions.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	decision := policy.Evaluate(pol, policy.Request{
+		Principal:     "user:admin@example.com",
+		Resource:      "projects/test-project/secrets/db-password",
+		Permission:    "secretmanager.secrets.setIamPolicy",
+		APIAttributes: api,
+	})
+	if !decision.Allowed {
+		t.Errorf("single --api-attribute role denied: %s", decision.Reason)
+	}
+}
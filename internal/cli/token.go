@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/token"
+)
+
+var tokenTTL time.Duration
+
+var tokenCmd = &cobra.Command{
+	Use:   "token <principal>",
+	Short: "Print an access token for a principal",
+	Long: `Print an emulator access token for a principal.
+
+The token is accepted by 'gcp-emulator proxy', which injects the principal
+into requests it forwards. Like 'gcloud auth print-access-token', only the
+token is printed so it can be used in scripts.
+
+The principal must be user:<email>, serviceAccount:<email>, or a workload
+identity federation principal (principal://iam.googleapis.com/...).`,
+	Example: `  gcp-emulator token user:alice@example.com
+  export TOKEN=$(gcp-emulator token serviceAccount:ci@test-project.iam.gserviceaccount.com --ttl 10m)`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tok, err := token.Mint(args[0], tokenTTL, time.Now())
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(tok)
+		return nil
+	},
+}
+
+func init() {
+	tokenCmd.Flags().DurationVar(&tokenTTL, "ttl", time.Hour, "Token lifetime (0 for no expiry)")
+}
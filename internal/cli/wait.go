@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/docker"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/health"
+)
+
+var waitCmd = &cobra.Command{
+	Use:   "wait",
+	Short: "Wait until all services are ready",
+	Long: `Poll each service's health endpoint and gRPC port until the whole
+stack is ready.
+
+Exits non-zero and prints the failing service's recent logs if the
+timeout expires. Use this instead of sleeping in scripts.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+
+		return waitForStack(cfg, timeout)
+	},
+}
+
+type waitResult struct {
+	service docker.Service
+	elapsed time.Duration
+	err     error
+}
+
+// waitForStack polls every service until ready or the timeout expires
+func waitForStack(cfg *config.Config, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	services := docker.Services(cfg)
+	color.Cyan("→ Waiting for %d services (timeout %s)...", len(services), timeout)
+
+	start := time.Now()
+	results := make(chan waitResult, len(services))
+	for _, svc := range services {
+		go func(svc docker.Service) {
+			err := health.Wait(ctx, health.Target{
+				Name:      svc.DisplayName,
+				HealthURL: svc.HealthURL(),
+				GRPCAddr:  svc.GRPCAddr(),
+			}, 500*time.Millisecond)
+			results <- waitResult{service: svc, elapsed: time.Since(start), err: err}
+		}(svc)
+	}
+
+	var failed []waitResult
+	for range services {
+		r := <-results
+		if r.err != nil {
+			color.Red("  ✗ %-16s not ready after %s", r.service.DisplayName, r.elapsed.Round(100*time.Millisecond))
+			failed = append(failed, r)
+			continue
+		}
+		color.Green("  ✓ %-16s ready (%s)", r.service.DisplayName, r.elapsed.Round(100*time.Millisecond))
+	}
+
+	if len(failed) == 0 {
+		color.Green("✓ Stack is ready")
+		return nil
+	}
+
+	var names []string
+	for _, r := range failed {
+		names = append(names, r.service.Name)
+
+		color.Red("\n%v", r.err)
+		color.Yellow("Recent logs (%s):", r.service.Name)
+		logs, err := docker.Logs(r.service.Name, 30)
+		if err != nil {
+			color.Yellow("  (unable to fetch logs: %v)", err)
+			continue
+		}
+		fmt.Print(logs)
+	}
+
+	return fmt.Errorf("services not ready within %s: %s", timeout, strings.Join(names, ", "))
+}
+
+func init() {
+	waitCmd.Flags().Duration("timeout", 60*time.Second, "Maximum time to wait for readiness")
+}
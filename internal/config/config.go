@@ -7,6 +7,7 @@ package config
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/spf13/viper"
 )
@@ -16,6 +17,7 @@ import (
 type Config struct {
 	IAMMode     string
 	Trace       bool
+	AuditFile   string
 	PullOnStart bool
 	PolicyFile  string
 	Ports       PortConfig
@@ -41,6 +43,7 @@ func Init() error {
 	// Set defaults
 	viper.SetDefault("iam-mode", "permissive")
 	viper.SetDefault("trace", false)
+	viper.SetDefault("audit-file", "./audit.jsonl")
 	viper.SetDefault("pull-on-start", false)
 	viper.SetDefault("policy-file", "./policy.yaml")
 	viper.SetDefault("port-iam", 8080)
@@ -66,6 +69,7 @@ func Load() (*Config, error) {
 	cfg := &Config{
 		IAMMode:     viper.GetString("iam-mode"),
 		Trace:       viper.GetBool("trace"),
+		AuditFile:   viper.GetString("audit-file"),
 		PullOnStart: viper.GetBool("pull-on-start"),
 		PolicyFile:  viper.GetString("policy-file"),
 		Ports: PortConfig{
@@ -104,10 +108,24 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// AuditPath returns the file IAM decisions are recorded to, or "" when
+// auditing is off. IAM_TRACE_OUTPUT, if set, takes precedence over the
+// trace and audit-file keys.
+func (c *Config) AuditPath() string {
+	if path := os.Getenv("IAM_TRACE_OUTPUT"); path != "" {
+		return path
+	}
+	if c.Trace {
+		return c.AuditFile
+	}
+	return ""
+}
+
 // Save writes current config to file
 func Save(cfg *Config) error {
 	viper.Set("iam-mode", cfg.IAMMode)
 	viper.Set("trace", cfg.Trace)
+	viper.Set("audit-file", cfg.AuditFile)
 	viper.Set("pull-on-start", cfg.PullOnStart)
 	viper.Set("policy-file", cfg.PolicyFile)
 	viper.Set("port-iam", cfg.Ports.IAM)
@@ -132,6 +150,7 @@ func Display() (string, error) {
 	return fmt.Sprintf(`Configuration:
   iam-mode:           %s
   trace:              %t
+  audit-file:         %s
   pull-on-start:      %t
   policy-file:        %s
   
@@ -147,6 +166,7 @@ Sources:
 `,
 		cfg.IAMMode,
 		cfg.Trace,
+		cfg.AuditFile,
 		cfg.PullOnStart,
 		cfg.PolicyFile,
 		cfg.Ports.IAM,
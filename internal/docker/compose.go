@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 
 	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
 )
@@ -35,6 +36,23 @@ func Start(cfg *config.Config) error {
 		fmt.Sprintf("KMS_PORT=%d", cfg.Ports.KMS),
 	)
 
+	// The IAM container writes its trace to the mounted audit directory
+	if path := cfg.AuditPath(); path != "" {
+		dir, err := filepath.Abs(filepath.Dir(path))
+		if err != nil {
+			return fmt.Errorf("invalid audit file: %w", err)
+		}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create audit directory: %w", err)
+		}
+		env = append(env,
+			fmt.Sprintf("AUDIT_DIR=%s", dir),
+			fmt.Sprintf("IAM_TRACE_OUTPUT=/audit/%s", filepath.Base(path)),
+		)
+	} else {
+		env = append(env, "IAM_TRACE_OUTPUT=")
+	}
+
 	// Get appropriate compose command
 	binary, baseArgs := getComposeCommand()
 	args := append(baseArgs, "up", "-d")
@@ -80,3 +98,46 @@ func Pull() error {
 
 	return nil
 }
+
+// Logs returns the last tail lines of a service's logs
+func Logs(service string, tail int) (string, error) {
+	binary, baseArgs := getComposeCommand()
+	args := append(baseArgs, "logs", "--no-color", "--tail", fmt.Sprintf("%d", tail), service)
+
+	cmd := exec.Command(binary, args...)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("docker compose logs failed: %w\n%s", err, output)
+	}
+
+	return string(output), nil
+}
+
+// LogsOptions controls which log lines LogsCommand returns
+type LogsOptions struct {
+	Follow bool
+	// Tail is the number of lines per service (0 for all)
+	Tail int
+	// Since is a duration (e.g. 10m) or timestamp
+	Since string
+}
+
+// LogsCommand returns an unstarted command streaming one service's logs
+// with timestamps and without the compose "service |" prefix
+func LogsCommand(service string, opts LogsOptions) *exec.Cmd {
+	binary, baseArgs := getComposeCommand()
+	args := append(baseArgs, "logs", "--no-color", "--no-log-prefix", "--timestamps")
+
+	if opts.Follow {
+		args = append(args, "--follow")
+	}
+	if opts.Tail > 0 {
+		args = append(args, "--tail", fmt.Sprintf("%d", opts.Tail))
+	}
+	if opts.Since != "" {
+		args = append(args, "--since", opts.Since)
+	}
+
+	return exec.Command(binary, append(args, service)...)
+}
@@ -0,0 +1,54 @@
+package docker
+
+import (
+	"fmt"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+)
+
+// Service describes how to reach one emulator in the stack
+type Service struct {
+	// Name is the docker compose service name
+	Name        string
+	DisplayName string
+	GRPCPort    int
+	HTTPPort    int
+}
+
+// GRPCAddr returns the host:port of the service's gRPC API
+func (s Service) GRPCAddr() string {
+	return fmt.Sprintf("localhost:%d", s.GRPCPort)
+}
+
+// HealthURL returns the service's HTTP health endpoint
+func (s Service) HealthURL() string {
+	return fmt.Sprintf("http://localhost:%d/health", s.HTTPPort)
+}
+
+// Services returns the stack's services in startup order (IAM first,
+// since the data planes depend on it)
+func Services(cfg *config.Config) []Service {
+	return []Service{
+		{
+			Name:        "iam",
+			DisplayName: "IAM Emulator",
+			GRPCPort:    cfg.Ports.IAM,
+			// Health server listens on gRPC port + 1000
+			HTTPPort: cfg.Ports.IAM + 1000,
+		},
+		{
+			Name:        "secret-manager",
+			DisplayName: "Secret Manager",
+			GRPCPort:    cfg.Ports.SecretManager,
+			// HTTP port is 8081, mapped from container 8080
+			HTTPPort: 8081,
+		},
+		{
+			Name:        "kms",
+			DisplayName: "KMS",
+			GRPCPort:    cfg.Ports.KMS,
+			// HTTP port is 8082, mapped from container 8080
+			HTTPPort: 8082,
+		},
+	}
+}
@@ -1,11 +1,19 @@
 package docker
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
-	"net/http"
+	"os/exec"
+	"strings"
 	"time"
 
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
 	"github.com/blackwell-systems/gcp-iam-control-plane/internal/config"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/health"
 )
 
 // ServiceStatus represents the status of a service
@@ -16,45 +24,400 @@ const (
 	ServiceUp
 	ServiceDown
 	ServiceStarting
+	// ServiceDegraded means the service responds but part of it is broken
+	// (e.g. HTTP health is up but gRPC is not, or IAM is unreachable from
+	// a data plane)
+	ServiceDegraded
 )
 
+// startupGrace is how long a running container whose health check fails
+// is reported as starting rather than down
+const startupGrace = 60 * time.Second
+
+// String returns the lowercase status name used in JSON output
+func (s ServiceStatus) String() string {
+	switch s {
+	case ServiceUp:
+		return "up"
+	case ServiceDown:
+		return "down"
+	case ServiceStarting:
+		return "starting"
+	case ServiceDegraded:
+		return "degraded"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalJSON encodes the status as its name
+func (s ServiceStatus) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
 // StackStatus represents the status of all services
 type StackStatus struct {
-	IAM           ServiceStatus
-	SecretManager ServiceStatus
-	KMS           ServiceStatus
+	Healthy  bool          `json:"healthy"`
+	Services []ServiceInfo `json:"services"`
+	// ContainerError is set when container details could not be read
+	// (e.g. Docker is not installed or the stack runs in native mode)
+	ContainerError string `json:"container_error,omitempty"`
+}
+
+// ServiceInfo describes the state of one service
+type ServiceInfo struct {
+	Name          string         `json:"name"`
+	DisplayName   string         `json:"display_name"`
+	Status        ServiceStatus  `json:"status"`
+	Ports         []int          `json:"ports"`
+	IAMMode       string         `json:"iam_mode,omitempty"`
+	Container     *ContainerInfo `json:"container,omitempty"`
+	HealthURL     string         `json:"health_url"`
+	HealthLatency time.Duration  `json:"health_latency_ns"`
+	HealthError   string         `json:"health_error,omitempty"`
+
+	GRPCAddr string `json:"grpc_addr"`
+	// GRPCHealth is the grpc.health.v1 result (see health.GRPCServing etc.)
+	GRPCHealth  string        `json:"grpc_health"`
+	GRPCLatency time.Duration `json:"grpc_latency_ns"`
+	GRPCError   string        `json:"grpc_error,omitempty"`
+	// RPC is the outcome of a real API call made as health.ProbePrincipal
+	RPC        *health.RPCResult `json:"rpc,omitempty"`
+	RPCLatency time.Duration     `json:"rpc_latency_ns"`
+
+	// Problem explains a degraded or down status
+	Problem string `json:"problem,omitempty"`
+}
+
+// ContainerInfo holds docker details for a service's container
+type ContainerInfo struct {
+	ID           string    `json:"id"`
+	Image        string    `json:"image"`
+	ImageDigest  string    `json:"image_digest"`
+	State        string    `json:"state"`
+	Health       string    `json:"health,omitempty"`
+	StartedAt    time.Time `json:"started_at"`
+	Uptime       string    `json:"uptime,omitempty"`
+	RestartCount int       `json:"restart_count"`
+
+	iamMode string
 }
 
-// Status returns health status of all services
+// Status returns health and container status of all services.
+//
+// Container details come from docker compose; health comes from probing
+// each service directly, so a stack running in native mode is still
+// reported. An error is returned only if Docker cannot be queried and no
+// service responds.
 func Status(cfg *config.Config) (*StackStatus, error) {
-	status := &StackStatus{}
+	status := &StackStatus{Healthy: true}
+
+	containers, containerErr := inspectContainers()
+	if containerErr != nil {
+		status.ContainerError = containerErr.Error()
+	}
+
+	anyReachable := false
+	for _, svc := range Services(cfg) {
+		info := ServiceInfo{
+			Name:        svc.Name,
+			DisplayName: svc.DisplayName,
+			Ports:       []int{svc.GRPCPort, svc.HTTPPort},
+			HealthURL:   svc.HealthURL(),
+			Container:   containers[svc.Name],
+		}
+
+		start := time.Now()
+		err := health.CheckHTTP(context.Background(), info.HealthURL)
+		info.HealthLatency = time.Since(start)
+		if err != nil {
+			info.HealthError = err.Error()
+		}
 
-	// Check IAM health (health server on gRPC port + 1000)
-	status.IAM = checkHealth(fmt.Sprintf("http://localhost:%d/health", cfg.Ports.IAM+1000))
+		probeGRPC(&info, svc)
+		if err == nil || info.RPC != nil && info.RPC.Serving {
+			anyReachable = true
+		}
 
-	// Check Secret Manager health (HTTP port is 8081, mapped from container 8080)
-	status.SecretManager = checkHealth("http://localhost:8081/health")
+		if info.Container != nil {
+			info.IAMMode = info.Container.iamMode
+		}
 
-	// Check KMS health (HTTP port is 8082, mapped from container 8080)
-	status.KMS = checkHealth("http://localhost:8082/health")
+		info.Status = classify(info.Container, err == nil)
+		running := info.Container == nil || info.Container.State == "running"
+		if info.Status != ServiceStarting && running {
+			info.Status, info.Problem = degradation(info)
+		}
+		if info.Status != ServiceUp {
+			status.Healthy = false
+		}
+
+		status.Services = append(status.Services, info)
+	}
+
+	if containerErr != nil && !anyReachable {
+		return status, fmt.Errorf("failed to query containers: %w", containerErr)
+	}
 
 	return status, nil
 }
 
-func checkHealth(url string) ServiceStatus {
-	client := &http.Client{
-		Timeout: 2 * time.Second,
+// classify combines container state and the health probe into a status
+func classify(c *ContainerInfo, healthy bool) ServiceStatus {
+	if c == nil {
+		// No container: native mode or not started
+		if healthy {
+			return ServiceUp
+		}
+		return ServiceDown
 	}
 
-	resp, err := client.Get(url)
-	if err != nil {
+	if c.State != "running" {
 		return ServiceDown
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode == 200 {
+	if healthy {
 		return ServiceUp
 	}
 
+	if c.Health == "starting" || time.Since(c.StartedAt) < startupGrace {
+		return ServiceStarting
+	}
+
 	return ServiceDown
 }
+
+// probeGRPC checks the gRPC port with grpc.health.v1 and a real RPC
+func probeGRPC(info *ServiceInfo, svc Service) {
+	info.GRPCAddr = svc.GRPCAddr()
+
+	conn, err := grpc.NewClient(info.GRPCAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		info.GRPCHealth = health.GRPCUnreachable
+		info.GRPCError = err.Error()
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	info.GRPCHealth, err = health.CheckGRPC(ctx, conn)
+	info.GRPCLatency = time.Since(start)
+	if err != nil {
+		info.GRPCError = err.Error()
+		return
+	}
+
+	var result health.RPCResult
+	start = time.Now()
+	switch svc.Name {
+	case "iam":
+		result = health.ProbeIAM(ctx, conn)
+	case "secret-manager":
+		result = health.ProbeSecretManager(ctx, conn)
+	case "kms":
+		result = health.ProbeKMS(ctx, conn)
+	default:
+		return
+	}
+	info.RPCLatency = time.Since(start)
+	info.RPC = &result
+}
+
+// degradation refines a status from the HTTP probe alone using the gRPC
+// probes, so "HTTP up, gRPC broken" and "IAM unreachable from the data
+// plane" are reported rather than hidden behind a green /health
+func degradation(info ServiceInfo) (ServiceStatus, string) {
+	httpUp := info.HealthError == ""
+	grpcUp := info.GRPCHealth == health.GRPCServing || info.GRPCHealth == health.GRPCUnimplemented
+	rpcUp := info.RPC != nil && info.RPC.Serving
+
+	switch {
+	case !httpUp && !grpcUp && !rpcUp:
+		return ServiceDown, "not responding"
+	case !httpUp:
+		return ServiceDegraded, "HTTP health endpoint down, gRPC up"
+	case info.GRPCHealth == health.GRPCNotServing:
+		return ServiceDegraded, "HTTP up, gRPC reports NOT_SERVING"
+	case !rpcUp:
+		return ServiceDegraded, "HTTP up, gRPC broken"
+	case info.RPC.IAM == health.IAMUnreachable:
+		return ServiceDegraded, "IAM unreachable from data plane"
+	}
+
+	return ServiceUp, ""
+}
+
+// composeContainer is one entry of `docker compose ps --format json`
+type composeContainer struct {
+	ID      string `json:"ID"`
+	Service string `json:"Service"`
+	State   string `json:"State"`
+	Health  string `json:"Health"`
+}
+
+// containerInspect is the subset of `docker inspect` output we use
+type containerInspect struct {
+	ID    string `json:"Id"`
+	Image string `json:"Image"`
+	State struct {
+		Status    string    `json:"Status"`
+		StartedAt time.Time `json:"StartedAt"`
+		Health    *struct {
+			Status string `json:"Status"`
+		} `json:"Health"`
+	} `json:"State"`
+	RestartCount int `json:"RestartCount"`
+	Config       struct {
+		Image string   `json:"Image"`
+		Env   []string `json:"Env"`
+	} `json:"Config"`
+}
+
+// inspectContainers returns container details keyed by compose service name
+func inspectContainers() (map[string]*ContainerInfo, error) {
+	binary, baseArgs := getComposeCommand()
+	args := append(baseArgs, "ps", "--all", "--format", "json")
+
+	output, err := exec.Command(binary, args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("docker compose ps failed: %w", err)
+	}
+
+	entries, err := parseComposePS(output)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]*ContainerInfo)
+	if len(entries) == 0 {
+		return result, nil
+	}
+
+	ids := make([]string, 0, len(entries))
+	for _, e := range entries {
+		ids = append(ids, e.ID)
+	}
+
+	output, err = exec.Command("docker", append([]string{"inspect"}, ids...)...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("docker inspect failed: %w", err)
+	}
+
+	var inspected []containerInspect
+	if err := json.Unmarshal(output, &inspected); err != nil {
+		return nil, fmt.Errorf("failed to parse docker inspect output: %w", err)
+	}
+
+	byID := make(map[string]containerInspect, len(inspected))
+	for _, c := range inspected {
+		byID[c.ID] = c
+	}
+
+	for _, e := range entries {
+		c, ok := findInspect(byID, e.ID)
+		if !ok {
+			continue
+		}
+
+		info := &ContainerInfo{
+			ID:           shortID(c.ID),
+			Image:        c.Config.Image,
+			ImageDigest:  imageDigest(c.Image),
+			State:        c.State.Status,
+			StartedAt:    c.State.StartedAt,
+			RestartCount: c.RestartCount,
+			iamMode:      envValue(c.Config.Env, "IAM_MODE"),
+		}
+		if c.State.Health != nil {
+			info.Health = c.State.Health.Status
+		}
+		if info.State == "running" && !info.StartedAt.IsZero() {
+			info.Uptime = time.Since(info.StartedAt).Round(time.Second).String()
+		}
+
+		result[e.Service] = info
+	}
+
+	return result, nil
+}
+
+// parseComposePS accepts both output styles of `docker compose ps --format json`:
+// a JSON array (compose < 2.21) or one JSON object per line
+func parseComposePS(data []byte) ([]composeContainer, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var entries []composeContainer
+	if data[0] == '[' {
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, fmt.Errorf("failed to parse docker compose ps output: %w", err)
+		}
+		return entries, nil
+	}
+
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		var e composeContainer
+		if err := json.Unmarshal(line, &e); err != nil {
+			return nil, fmt.Errorf("failed to parse docker compose ps output: %w", err)
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+// imageDigest returns the repo digest of an image, falling back to its ID
+func imageDigest(imageID string) string {
+	output, err := exec.Command("docker", "image", "inspect", "--format", "{{json .RepoDigests}}", imageID).Output()
+	if err == nil {
+		var digests []string
+		if json.Unmarshal(output, &digests) == nil && len(digests) > 0 {
+			if _, digest, ok := strings.Cut(digests[0], "@"); ok {
+				return digest
+			}
+		}
+	}
+
+	return imageID
+}
+
+func findInspect(byID map[string]containerInspect, id string) (containerInspect, bool) {
+	if c, ok := byID[id]; ok {
+		return c, true
+	}
+
+	// compose may report short IDs
+	for full, c := range byID {
+		if strings.HasPrefix(full, id) {
+			return c, true
+		}
+	}
+
+	return containerInspect{}, false
+}
+
+func envValue(env []string, key string) string {
+	for _, kv := range env {
+		if k, v, ok := strings.Cut(kv, "="); ok && k == key {
+			return v
+		}
+	}
+	return ""
+}
+
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
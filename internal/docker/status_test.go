@@ -0,0 +1,90 @@
+package docker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/health"
+)
+
+func TestParseComposePS(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  int
+	}{
+		{"empty", "", 0},
+		{"array", `[{"ID":"abc","Service":"iam","State":"running"},{"ID":"def","Service":"kms","State":"exited"}]`, 2},
+		{"json lines", "{\"ID\":\"abc\",\"Service\":\"iam\",\"State\":\"running\"}\n{\"ID\":\"def\",\"Service\":\"kms\",\"State\":\"exited\"}\n", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := parseComposePS([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("parseComposePS() error = %v", err)
+			}
+			if len(entries) != tt.want {
+				t.Errorf("got %d entries, want %d", len(entries), tt.want)
+			}
+		})
+	}
+
+	if _, err := parseComposePS([]byte("not json")); err == nil {
+		t.Error("Expected error for invalid output")
+	}
+}
+
+func TestClassify(t *testing.T) {
+	old := time.Now().Add(-10 * time.Minute)
+	recent := time.Now().Add(-5 * time.Second)
+
+	tests := []struct {
+		name      string
+		container *ContainerInfo
+		healthy   bool
+		want      ServiceStatus
+	}{
+		{"native up", nil, true, ServiceUp},
+		{"native down", nil, false, ServiceDown},
+		{"running healthy", &ContainerInfo{State: "running", StartedAt: old}, true, ServiceUp},
+		{"exited", &ContainerInfo{State: "exited", StartedAt: old}, true, ServiceDown},
+		{"docker health starting", &ContainerInfo{State: "running", Health: "starting", StartedAt: old}, false, ServiceStarting},
+		{"within grace period", &ContainerInfo{State: "running", StartedAt: recent}, false, ServiceStarting},
+		{"past grace period", &ContainerInfo{State: "running", StartedAt: old}, false, ServiceDown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classify(tt.container, tt.healthy); got != tt.want {
+				t.Errorf("classify() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDegradation(t *testing.T) {
+	reachable := &health.RPCResult{Serving: true, IAM: health.IAMReachable}
+
+	tests := []struct {
+		name string
+		info ServiceInfo
+		want ServiceStatus
+	}{
+		{"all up", ServiceInfo{GRPCHealth: health.GRPCServing, RPC: reachable}, ServiceUp},
+		{"no grpc health service", ServiceInfo{GRPCHealth: health.GRPCUnimplemented, RPC: reachable}, ServiceUp},
+		{"grpc broken", ServiceInfo{GRPCHealth: health.GRPCUnreachable}, ServiceDegraded},
+		{"grpc not serving", ServiceInfo{GRPCHealth: health.GRPCNotServing, RPC: reachable}, ServiceDegraded},
+		{"iam unreachable", ServiceInfo{GRPCHealth: health.GRPCServing, RPC: &health.RPCResult{Serving: true, IAM: health.IAMUnreachable}}, ServiceDegraded},
+		{"http down grpc up", ServiceInfo{HealthError: "refused", GRPCHealth: health.GRPCServing, RPC: reachable}, ServiceDegraded},
+		{"nothing responds", ServiceInfo{HealthError: "refused", GRPCHealth: health.GRPCUnreachable}, ServiceDown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := degradation(tt.info); got != tt.want {
+				t.Errorf("degradation() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
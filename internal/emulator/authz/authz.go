@@ -0,0 +1,163 @@
+// Package authz implements the data plane side of the integration contract:
+// principal extraction from incoming requests and permission checks against
+// the IAM emulator using the off/permissive/strict modes.
+package authz
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	iampb "cloud.google.com/go/iam/apiv1/iampb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// PrincipalMetadataKey is the gRPC metadata key carrying the caller identity
+const PrincipalMetadataKey = "x-emulator-principal"
+
+// PrincipalHeader is the HTTP header carrying the caller identity
+const PrincipalHeader = "X-Emulator-Principal"
+
+// DelegatesMetadataKey is the gRPC metadata key carrying an impersonation
+// chain: comma-separated service account emails the principal impersonates
+// in turn, the request being made as the last
+const DelegatesMetadataKey = "x-emulator-delegates"
+
+// DelegatesHeader is the HTTP header carrying an impersonation chain
+const DelegatesHeader = "X-Emulator-Delegates"
+
+// CallerMetadataKey is the gRPC metadata key a data plane uses to identify
+// itself to IAM, so decisions can be attributed to the calling service
+const CallerMetadataKey = "x-emulator-caller"
+
+// StatusCaller is the CallerMetadataKey value sent by gcp-emulator status
+// probes
+const StatusCaller = "gcp-emulator-status"
+
+// ProbePrincipal is the identity status probes are made as. It should not
+// be granted anything, so a data plane that consulted IAM denies the probe.
+// Its checks are not audited.
+const ProbePrincipal = "serviceAccount:status-probe@gcp-emulator.iam.gserviceaccount.com"
+
+// IAM modes (see docs/INTEGRATION_CONTRACT.md)
+const (
+	ModeOff        = "off"
+	ModePermissive = "permissive"
+	ModeStrict     = "strict"
+)
+
+// Checker performs permission checks against the IAM emulator
+type Checker struct {
+	Mode   string
+	Client iampb.IAMPolicyClient
+	// Caller names the data plane in checks sent to IAM (e.g. secret-manager)
+	Caller string
+}
+
+// PrincipalFromContext extracts the principal from incoming gRPC metadata
+func PrincipalFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	principals := md.Get(PrincipalMetadataKey)
+	if len(principals) == 0 {
+		return ""
+	}
+
+	return principals[0]
+}
+
+// DelegatesFromContext extracts the impersonation chain from incoming gRPC
+// metadata (nil if there is none)
+func DelegatesFromContext(ctx context.Context) []string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	var delegates []string
+	for _, value := range md.Get(DelegatesMetadataKey) {
+		for _, delegate := range strings.Split(value, ",") {
+			if delegate = strings.TrimSpace(delegate); delegate != "" {
+				delegates = append(delegates, delegate)
+			}
+		}
+	}
+
+	return delegates
+}
+
+// CallerFromContext returns the calling service from incoming gRPC
+// metadata, falling back to the client's user agent
+func CallerFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	if callers := md.Get(CallerMetadataKey); len(callers) > 0 {
+		return callers[0]
+	}
+	if agents := md.Get("user-agent"); len(agents) > 0 {
+		return agents[0]
+	}
+
+	return ""
+}
+
+// Check returns nil if the caller in ctx holds permission on resource.
+//
+// In off mode no check is made. In permissive mode requests without a
+// principal and requests made while the IAM emulator is unreachable are
+// allowed; in strict mode both are denied.
+func (c *Checker) Check(ctx context.Context, resource, permission string) error {
+	if c == nil || c.Mode == ModeOff || c.Mode == "" {
+		return nil
+	}
+
+	principal := PrincipalFromContext(ctx)
+	if principal == "" {
+		if c.Mode == ModePermissive {
+			return nil
+		}
+		return status.Error(codes.PermissionDenied, "Permission denied: no principal provided")
+	}
+
+	outCtx := metadata.AppendToOutgoingContext(ctx, PrincipalMetadataKey, principal)
+	if delegates := DelegatesFromContext(ctx); len(delegates) > 0 {
+		outCtx = metadata.AppendToOutgoingContext(outCtx, DelegatesMetadataKey, strings.Join(delegates, ","))
+	}
+	if c.Caller != "" {
+		outCtx = metadata.AppendToOutgoingContext(outCtx, CallerMetadataKey, c.Caller)
+	}
+	resp, err := c.Client.TestIamPermissions(outCtx, &iampb.TestIamPermissionsRequest{
+		Resource:    resource,
+		Permissions: []string{permission},
+	})
+	if err != nil {
+		if isConnectivityError(err) && c.Mode == ModePermissive {
+			return nil
+		}
+		return status.Errorf(codes.Internal, "IAM check failed: %v", err)
+	}
+
+	for _, granted := range resp.Permissions {
+		if granted == permission {
+			return nil
+		}
+	}
+
+	return status.Error(codes.PermissionDenied, fmt.Sprintf("Permission '%s' denied on resource '%s'", permission, resource))
+}
+
+func isConnectivityError(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Canceled:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,174 @@
+// Package iam implements an in-process IAM authorization server backed by
+// internal/policy. It serves the google.iam.v1.IAMPolicy gRPC service that
+// data plane emulators call to check permissions.
+package iam
+
+import (
+	"context"
+	"encoding/base64"
+	"log/slog"
+	"sync"
+	"time"
+
+	iampb "cloud.google.com/go/iam/apiv1/iampb"
+	exprpb "google.golang.org/genproto/googleapis/type/expr"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/audit"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/emulator/authz"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+// Server evaluates TestIamPermissions requests against a policy
+type Server struct {
+	iampb.UnimplementedIAMPolicyServer
+
+	mu     sync.RWMutex
+	policy *policy.Policy
+	logger *slog.Logger
+	audit  *audit.Writer
+}
+
+// NewServer creates an IAM server for the given policy
+func NewServer(pol *policy.Policy, logger *slog.Logger) *Server {
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+
+	return &Server{
+		policy: pol,
+		logger: logger,
+	}
+}
+
+// SetPolicy atomically replaces the policy used for evaluation
+func (s *Server) SetPolicy(pol *policy.Policy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.policy = pol
+}
+
+// SetAuditLog records every decision to w (nil disables auditing)
+func (s *Server) SetAuditLog(w *audit.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.audit = w
+}
+
+// Policy returns the policy currently used for evaluation
+func (s *Server) Policy() *policy.Policy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.policy
+}
+
+// TestIamPermissions returns the subset of requested permissions the caller holds
+func (s *Server) TestIamPermissions(ctx context.Context, req *iampb.TestIamPermissionsRequest) (*iampb.TestIamPermissionsResponse, error) {
+	if req.Resource == "" {
+		return nil, status.Error(codes.InvalidArgument, "resource is required")
+	}
+
+	principal := authz.PrincipalFromContext(ctx)
+	delegates := authz.DelegatesFromContext(ctx)
+	caller := authz.CallerFromContext(ctx)
+
+	s.mu.RLock()
+	pol, auditLog := s.policy, s.audit
+	s.mu.RUnlock()
+
+	// Status probes are denied on every tick, directly or through a data
+	// plane; auditing them would bury real denials
+	if caller == authz.StatusCaller || principal == authz.ProbePrincipal {
+		auditLog = nil
+	}
+
+	resp := &iampb.TestIamPermissionsResponse{}
+	for _, permission := range req.Permissions {
+		start := time.Now()
+		decision := policy.Evaluate(pol, policy.Request{
+			Principal:  principal,
+			Resource:   req.Resource,
+			Permission: permission,
+			Delegates:  delegates,
+		})
+
+		outcome := "DENY"
+		if decision.Allowed {
+			outcome = "ALLOW"
+			resp.Permissions = append(resp.Permissions, permission)
+		}
+
+		latency := time.Since(start)
+
+		attrs := []any{
+			"principal", principal,
+			"resource", req.Resource,
+			"permission", permission,
+			"decision", outcome,
+			"reason", decision.Reason,
+			"caller", caller,
+			"latency", latency,
+		}
+		if len(delegates) > 0 {
+			attrs = append(attrs, "delegates", delegates)
+		}
+		s.logger.Info("authz_check", attrs...)
+
+		if auditLog != nil {
+			if err := auditLog.Write(audit.FromDecision(decision, caller, latency)); err != nil {
+				s.logger.Warn("failed to write audit event", "error", err)
+			}
+		}
+	}
+
+	return resp, nil
+}
+
+// GetIamPolicy returns the bindings configured on the resource in the
+// policy's resources section, those of the service account it names, or
+// else those of the project that owns it
+func (s *Server) GetIamPolicy(ctx context.Context, req *iampb.GetIamPolicyRequest) (*iampb.Policy, error) {
+	projectID := policy.ProjectFromResource(req.Resource)
+	if projectID == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "resource %q is not within a project", req.Resource)
+	}
+
+	pol := s.Policy()
+	project, ok := pol.Projects[projectID]
+	if !ok {
+		return &iampb.Policy{Version: 3}, nil
+	}
+
+	out := &iampb.Policy{Version: 3}
+	bindings := project.Bindings
+	if resource, ok := pol.Resources[req.Resource]; ok {
+		bindings = resource.Bindings
+	} else if _, email, ok := policy.ParseServiceAccountResource(req.Resource); ok {
+		bindings = project.ServiceAccounts[email].Bindings
+	} else {
+		if project.Version != 0 {
+			out.Version = int32(project.Version)
+		}
+		if etag, err := base64.StdEncoding.DecodeString(project.Etag); err == nil {
+			out.Etag = etag
+		}
+	}
+
+	for _, binding := range bindings {
+		b := &iampb.Binding{
+			Role:    binding.Role,
+			Members: binding.Members,
+		}
+		if binding.Condition != nil {
+			b.Condition = &exprpb.Expr{
+				Expression:  binding.Condition.Expression,
+				Title:       binding.Condition.Title,
+				Description: binding.Condition.Description,
+			}
+		}
+		out.Bindings = append(out.Bindings, b)
+	}
+
+	return out, nil
+}
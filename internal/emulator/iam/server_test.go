@@ -0,0 +1,85 @@
+package iam
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	iampb "cloud.google.com/go/iam/apiv1/iampb"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/audit"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/emulator/authz"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+func TestStatusProbesNotAudited(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	w, err := audit.NewWriter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewServer(&policy.Policy{Projects: map[string]policy.Project{}}, nil)
+	s.SetAuditLog(w)
+
+	check := func(kv ...string) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(kv...))
+		if _, err := s.TestIamPermissions(ctx, &iampb.TestIamPermissionsRequest{
+			Resource:    "projects/p",
+			Permissions: []string{"secretmanager.secrets.list"},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// gcp-emulator status calling IAM directly, then through a data plane
+	check(authz.PrincipalMetadataKey, authz.ProbePrincipal, authz.CallerMetadataKey, authz.StatusCaller)
+	check(authz.PrincipalMetadataKey, authz.ProbePrincipal, authz.CallerMetadataKey, "secret-manager")
+	check(authz.PrincipalMetadataKey, "user:alice@example.com", authz.CallerMetadataKey, "secret-manager")
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	events, err := audit.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Actor.Principal != "user:alice@example.com" {
+		t.Errorf("audited %+v, want only alice's check", events)
+	}
+}
+
+func TestGetIamPolicyServiceAccount(t *testing.T) {
+	s := NewServer(&policy.Policy{Projects: map[string]policy.Project{
+		"p": {
+			Etag:     "BwYd7W5RmCo=",
+			Bindings: []policy.Binding{{Role: "roles/viewer", Members: []string{"user:alice@example.com"}}},
+			ServiceAccounts: map[string]policy.ServiceAccount{
+				"ci@p.iam.gserviceaccount.com": {Bindings: []policy.Binding{
+					{Role: "roles/iam.serviceAccountTokenCreator", Members: []string{"user:bob@example.com"}},
+				}},
+			},
+		},
+	}}, nil)
+
+	for _, resource := range []string{
+		"projects/p/serviceAccounts/ci@p.iam.gserviceaccount.com",
+		"projects/-/serviceAccounts/ci@p.iam.gserviceaccount.com",
+	} {
+		got, err := s.GetIamPolicy(context.Background(), &iampb.GetIamPolicyRequest{Resource: resource})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Bindings) != 1 || got.Bindings[0].Role != "roles/iam.serviceAccountTokenCreator" || len(got.Etag) != 0 {
+			t.Errorf("%s: got %+v, want the service account's binding and no project etag", resource, got)
+		}
+	}
+
+	got, err := s.GetIamPolicy(context.Background(), &iampb.GetIamPolicyRequest{Resource: "projects/p/serviceAccounts/other@p.iam.gserviceaccount.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Bindings) != 0 {
+		t.Errorf("service account without bindings: got %+v", got.Bindings)
+	}
+}
@@ -0,0 +1,474 @@
+// Package kms implements a lightweight in-memory Cloud KMS gRPC server for
+// the in-process stack. It supports key rings, symmetric crypto keys and
+// encrypt/decrypt. Every operation checks its permission through
+// authz.Checker before touching state.
+package kms
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/emulator/authz"
+)
+
+var (
+	idPattern       = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,63}$`)
+	locationPattern = regexp.MustCompile(`^projects/[^/]+/locations/[^/]+$`)
+)
+
+type keyRing struct {
+	meta *kmspb.KeyRing
+	keys map[string]*cryptoKey
+}
+
+type cryptoKey struct {
+	meta     *kmspb.CryptoKey
+	versions []*keyVersion
+}
+
+type keyVersion struct {
+	meta *kmspb.CryptoKeyVersion
+	aead cipher.AEAD
+}
+
+// Server is an in-memory KMS implementation
+type Server struct {
+	kmspb.UnimplementedKeyManagementServiceServer
+
+	checker *authz.Checker
+
+	mu       sync.Mutex
+	keyRings map[string]*keyRing
+}
+
+// NewServer creates a KMS server that authorizes through checker
+func NewServer(checker *authz.Checker) *Server {
+	return &Server{
+		checker:  checker,
+		keyRings: make(map[string]*keyRing),
+	}
+}
+
+// CreateKeyRing creates a key ring in a location
+func (s *Server) CreateKeyRing(ctx context.Context, req *kmspb.CreateKeyRingRequest) (*kmspb.KeyRing, error) {
+	if !locationPattern.MatchString(req.Parent) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid parent: %s (expected projects/{project}/locations/{location})", req.Parent)
+	}
+	if !idPattern.MatchString(req.KeyRingId) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid key_ring_id: %q", req.KeyRingId)
+	}
+	if err := s.checker.Check(ctx, req.Parent, "cloudkms.keyRings.create"); err != nil {
+		return nil, err
+	}
+
+	name := req.Parent + "/keyRings/" + req.KeyRingId
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.keyRings[name]; exists {
+		return nil, status.Errorf(codes.AlreadyExists, "KeyRing %s already exists.", name)
+	}
+
+	kr := &keyRing{
+		meta: &kmspb.KeyRing{Name: name, CreateTime: timestamppb.Now()},
+		keys: make(map[string]*cryptoKey),
+	}
+	s.keyRings[name] = kr
+
+	return proto.Clone(kr.meta).(*kmspb.KeyRing), nil
+}
+
+// GetKeyRing returns key ring metadata
+func (s *Server) GetKeyRing(ctx context.Context, req *kmspb.GetKeyRingRequest) (*kmspb.KeyRing, error) {
+	if err := s.checker.Check(ctx, req.Name, "cloudkms.keyRings.get"); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kr, err := s.lookupKeyRing(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return proto.Clone(kr.meta).(*kmspb.KeyRing), nil
+}
+
+// ListKeyRings lists key rings in a location
+func (s *Server) ListKeyRings(ctx context.Context, req *kmspb.ListKeyRingsRequest) (*kmspb.ListKeyRingsResponse, error) {
+	if err := s.checker.Check(ctx, req.Parent, "cloudkms.keyRings.list"); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prefix := req.Parent + "/keyRings/"
+	resp := &kmspb.ListKeyRingsResponse{}
+	for name, kr := range s.keyRings {
+		if strings.HasPrefix(name, prefix) {
+			resp.KeyRings = append(resp.KeyRings, proto.Clone(kr.meta).(*kmspb.KeyRing))
+		}
+	}
+	sort.Slice(resp.KeyRings, func(i, j int) bool { return resp.KeyRings[i].Name < resp.KeyRings[j].Name })
+	resp.TotalSize = int32(len(resp.KeyRings))
+
+	return resp, nil
+}
+
+// CreateCryptoKey creates a crypto key with an initial primary version
+func (s *Server) CreateCryptoKey(ctx context.Context, req *kmspb.CreateCryptoKeyRequest) (*kmspb.CryptoKey, error) {
+	if !idPattern.MatchString(req.CryptoKeyId) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid crypto_key_id: %q", req.CryptoKeyId)
+	}
+	if err := s.checker.Check(ctx, req.Parent, "cloudkms.cryptoKeys.create"); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kr, err := s.lookupKeyRing(req.Parent)
+	if err != nil {
+		return nil, err
+	}
+
+	name := req.Parent + "/cryptoKeys/" + req.CryptoKeyId
+	if _, exists := kr.keys[name]; exists {
+		return nil, status.Errorf(codes.AlreadyExists, "CryptoKey %s already exists.", name)
+	}
+
+	meta := &kmspb.CryptoKey{}
+	if req.CryptoKey != nil {
+		meta = proto.Clone(req.CryptoKey).(*kmspb.CryptoKey)
+	}
+	meta.Name = name
+	meta.CreateTime = timestamppb.Now()
+	if meta.Purpose == kmspb.CryptoKey_CRYPTO_KEY_PURPOSE_UNSPECIFIED {
+		return nil, status.Error(codes.InvalidArgument, "crypto_key.purpose is required")
+	}
+
+	key := &cryptoKey{meta: meta}
+	if !req.SkipInitialVersionCreation {
+		v, err := key.addVersion()
+		if err != nil {
+			return nil, err
+		}
+		meta.Primary = v.meta
+	}
+	kr.keys[name] = key
+
+	return proto.Clone(meta).(*kmspb.CryptoKey), nil
+}
+
+// GetCryptoKey returns crypto key metadata
+func (s *Server) GetCryptoKey(ctx context.Context, req *kmspb.GetCryptoKeyRequest) (*kmspb.CryptoKey, error) {
+	if err := s.checker.Check(ctx, req.Name, "cloudkms.cryptoKeys.get"); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, err := s.lookupCryptoKey(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return proto.Clone(key.meta).(*kmspb.CryptoKey), nil
+}
+
+// ListCryptoKeys lists crypto keys in a key ring
+func (s *Server) ListCryptoKeys(ctx context.Context, req *kmspb.ListCryptoKeysRequest) (*kmspb.ListCryptoKeysResponse, error) {
+	if err := s.checker.Check(ctx, req.Parent, "cloudkms.cryptoKeys.list"); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kr, err := s.lookupKeyRing(req.Parent)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &kmspb.ListCryptoKeysResponse{}
+	for _, key := range kr.keys {
+		resp.CryptoKeys = append(resp.CryptoKeys, proto.Clone(key.meta).(*kmspb.CryptoKey))
+	}
+	sort.Slice(resp.CryptoKeys, func(i, j int) bool { return resp.CryptoKeys[i].Name < resp.CryptoKeys[j].Name })
+	resp.TotalSize = int32(len(resp.CryptoKeys))
+
+	return resp, nil
+}
+
+// CreateCryptoKeyVersion adds a new version to a crypto key
+func (s *Server) CreateCryptoKeyVersion(ctx context.Context, req *kmspb.CreateCryptoKeyVersionRequest) (*kmspb.CryptoKeyVersion, error) {
+	if err := s.checker.Check(ctx, req.Parent, "cloudkms.cryptoKeyVersions.create"); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, err := s.lookupCryptoKey(req.Parent)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := key.addVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	return proto.Clone(v.meta).(*kmspb.CryptoKeyVersion), nil
+}
+
+// GetCryptoKeyVersion returns version metadata
+func (s *Server) GetCryptoKeyVersion(ctx context.Context, req *kmspb.GetCryptoKeyVersionRequest) (*kmspb.CryptoKeyVersion, error) {
+	if err := s.checker.Check(ctx, req.Name, "cloudkms.cryptoKeyVersions.get"); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keyName, _, _ := strings.Cut(req.Name, "/cryptoKeyVersions/")
+	key, err := s.lookupCryptoKey(keyName)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := key.lookupVersion(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return proto.Clone(v.meta).(*kmspb.CryptoKeyVersion), nil
+}
+
+// ListCryptoKeyVersions lists the versions of a crypto key
+func (s *Server) ListCryptoKeyVersions(ctx context.Context, req *kmspb.ListCryptoKeyVersionsRequest) (*kmspb.ListCryptoKeyVersionsResponse, error) {
+	if err := s.checker.Check(ctx, req.Parent, "cloudkms.cryptoKeyVersions.list"); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, err := s.lookupCryptoKey(req.Parent)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &kmspb.ListCryptoKeyVersionsResponse{}
+	for _, v := range key.versions {
+		resp.CryptoKeyVersions = append(resp.CryptoKeyVersions, proto.Clone(v.meta).(*kmspb.CryptoKeyVersion))
+	}
+	resp.TotalSize = int32(len(resp.CryptoKeyVersions))
+
+	return resp, nil
+}
+
+// Encrypt encrypts plaintext with the key's primary version
+func (s *Server) Encrypt(ctx context.Context, req *kmspb.EncryptRequest) (*kmspb.EncryptResponse, error) {
+	keyName, _, _ := strings.Cut(req.Name, "/cryptoKeyVersions/")
+	if err := s.checker.Check(ctx, keyName, "cloudkms.cryptoKeys.encrypt"); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, err := s.lookupCryptoKey(keyName)
+	if err != nil {
+		return nil, err
+	}
+	if key.meta.Purpose != kmspb.CryptoKey_ENCRYPT_DECRYPT {
+		return nil, status.Errorf(codes.FailedPrecondition, "%s has purpose %s, not ENCRYPT_DECRYPT", keyName, key.meta.Purpose)
+	}
+
+	var v *keyVersion
+	if req.Name != keyName {
+		v, err = key.lookupVersion(req.Name)
+		if err != nil {
+			return nil, err
+		}
+	} else if v = key.primary(); v == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "%s has no primary version", keyName)
+	}
+
+	if v.meta.State != kmspb.CryptoKeyVersion_ENABLED {
+		return nil, status.Errorf(codes.FailedPrecondition, "%s is not enabled, current state is: %s.", v.meta.Name, v.meta.State)
+	}
+
+	nonce := make([]byte, v.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to generate nonce: %v", err)
+	}
+
+	// Ciphertext layout: version number (4 bytes) | nonce | sealed data
+	ciphertext := binary.BigEndian.AppendUint32(nil, uint32(versionNumber(v.meta.Name)))
+	ciphertext = append(ciphertext, nonce...)
+	ciphertext = v.aead.Seal(ciphertext, nonce, req.Plaintext, req.AdditionalAuthenticatedData)
+
+	return &kmspb.EncryptResponse{
+		Name:            v.meta.Name,
+		Ciphertext:      ciphertext,
+		ProtectionLevel: kmspb.ProtectionLevel_SOFTWARE,
+	}, nil
+}
+
+// Decrypt decrypts ciphertext produced by Encrypt
+func (s *Server) Decrypt(ctx context.Context, req *kmspb.DecryptRequest) (*kmspb.DecryptResponse, error) {
+	if err := s.checker.Check(ctx, req.Name, "cloudkms.cryptoKeys.decrypt"); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, err := s.lookupCryptoKey(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(req.Ciphertext) < 4 {
+		return nil, status.Error(codes.InvalidArgument, "Decryption failed: the ciphertext is invalid.")
+	}
+
+	n := int(binary.BigEndian.Uint32(req.Ciphertext[:4]))
+	if n < 1 || n > len(key.versions) {
+		return nil, status.Error(codes.InvalidArgument, "Decryption failed: the ciphertext is invalid.")
+	}
+
+	v := key.versions[n-1]
+	if v.meta.State != kmspb.CryptoKeyVersion_ENABLED {
+		return nil, status.Errorf(codes.FailedPrecondition, "%s is not enabled, current state is: %s.", v.meta.Name, v.meta.State)
+	}
+
+	body := req.Ciphertext[4:]
+	if len(body) < v.aead.NonceSize() {
+		return nil, status.Error(codes.InvalidArgument, "Decryption failed: the ciphertext is invalid.")
+	}
+
+	plaintext, err := v.aead.Open(nil, body[:v.aead.NonceSize()], body[v.aead.NonceSize():], req.AdditionalAuthenticatedData)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "Decryption failed: the ciphertext is invalid.")
+	}
+
+	primary := key.primary()
+	return &kmspb.DecryptResponse{
+		Plaintext:       plaintext,
+		UsedPrimary:     primary != nil && primary == v,
+		ProtectionLevel: kmspb.ProtectionLevel_SOFTWARE,
+	}, nil
+}
+
+// lookupKeyRing must be called with s.mu held
+func (s *Server) lookupKeyRing(name string) (*keyRing, error) {
+	kr, ok := s.keyRings[name]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "KeyRing %s not found.", name)
+	}
+	return kr, nil
+}
+
+// lookupCryptoKey must be called with s.mu held
+func (s *Server) lookupCryptoKey(name string) (*cryptoKey, error) {
+	ringName, _, ok := strings.Cut(name, "/cryptoKeys/")
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid crypto key name: %s", name)
+	}
+
+	kr, err := s.lookupKeyRing(ringName)
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := kr.keys[name]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "CryptoKey %s not found.", name)
+	}
+
+	return key, nil
+}
+
+func (k *cryptoKey) addVersion() (*keyVersion, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to generate key material: %v", err)
+	}
+
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create cipher: %v", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create cipher: %v", err)
+	}
+
+	v := &keyVersion{
+		meta: &kmspb.CryptoKeyVersion{
+			Name:            fmt.Sprintf("%s/cryptoKeyVersions/%d", k.meta.Name, len(k.versions)+1),
+			State:           kmspb.CryptoKeyVersion_ENABLED,
+			ProtectionLevel: kmspb.ProtectionLevel_SOFTWARE,
+			Algorithm:       kmspb.CryptoKeyVersion_GOOGLE_SYMMETRIC_ENCRYPTION,
+			CreateTime:      timestamppb.Now(),
+		},
+		aead: aead,
+	}
+	k.versions = append(k.versions, v)
+
+	return v, nil
+}
+
+func (k *cryptoKey) primary() *keyVersion {
+	if k.meta.Primary == nil {
+		return nil
+	}
+
+	for _, v := range k.versions {
+		if v.meta.Name == k.meta.Primary.Name {
+			return v
+		}
+	}
+
+	return nil
+}
+
+func (k *cryptoKey) lookupVersion(name string) (*keyVersion, error) {
+	n := versionNumber(name)
+	if n < 1 || n > len(k.versions) || k.versions[n-1].meta.Name != name {
+		return nil, status.Errorf(codes.NotFound, "CryptoKeyVersion %s not found.", name)
+	}
+	return k.versions[n-1], nil
+}
+
+func versionNumber(name string) int {
+	_, id, _ := strings.Cut(name, "/cryptoKeyVersions/")
+	n, err := strconv.Atoi(id)
+	if err != nil {
+		return 0
+	}
+	return n
+}
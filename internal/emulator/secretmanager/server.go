@@ -0,0 +1,349 @@
+// Package secretmanager implements a lightweight in-memory Secret Manager
+// gRPC server for the in-process stack. Every operation checks its
+// permission through authz.Checker before touching state.
+package secretmanager
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/emulator/authz"
+)
+
+var secretIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,255}$`)
+
+type secret struct {
+	meta     *secretmanagerpb.Secret
+	versions []*version
+}
+
+type version struct {
+	meta    *secretmanagerpb.SecretVersion
+	payload []byte
+}
+
+// Server is an in-memory Secret Manager implementation
+type Server struct {
+	secretmanagerpb.UnimplementedSecretManagerServiceServer
+
+	checker *authz.Checker
+
+	mu      sync.Mutex
+	secrets map[string]*secret
+}
+
+// NewServer creates a Secret Manager server that authorizes through checker
+func NewServer(checker *authz.Checker) *Server {
+	return &Server{
+		checker: checker,
+		secrets: make(map[string]*secret),
+	}
+}
+
+// CreateSecret creates a secret with no versions
+func (s *Server) CreateSecret(ctx context.Context, req *secretmanagerpb.CreateSecretRequest) (*secretmanagerpb.Secret, error) {
+	if !strings.HasPrefix(req.Parent, "projects/") || strings.Count(req.Parent, "/") != 1 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid parent: %s (expected projects/{project})", req.Parent)
+	}
+	if !secretIDPattern.MatchString(req.SecretId) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid secret_id: %q", req.SecretId)
+	}
+	if err := s.checker.Check(ctx, req.Parent, "secretmanager.secrets.create"); err != nil {
+		return nil, err
+	}
+
+	name := req.Parent + "/secrets/" + req.SecretId
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.secrets[name]; exists {
+		return nil, status.Errorf(codes.AlreadyExists, "Secret [%s] already exists.", name)
+	}
+
+	meta := &secretmanagerpb.Secret{}
+	if req.Secret != nil {
+		meta = proto.Clone(req.Secret).(*secretmanagerpb.Secret)
+	}
+	meta.Name = name
+	meta.CreateTime = timestamppb.Now()
+
+	s.secrets[name] = &secret{meta: meta}
+
+	return proto.Clone(meta).(*secretmanagerpb.Secret), nil
+}
+
+// GetSecret returns secret metadata
+func (s *Server) GetSecret(ctx context.Context, req *secretmanagerpb.GetSecretRequest) (*secretmanagerpb.Secret, error) {
+	if err := s.checker.Check(ctx, req.Name, "secretmanager.secrets.get"); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sec, err := s.lookupSecret(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return proto.Clone(sec.meta).(*secretmanagerpb.Secret), nil
+}
+
+// ListSecrets lists secrets in a project
+func (s *Server) ListSecrets(ctx context.Context, req *secretmanagerpb.ListSecretsRequest) (*secretmanagerpb.ListSecretsResponse, error) {
+	if err := s.checker.Check(ctx, req.Parent, "secretmanager.secrets.list"); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prefix := req.Parent + "/secrets/"
+	resp := &secretmanagerpb.ListSecretsResponse{}
+	for name, sec := range s.secrets {
+		if strings.HasPrefix(name, prefix) {
+			resp.Secrets = append(resp.Secrets, proto.Clone(sec.meta).(*secretmanagerpb.Secret))
+		}
+	}
+	sort.Slice(resp.Secrets, func(i, j int) bool { return resp.Secrets[i].Name < resp.Secrets[j].Name })
+	resp.TotalSize = int32(len(resp.Secrets))
+
+	return resp, nil
+}
+
+// UpdateSecret updates labels and annotations on a secret
+func (s *Server) UpdateSecret(ctx context.Context, req *secretmanagerpb.UpdateSecretRequest) (*secretmanagerpb.Secret, error) {
+	if req.Secret == nil {
+		return nil, status.Error(codes.InvalidArgument, "secret is required")
+	}
+	if err := s.checker.Check(ctx, req.Secret.Name, "secretmanager.secrets.update"); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sec, err := s.lookupSecret(req.Secret.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := req.GetUpdateMask().GetPaths()
+	if len(paths) == 0 {
+		paths = []string{"labels", "annotations"}
+	}
+	for _, path := range paths {
+		switch path {
+		case "labels":
+			sec.meta.Labels = req.Secret.Labels
+		case "annotations":
+			sec.meta.Annotations = req.Secret.Annotations
+		default:
+			return nil, status.Errorf(codes.InvalidArgument, "unsupported update_mask path: %s", path)
+		}
+	}
+
+	return proto.Clone(sec.meta).(*secretmanagerpb.Secret), nil
+}
+
+// DeleteSecret deletes a secret and all of its versions
+func (s *Server) DeleteSecret(ctx context.Context, req *secretmanagerpb.DeleteSecretRequest) (*emptypb.Empty, error) {
+	if err := s.checker.Check(ctx, req.Name, "secretmanager.secrets.delete"); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.lookupSecret(req.Name); err != nil {
+		return nil, err
+	}
+	delete(s.secrets, req.Name)
+
+	return &emptypb.Empty{}, nil
+}
+
+// AddSecretVersion adds a new enabled version to a secret
+func (s *Server) AddSecretVersion(ctx context.Context, req *secretmanagerpb.AddSecretVersionRequest) (*secretmanagerpb.SecretVersion, error) {
+	if err := s.checker.Check(ctx, req.Parent, "secretmanager.versions.add"); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sec, err := s.lookupSecret(req.Parent)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &version{
+		meta: &secretmanagerpb.SecretVersion{
+			Name:       fmt.Sprintf("%s/versions/%d", req.Parent, len(sec.versions)+1),
+			CreateTime: timestamppb.Now(),
+			State:      secretmanagerpb.SecretVersion_ENABLED,
+		},
+		payload: req.GetPayload().GetData(),
+	}
+	sec.versions = append(sec.versions, v)
+
+	return proto.Clone(v.meta).(*secretmanagerpb.SecretVersion), nil
+}
+
+// GetSecretVersion returns version metadata
+func (s *Server) GetSecretVersion(ctx context.Context, req *secretmanagerpb.GetSecretVersionRequest) (*secretmanagerpb.SecretVersion, error) {
+	if err := s.checker.Check(ctx, req.Name, "secretmanager.versions.get"); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.lookupVersion(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return proto.Clone(v.meta).(*secretmanagerpb.SecretVersion), nil
+}
+
+// ListSecretVersions lists the versions of a secret
+func (s *Server) ListSecretVersions(ctx context.Context, req *secretmanagerpb.ListSecretVersionsRequest) (*secretmanagerpb.ListSecretVersionsResponse, error) {
+	if err := s.checker.Check(ctx, req.Parent, "secretmanager.versions.list"); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sec, err := s.lookupSecret(req.Parent)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &secretmanagerpb.ListSecretVersionsResponse{}
+	// Newest first, like the real API
+	for i := len(sec.versions) - 1; i >= 0; i-- {
+		resp.Versions = append(resp.Versions, proto.Clone(sec.versions[i].meta).(*secretmanagerpb.SecretVersion))
+	}
+	resp.TotalSize = int32(len(resp.Versions))
+
+	return resp, nil
+}
+
+// AccessSecretVersion returns the payload of an enabled version
+func (s *Server) AccessSecretVersion(ctx context.Context, req *secretmanagerpb.AccessSecretVersionRequest) (*secretmanagerpb.AccessSecretVersionResponse, error) {
+	if err := s.checker.Check(ctx, req.Name, "secretmanager.versions.access"); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.lookupVersion(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if v.meta.State != secretmanagerpb.SecretVersion_ENABLED {
+		return nil, status.Errorf(codes.FailedPrecondition, "SecretVersion [%s] is in %s state.", v.meta.Name, v.meta.State)
+	}
+
+	return &secretmanagerpb.AccessSecretVersionResponse{
+		Name:    v.meta.Name,
+		Payload: &secretmanagerpb.SecretPayload{Data: v.payload},
+	}, nil
+}
+
+// EnableSecretVersion enables a disabled version
+func (s *Server) EnableSecretVersion(ctx context.Context, req *secretmanagerpb.EnableSecretVersionRequest) (*secretmanagerpb.SecretVersion, error) {
+	return s.setVersionState(ctx, req.Name, "secretmanager.versions.enable", secretmanagerpb.SecretVersion_ENABLED)
+}
+
+// DisableSecretVersion disables an enabled version
+func (s *Server) DisableSecretVersion(ctx context.Context, req *secretmanagerpb.DisableSecretVersionRequest) (*secretmanagerpb.SecretVersion, error) {
+	return s.setVersionState(ctx, req.Name, "secretmanager.versions.disable", secretmanagerpb.SecretVersion_DISABLED)
+}
+
+// DestroySecretVersion irreversibly destroys a version's payload
+func (s *Server) DestroySecretVersion(ctx context.Context, req *secretmanagerpb.DestroySecretVersionRequest) (*secretmanagerpb.SecretVersion, error) {
+	return s.setVersionState(ctx, req.Name, "secretmanager.versions.destroy", secretmanagerpb.SecretVersion_DESTROYED)
+}
+
+func (s *Server) setVersionState(ctx context.Context, name, permission string, state secretmanagerpb.SecretVersion_State) (*secretmanagerpb.SecretVersion, error) {
+	if err := s.checker.Check(ctx, name, permission); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.lookupVersion(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if v.meta.State == secretmanagerpb.SecretVersion_DESTROYED {
+		return nil, status.Errorf(codes.FailedPrecondition, "SecretVersion [%s] is destroyed.", v.meta.Name)
+	}
+
+	v.meta.State = state
+	if state == secretmanagerpb.SecretVersion_DESTROYED {
+		v.payload = nil
+		v.meta.DestroyTime = timestamppb.Now()
+	}
+
+	return proto.Clone(v.meta).(*secretmanagerpb.SecretVersion), nil
+}
+
+// lookupSecret must be called with s.mu held
+func (s *Server) lookupSecret(name string) (*secret, error) {
+	sec, ok := s.secrets[name]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "Secret [%s] not found.", name)
+	}
+	return sec, nil
+}
+
+// lookupVersion resolves projects/{p}/secrets/{s}/versions/{v|latest}.
+// It must be called with s.mu held.
+func (s *Server) lookupVersion(name string) (*version, error) {
+	secretName, id, ok := strings.Cut(name, "/versions/")
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid version name: %s", name)
+	}
+
+	sec, err := s.lookupSecret(secretName)
+	if err != nil {
+		return nil, err
+	}
+
+	if id == "latest" {
+		for i := len(sec.versions) - 1; i >= 0; i-- {
+			if sec.versions[i].meta.State == secretmanagerpb.SecretVersion_ENABLED {
+				return sec.versions[i], nil
+			}
+		}
+		return nil, status.Errorf(codes.NotFound, "Secret [%s] has no enabled versions.", secretName)
+	}
+
+	n, err := strconv.Atoi(id)
+	if err != nil || n < 1 || n > len(sec.versions) {
+		return nil, status.Errorf(codes.NotFound, "Secret Version [%s] not found.", name)
+	}
+
+	return sec.versions[n-1], nil
+}
@@ -0,0 +1,172 @@
+package health
+
+import (
+	"context"
+	"strings"
+
+	iampb "cloud.google.com/go/iam/apiv1/iampb"
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/emulator/authz"
+)
+
+// ProbePrincipal is the identity sent with probe RPCs. It should not be
+// granted anything, so a data plane that consulted IAM denies the probe.
+const ProbePrincipal = authz.ProbePrincipal
+
+// ProbeProject is the project probe RPCs are made against
+const ProbeProject = "projects/gcp-emulator-status-probe"
+
+// GRPC health results
+const (
+	GRPCServing        = "SERVING"
+	GRPCNotServing     = "NOT_SERVING"
+	GRPCUnimplemented  = "UNIMPLEMENTED"
+	GRPCUnreachable    = "UNREACHABLE"
+	GRPCServiceUnknown = "SERVICE_UNKNOWN"
+)
+
+// CheckGRPC calls grpc.health.v1.Health/Check for the overall server and
+// returns the reported status. UNIMPLEMENTED means the server does not
+// expose the health service (its gRPC port is still up); the error is set
+// only for UNREACHABLE.
+func CheckGRPC(ctx context.Context, conn *grpc.ClientConn) (string, error) {
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		switch status.Code(err) {
+		case codes.Unimplemented:
+			return GRPCUnimplemented, nil
+		case codes.NotFound:
+			return GRPCServiceUnknown, nil
+		}
+		return GRPCUnreachable, err
+	}
+
+	switch resp.Status {
+	case healthpb.HealthCheckResponse_SERVING:
+		return GRPCServing, nil
+	case healthpb.HealthCheckResponse_SERVICE_UNKNOWN:
+		return GRPCServiceUnknown, nil
+	default:
+		return GRPCNotServing, nil
+	}
+}
+
+// IAM reachability as seen by a data plane
+const (
+	IAMReachable   = "reachable"
+	IAMUnreachable = "unreachable"
+	IAMUnknown     = "unknown"
+)
+
+// RPCResult describes the outcome of a probe RPC
+type RPCResult struct {
+	// Method is the RPC that was called
+	Method string `json:"method"`
+	// Code is the gRPC status code returned
+	Code string `json:"code"`
+	// Serving is true if the request reached the service implementation
+	Serving bool `json:"serving"`
+	// IAM reports whether a data plane got a decision from IAM; always
+	// unknown for the IAM service itself
+	IAM string `json:"iam"`
+	// Detail explains the interpretation
+	Detail string `json:"detail,omitempty"`
+}
+
+// ProbeIAM calls TestIamPermissions as the probe principal
+func ProbeIAM(ctx context.Context, conn *grpc.ClientConn) RPCResult {
+	_, err := iampb.NewIAMPolicyClient(conn).TestIamPermissions(probeContext(ctx), &iampb.TestIamPermissionsRequest{
+		Resource:    ProbeProject,
+		Permissions: []string{"secretmanager.secrets.list"},
+	})
+
+	result := RPCResult{
+		Method:  "TestIamPermissions",
+		Code:    status.Code(err).String(),
+		Serving: servedBy(err),
+		IAM:     IAMUnknown,
+	}
+	if err != nil {
+		result.Detail = status.Convert(err).Message()
+	}
+
+	return result
+}
+
+// ProbeSecretManager calls ListSecrets as the probe principal
+func ProbeSecretManager(ctx context.Context, conn *grpc.ClientConn) RPCResult {
+	_, err := secretmanagerpb.NewSecretManagerServiceClient(conn).ListSecrets(probeContext(ctx), &secretmanagerpb.ListSecretsRequest{
+		Parent:   ProbeProject,
+		PageSize: 1,
+	})
+
+	return interpretDataPlane("ListSecrets", err)
+}
+
+// ProbeKMS calls ListKeyRings as the probe principal
+func ProbeKMS(ctx context.Context, conn *grpc.ClientConn) RPCResult {
+	_, err := kmspb.NewKeyManagementServiceClient(conn).ListKeyRings(probeContext(ctx), &kmspb.ListKeyRingsRequest{
+		Parent:   ProbeProject + "/locations/global",
+		PageSize: 1,
+	})
+
+	return interpretDataPlane("ListKeyRings", err)
+}
+
+// interpretDataPlane maps a data plane probe error onto the integration
+// contract: a permission denial for the probe principal means IAM made a
+// decision; "IAM check failed" means the data plane could not reach IAM.
+func interpretDataPlane(method string, err error) RPCResult {
+	result := RPCResult{
+		Method:  method,
+		Code:    status.Code(err).String(),
+		Serving: servedBy(err),
+		IAM:     IAMUnknown,
+	}
+
+	msg := status.Convert(err).Message()
+
+	switch status.Code(err) {
+	case codes.OK:
+		result.Detail = "probe allowed without a denial (IAM mode off, or permissive mode failing open)"
+	case codes.PermissionDenied:
+		if strings.Contains(msg, "no principal") {
+			result.Detail = msg
+		} else {
+			result.IAM = IAMReachable
+		}
+	case codes.Internal, codes.Unavailable:
+		if strings.Contains(msg, "IAM") {
+			result.Serving = true
+			result.IAM = IAMUnreachable
+		}
+		result.Detail = msg
+	default:
+		result.Detail = msg
+	}
+
+	return result
+}
+
+// servedBy reports whether err came from the service rather than the transport
+func servedBy(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Canceled, codes.Unimplemented:
+		return false
+	}
+	return true
+}
+
+func probeContext(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx,
+		authz.PrincipalMetadataKey, ProbePrincipal,
+		authz.CallerMetadataKey, authz.StatusCaller,
+	)
+}
@@ -0,0 +1,38 @@
+package health
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestInterpretDataPlane(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		serving bool
+		iam     string
+	}{
+		{"allowed", nil, true, IAMUnknown},
+		{"denied by IAM", status.Error(codes.PermissionDenied, "Permission 'secretmanager.secrets.list' denied"), true, IAMReachable},
+		{"no principal", status.Error(codes.PermissionDenied, "Permission denied: no principal provided"), true, IAMUnknown},
+		{"IAM unreachable", status.Error(codes.Internal, "IAM check failed: connection refused"), true, IAMUnreachable},
+		{"transport down", status.Error(codes.Unavailable, "connection refused"), false, IAMUnknown},
+		{"not a data plane", status.Error(codes.Unimplemented, "unknown service"), false, IAMUnknown},
+		{"non-status error", errors.New("boom"), true, IAMUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := interpretDataPlane("ListSecrets", tt.err)
+			if got.Serving != tt.serving {
+				t.Errorf("Serving = %v, want %v", got.Serving, tt.serving)
+			}
+			if got.IAM != tt.iam {
+				t.Errorf("IAM = %q, want %q", got.IAM, tt.iam)
+			}
+		})
+	}
+}
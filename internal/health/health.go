@@ -0,0 +1,107 @@
+// Package health provides readiness probes for emulator services: an HTTP
+// GET against the /health endpoint and a gRPC connection readiness check.
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Target describes how to probe a single service
+type Target struct {
+	Name string
+	// HealthURL is polled with HTTP GET; empty skips the HTTP probe
+	HealthURL string
+	// GRPCAddr is dialed until the connection is ready; empty skips the gRPC probe
+	GRPCAddr string
+}
+
+// CheckHTTP performs a single GET and returns an error unless it returns 200
+func CheckHTTP(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: 2 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d", url, resp.StatusCode)
+	}
+
+	return nil
+}
+
+// WaitGRPC blocks until conn is ready or ctx is done
+func WaitGRPC(ctx context.Context, conn *grpc.ClientConn) error {
+	conn.Connect()
+
+	for {
+		state := conn.GetState()
+		if state == connectivity.Ready {
+			return nil
+		}
+		if !conn.WaitForStateChange(ctx, state) {
+			return fmt.Errorf("gRPC connection not ready (last state %s): %w", state, ctx.Err())
+		}
+	}
+}
+
+// Wait polls the target every interval until all configured probes pass or
+// ctx is done. The returned error describes the last probe failure.
+func Wait(ctx context.Context, target Target, interval time.Duration) error {
+	var conn *grpc.ClientConn
+	if target.GRPCAddr != "" {
+		var err error
+		conn, err = grpc.NewClient(target.GRPCAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			return fmt.Errorf("%s: %w", target.Name, err)
+		}
+		defer conn.Close()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := probe(ctx, target, conn, interval)
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s not ready: %w", target.Name, err)
+		case <-ticker.C:
+		}
+	}
+}
+
+func probe(ctx context.Context, target Target, conn *grpc.ClientConn, timeout time.Duration) error {
+	if target.HealthURL != "" {
+		if err := CheckHTTP(ctx, target.HealthURL); err != nil {
+			return err
+		}
+	}
+
+	if conn != nil {
+		probeCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		if err := WaitGRPC(probeCtx, conn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package logs
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+// Filter selects records. Zero-value fields match everything.
+type Filter struct {
+	// MinLevel drops records below this level
+	MinLevel Level
+	// Principal matches records whose principal contains it
+	Principal string
+	// Permission matches exactly, or as a glob when it contains '*'
+	// (e.g. secretmanager.*)
+	Permission string
+	// Decision is ALLOW or DENY; it also drops non-authorization records
+	Decision string
+	// Grep matches against the raw line
+	Grep *regexp.Regexp
+}
+
+// Match reports whether r passes the filter
+func (f Filter) Match(r Record) bool {
+	if r.Level < f.MinLevel {
+		return false
+	}
+
+	if f.Principal != "" && !strings.Contains(r.Principal, f.Principal) {
+		return false
+	}
+
+	if f.Permission != "" && !policy.MatchPermission(f.Permission, r.Permission) {
+		return false
+	}
+
+	if f.Decision != "" && r.Decision != normalizeDecision(f.Decision) {
+		return false
+	}
+
+	if f.Grep != nil && !f.Grep.MatchString(r.Raw) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,45 @@
+package logs
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestFilterMatch(t *testing.T) {
+	deny := Record{
+		Level:      LevelInfo,
+		Principal:  "user:alice@example.com",
+		Permission: "secretmanager.versions.access",
+		Decision:   DecisionDeny,
+		Raw:        "denied alice",
+	}
+	warn := Record{Level: LevelWarn, Message: "slow", Raw: "slow"}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		record Record
+		want   bool
+	}{
+		{"empty filter", Filter{}, deny, true},
+		{"level below minimum", Filter{MinLevel: LevelWarn}, deny, false},
+		{"level at minimum", Filter{MinLevel: LevelWarn}, warn, true},
+		{"principal substring", Filter{Principal: "alice@"}, deny, true},
+		{"principal mismatch", Filter{Principal: "bob"}, deny, false},
+		{"permission exact", Filter{Permission: "secretmanager.versions.access"}, deny, true},
+		{"permission glob", Filter{Permission: "secretmanager.*"}, deny, true},
+		{"permission glob mismatch", Filter{Permission: "cloudkms.*"}, deny, false},
+		{"decision lowercase", Filter{Decision: "deny"}, deny, true},
+		{"decision drops non-authz", Filter{Decision: "deny"}, warn, false},
+		{"grep", Filter{Grep: regexp.MustCompile("ali")}, deny, true},
+		{"grep mismatch", Filter{Grep: regexp.MustCompile("^slow$")}, deny, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(tt.record); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package logs
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ParseLine parses one log line from service. The line may start with a
+// docker timestamp (docker compose logs --timestamps). The body may be a
+// JSON object (trace events or slog JSON), logfmt (slog text) or plain
+// text; a "service" attribute in the body overrides service.
+func ParseLine(service, line string) Record {
+	r := Record{
+		Service: service,
+		Level:   LevelInfo,
+		Raw:     line,
+	}
+
+	body := strings.TrimRight(line, "\r\n")
+	if ts, rest, ok := cutTimestamp(body); ok {
+		r.Time = ts
+		body = rest
+	}
+
+	trimmed := strings.TrimSpace(body)
+	switch {
+	case strings.HasPrefix(trimmed, "{"):
+		var obj map[string]any
+		if err := json.Unmarshal([]byte(trimmed), &obj); err == nil {
+			r.applyJSON(obj)
+			return r
+		}
+	case isLogfmt(trimmed):
+		r.applyFields(parseLogfmt(trimmed))
+		return r
+	}
+
+	r.applyText(trimmed)
+	return r
+}
+
+// cutTimestamp strips a leading RFC 3339 timestamp
+func cutTimestamp(line string) (time.Time, string, bool) {
+	first, rest, ok := strings.Cut(line, " ")
+	if !ok || len(first) < len("2006-01-02T15:04:05Z") || first[4] != '-' {
+		return time.Time{}, line, false
+	}
+
+	ts, err := time.Parse(time.RFC3339Nano, first)
+	if err != nil {
+		return time.Time{}, line, false
+	}
+
+	return ts, rest, true
+}
+
+// applyJSON handles both trace schema v1.0 events, whose fields are nested
+// (actor.principal, decision.outcome, ...), and flat slog JSON
+func (r *Record) applyJSON(obj map[string]any) {
+	fields := make(map[string]string)
+	flatten("", obj, fields)
+
+	aliases := map[string]string{
+		"actor.principal":       "principal",
+		"target.resource":       "resource",
+		"action.permission":     "permission",
+		"decision.outcome":      "decision",
+		"decision.reason":       "reason",
+		"decision.evaluated_by": "evaluated_by",
+		"decision.latency_ms":   "latency_ms",
+		"timestamp":             "time",
+		"event_type":            "msg",
+		"environment.component": "component",
+		"severity":              "level",
+		"message":               "msg",
+	}
+	for from, to := range aliases {
+		if v, ok := fields[from]; ok {
+			if _, exists := fields[to]; !exists {
+				fields[to] = v
+			}
+			delete(fields, from)
+		}
+	}
+
+	r.applyFields(fields)
+}
+
+// applyFields moves well-known attributes into the record and keeps the rest
+func (r *Record) applyFields(fields map[string]string) {
+	take := func(key string) string {
+		v := fields[key]
+		delete(fields, key)
+		return v
+	}
+
+	if v := take("time"); v != "" && r.Time.IsZero() {
+		if ts, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			r.Time = ts
+		}
+	}
+	if level, ok := ParseLevel(take("level")); ok {
+		r.Level = level
+	}
+	if v := take("service"); v != "" {
+		r.Service = v
+	}
+
+	r.Message = take("msg")
+	r.Principal = take("principal")
+	r.Resource = take("resource")
+	r.Permission = take("permission")
+	r.Decision = normalizeDecision(take("decision"))
+	r.Reason = take("reason")
+
+	delete(fields, "schema_version")
+	if len(fields) > 0 {
+		r.Fields = fields
+	}
+}
+
+// applyText handles plain lines such as Go's log package output
+// ("2006/01/02 15:04:05 message") and "[ERROR] message" prefixes
+func (r *Record) applyText(text string) {
+	if len(text) >= 20 && text[4] == '/' && text[7] == '/' {
+		if ts, err := time.ParseInLocation("2006/01/02 15:04:05", text[:19], time.Local); err == nil {
+			if r.Time.IsZero() {
+				r.Time = ts
+			}
+			text = strings.TrimSpace(text[19:])
+		}
+	}
+
+	// A leading "ERROR", "[WARN]" or "info:" is taken as the level
+	first, rest, _ := strings.Cut(text, " ")
+	word := strings.Trim(first, "[]:")
+	if word != "" && (word != first || word == strings.ToUpper(word)) {
+		if level, ok := ParseLevel(word); ok {
+			r.Level = level
+			text = strings.TrimSpace(rest)
+		}
+	}
+
+	r.Message = text
+}
+
+func normalizeDecision(d string) string {
+	switch strings.ToUpper(d) {
+	case "ALLOW", "ALLOWED", "GRANTED":
+		return DecisionAllow
+	case "DENY", "DENIED":
+		return DecisionDeny
+	}
+	return strings.ToUpper(d)
+}
+
+// flatten converts nested JSON into dotted keys
+func flatten(prefix string, v any, out map[string]string) {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, child := range val {
+			key := k
+			if prefix != "" {
+				key = prefix + "." + k
+			}
+			flatten(key, child, out)
+		}
+	case string:
+		out[prefix] = val
+	case nil:
+		out[prefix] = ""
+	case float64:
+		out[prefix] = strconv.FormatFloat(val, 'f', -1, 64)
+	default:
+		data, _ := json.Marshal(val)
+		out[prefix] = string(data)
+	}
+}
+
+// isLogfmt reports whether a line looks like key=value pairs
+func isLogfmt(line string) bool {
+	return strings.HasPrefix(line, "time=") || strings.HasPrefix(line, "level=") || strings.Contains(line, " msg=")
+}
+
+// parseLogfmt parses key=value pairs; values may be double-quoted
+func parseLogfmt(line string) map[string]string {
+	fields := make(map[string]string)
+
+	for i := 0; i < len(line); {
+		for i < len(line) && line[i] == ' ' {
+			i++
+		}
+
+		start := i
+		for i < len(line) && line[i] != '=' && line[i] != ' ' {
+			i++
+		}
+		key := line[start:i]
+		if i >= len(line) || line[i] != '=' {
+			if key != "" {
+				fields[key] = ""
+			}
+			continue
+		}
+		i++ // skip '='
+
+		var value string
+		if i < len(line) && line[i] == '"' {
+			var b strings.Builder
+			i++
+			for i < len(line) && line[i] != '"' {
+				if line[i] == '\\' && i+1 < len(line) {
+					i++
+				}
+				b.WriteByte(line[i])
+				i++
+			}
+			i++ // skip closing quote
+			value = b.String()
+		} else {
+			start = i
+			for i < len(line) && !unicode.IsSpace(rune(line[i])) {
+				i++
+			}
+			value = line[start:i]
+		}
+
+		if key != "" {
+			fields[key] = value
+		}
+	}
+
+	return fields
+}
@@ -0,0 +1,115 @@
+package logs
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    Record
+		hasTime bool
+	}{
+		{
+			name: "trace event",
+			line: `{"schema_version":"1.0","event_type":"authz_check","timestamp":"2026-01-28T10:15:23.483Z","actor":{"principal":"user:alice@example.com"},"target":{"resource":"projects/test/secrets/db-password"},"action":{"permission":"secretmanager.secrets.get"},"decision":{"outcome":"DENY","reason":"no_binding","evaluated_by":"gcp-iam-emulator","latency_ms":3}}`,
+			want: Record{
+				Service:    "iam",
+				Level:      LevelInfo,
+				Message:    "authz_check",
+				Principal:  "user:alice@example.com",
+				Resource:   "projects/test/secrets/db-password",
+				Permission: "secretmanager.secrets.get",
+				Decision:   DecisionDeny,
+				Reason:     "no_binding",
+			},
+			hasTime: true,
+		},
+		{
+			name: "slog text",
+			line: `time=2026-01-28T10:15:23.483Z level=INFO msg=authz_check service=kms principal=user:bob@example.com resource=projects/p permission=cloudkms.cryptoKeys.encrypt decision=ALLOW reason="granted by roles/owner"`,
+			want: Record{
+				Service:    "kms",
+				Level:      LevelInfo,
+				Message:    "authz_check",
+				Principal:  "user:bob@example.com",
+				Resource:   "projects/p",
+				Permission: "cloudkms.cryptoKeys.encrypt",
+				Decision:   DecisionAllow,
+				Reason:     "granted by roles/owner",
+			},
+			hasTime: true,
+		},
+		{
+			name:    "slog json",
+			line:    `{"time":"2026-01-28T10:15:23.483Z","level":"WARN","msg":"slow check"}`,
+			want:    Record{Service: "iam", Level: LevelWarn, Message: "slow check"},
+			hasTime: true,
+		},
+		{
+			name:    "docker timestamp and plain text",
+			line:    "2026-01-28T10:15:23.483456789Z [ERROR] failed to load policy",
+			want:    Record{Service: "iam", Level: LevelError, Message: "failed to load policy"},
+			hasTime: true,
+		},
+		{
+			name:    "go log prefix",
+			line:    "2026/01/28 10:15:23 Listening on :8080",
+			want:    Record{Service: "iam", Level: LevelInfo, Message: "Listening on :8080"},
+			hasTime: true,
+		},
+		{
+			name: "plain text",
+			line: "Starting server",
+			want: Record{Service: "iam", Level: LevelInfo, Message: "Starting server"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseLine("iam", tt.line)
+
+			if got.Time.IsZero() == tt.hasTime {
+				t.Errorf("Time = %v, want set = %v", got.Time, tt.hasTime)
+			}
+			if got.Raw != tt.line {
+				t.Errorf("Raw = %q, want original line", got.Raw)
+			}
+
+			got.Time, got.Raw, got.Fields = time.Time{}, "", nil
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseLine() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLineKeepsExtraFields(t *testing.T) {
+	r := ParseLine("iam", `time=2026-01-28T10:15:23Z level=INFO msg="stack started" iam_mode=strict`)
+
+	if r.Fields["iam_mode"] != "strict" {
+		t.Errorf("Fields = %v, want iam_mode=strict", r.Fields)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	at := func(sec int) time.Time { return time.Date(2026, 1, 1, 0, 0, sec, 0, time.UTC) }
+
+	iam := []Record{{Time: at(1), Message: "a"}, {Time: at(4), Message: "c"}, {Message: "c-continued"}}
+	kms := []Record{{Time: at(2), Message: "b"}, {Time: at(5), Message: "d"}}
+
+	merged := Merge(iam, kms)
+
+	want := []string{"a", "b", "c", "c-continued", "d"}
+	if len(merged) != len(want) {
+		t.Fatalf("got %d records, want %d", len(merged), len(want))
+	}
+	for i, msg := range want {
+		if merged[i].Message != msg {
+			t.Errorf("merged[%d] = %q, want %q", i, merged[i].Message, msg)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+// Package logs turns emulator log output into structured records.
+//
+// Each emulator logs in its own format: the IAM emulator and enforcement
+// proxy emit authz_check trace events (schema v1.0), native mode emits slog
+// text, and other lines are plain text. ParseLine normalizes all of them into
+// a Record so logs from several services can be interleaved by timestamp
+// and filtered by level, principal, permission or decision.
+package logs
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Level is a log severity
+type Level int
+
+const (
+	LevelDebug Level = iota - 1
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// String returns the level name used in output
+func (l Level) String() string {
+	switch {
+	case l <= LevelDebug:
+		return "DEBUG"
+	case l == LevelInfo:
+		return "INFO"
+	case l == LevelWarn:
+		return "WARN"
+	default:
+		return "ERROR"
+	}
+}
+
+// MarshalJSON encodes the level as its name
+func (l Level) MarshalJSON() ([]byte, error) {
+	return json.Marshal(l.String())
+}
+
+// ParseLevel parses a level name (debug, info, warn/warning, error/fatal)
+func ParseLevel(s string) (Level, bool) {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
+	case "DEBUG", "TRACE":
+		return LevelDebug, true
+	case "INFO", "":
+		return LevelInfo, true
+	case "WARN", "WARNING":
+		return LevelWarn, true
+	case "ERROR", "ERR", "FATAL", "PANIC":
+		return LevelError, true
+	}
+	return LevelInfo, false
+}
+
+// Decision outcomes as they appear in authz_check events
+const (
+	DecisionAllow = "ALLOW"
+	DecisionDeny  = "DENY"
+)
+
+// Record is one parsed log line
+type Record struct {
+	Time    time.Time `json:"time"`
+	Service string    `json:"service"`
+	Level   Level     `json:"level"`
+	Message string    `json:"message"`
+
+	// Authorization fields, set for authz_check events
+	Principal  string `json:"principal,omitempty"`
+	Resource   string `json:"resource,omitempty"`
+	Permission string `json:"permission,omitempty"`
+	Decision   string `json:"decision,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+
+	// Fields holds any other key/value attributes
+	Fields map[string]string `json:"fields,omitempty"`
+	// Raw is the original line
+	Raw string `json:"raw"`
+}
+
+// IsAuthz reports whether the record is an authorization decision
+func (r Record) IsAuthz() bool {
+	return r.Decision != ""
+}
+
+// Merge interleaves the records of several services by timestamp.
+// A record without a timestamp (e.g. a continuation line) takes the time
+// of the record before it, so it stays next to it after sorting.
+func Merge(streams ...[]Record) []Record {
+	var merged []Record
+	for _, stream := range streams {
+		var last time.Time
+		for _, r := range stream {
+			if r.Time.IsZero() {
+				r.Time = last
+			}
+			last = r.Time
+			merged = append(merged, r)
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Time.Before(merged[j].Time)
+	})
+
+	return merged
+}
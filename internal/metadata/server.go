@@ -0,0 +1,318 @@
+// Package metadata emulates the GCE metadata server for local stacks.
+//
+// Google client libraries discover credentials through Application Default
+// Credentials: when GCE_METADATA_HOST is set they fetch the project ID and
+// service account tokens from that host instead of 169.254.169.254. This
+// server answers those requests with emulator tokens (see package token)
+// for a chosen service account, so an unmodified application authenticates
+// as that principal to 'gcp-emulator proxy'.
+//
+// Besides the /computeMetadata/v1 endpoints, /token issues access tokens
+// for any service account the server knows about, for test harnesses that
+// need to act as several identities.
+package metadata
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/token"
+)
+
+// FlavorHeader must be set to "Google" on every metadata request, and is
+// set on every response
+const FlavorHeader = "Metadata-Flavor"
+
+// Zone is the zone reported for the emulated instance
+const Zone = "us-central1-a"
+
+// DefaultScopes are reported for every service account
+var DefaultScopes = []string{"https://www.googleapis.com/auth/cloud-platform"}
+
+// Options configures a metadata server
+type Options struct {
+	// ServiceAccount is the email of the instance's default service account
+	ServiceAccount string
+	// ServiceAccounts are the emails /token and the service-accounts
+	// endpoints will issue tokens for. ServiceAccount is always included.
+	ServiceAccounts []string
+	// ProjectID defaults to the project of ServiceAccount's email
+	ProjectID string
+	// TokenTTL is the lifetime of issued tokens (default one hour)
+	TokenTTL time.Duration
+	// Now is the clock tokens are issued against (default time.Now)
+	Now func() time.Time
+	// OnToken, if set, is called for every token request
+	OnToken func(TokenRequest)
+}
+
+// TokenRequest describes one request for an access or ID token
+type TokenRequest struct {
+	// Path is the request path, e.g. /token
+	Path string
+	// ServiceAccount is the email the token was requested for
+	ServiceAccount string
+	// Kind is "access" or "identity"
+	Kind string
+	// Err is set if no token was issued
+	Err error
+}
+
+// Server is an emulated metadata server
+type Server struct {
+	opts     Options
+	accounts []string
+	mux      *http.ServeMux
+}
+
+// New returns a metadata server for opts
+func New(opts Options) (*Server, error) {
+	if opts.ServiceAccount == "" {
+		return nil, errors.New("a default service account is required")
+	}
+	if !strings.Contains(opts.ServiceAccount, "@") {
+		return nil, fmt.Errorf("invalid service account email: %s", opts.ServiceAccount)
+	}
+	if opts.ProjectID == "" {
+		opts.ProjectID = policy.ProjectFromEmail(opts.ServiceAccount)
+	}
+	if opts.ProjectID == "" {
+		return nil, fmt.Errorf("cannot infer a project ID from %s; set one explicitly", opts.ServiceAccount)
+	}
+	if opts.TokenTTL <= 0 {
+		opts.TokenTTL = time.Hour
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+
+	s := &Server{opts: opts, accounts: []string{opts.ServiceAccount}}
+	for _, email := range opts.ServiceAccounts {
+		if !slices.Contains(s.accounts, email) {
+			s.accounts = append(s.accounts, email)
+		}
+	}
+
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("/token", s.handleToken)
+	s.mux.HandleFunc("/computeMetadata/v1/", s.handleMetadata)
+	s.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set(FlavorHeader, "Google")
+		fmt.Fprintln(w, "computeMetadata/")
+	})
+
+	return s, nil
+}
+
+// ServiceAccounts returns the emails the server issues tokens for, the
+// default service account first
+func (s *Server) ServiceAccounts() []string {
+	return slices.Clone(s.accounts)
+}
+
+// ProjectID returns the project ID the server reports
+func (s *Server) ProjectID() string {
+	return s.opts.ProjectID
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// tokenResponse is the OAuth2 token response returned by /token and the
+// service account token endpoint
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+	TokenType   string `json:"token_type"`
+}
+
+// handleToken issues an access token for ?service_account=<email> (the
+// default service account if omitted). Unlike the metadata endpoints it
+// does not require the Metadata-Flavor header.
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	email := strings.TrimPrefix(r.Form.Get("service_account"), "serviceAccount:")
+	if email == "" {
+		email = s.opts.ServiceAccount
+	}
+	s.writeAccessToken(w, r, email)
+}
+
+func (s *Server) handleMetadata(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(FlavorHeader, "Google")
+
+	if r.Header.Get(FlavorHeader) != "Google" {
+		http.Error(w, "Missing Metadata-Flavor:Google header.", http.StatusForbidden)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/computeMetadata/v1/")
+	switch path {
+	case "project/project-id":
+		writeText(w, s.opts.ProjectID)
+	case "project/numeric-project-id":
+		writeText(w, numericID(s.opts.ProjectID))
+	case "instance/id":
+		writeText(w, numericID(s.opts.ProjectID+"/instance"))
+	case "instance/name":
+		writeText(w, "gcp-emulator")
+	case "instance/hostname":
+		writeText(w, "gcp-emulator."+Zone+".c."+s.opts.ProjectID+".internal")
+	case "instance/zone":
+		writeText(w, "projects/"+numericID(s.opts.ProjectID)+"/zones/"+Zone)
+	case "universe/universe-domain":
+		writeText(w, "googleapis.com")
+	case "instance/service-accounts", "instance/service-accounts/":
+		var b strings.Builder
+		b.WriteString("default/\n")
+		for _, email := range s.accounts {
+			b.WriteString(email + "/\n")
+		}
+		writeText(w, b.String())
+	default:
+		rest, ok := strings.CutPrefix(path, "instance/service-accounts/")
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		s.handleServiceAccount(w, r, rest)
+	}
+}
+
+// handleServiceAccount serves instance/service-accounts/<account>/<attr>,
+// where account is "default" or an email
+func (s *Server) handleServiceAccount(w http.ResponseWriter, r *http.Request, rest string) {
+	account, attr, _ := strings.Cut(rest, "/")
+
+	email := account
+	if account == "default" {
+		email = s.opts.ServiceAccount
+	}
+	if !slices.Contains(s.accounts, email) {
+		http.NotFound(w, r)
+		return
+	}
+
+	aliases := []string{}
+	if email == s.opts.ServiceAccount {
+		aliases = append(aliases, "default")
+	}
+
+	switch attr {
+	case "":
+		if r.URL.Query().Get("recursive") == "true" {
+			writeJSON(w, map[string]any{"aliases": aliases, "email": email, "scopes": DefaultScopes})
+			return
+		}
+		writeText(w, "aliases\nemail\nidentity\nscopes\ntoken\n")
+	case "email":
+		writeText(w, email)
+	case "aliases":
+		writeText(w, strings.Join(aliases, "\n"))
+	case "scopes":
+		writeText(w, strings.Join(DefaultScopes, "\n")+"\n")
+	case "token":
+		s.writeAccessToken(w, r, email)
+	case "identity":
+		s.writeIDToken(w, r, email, r.URL.Query().Get("audience"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) writeAccessToken(w http.ResponseWriter, r *http.Request, email string) {
+	report := TokenRequest{Path: r.URL.Path, ServiceAccount: email, Kind: "access"}
+	defer func() {
+		if s.opts.OnToken != nil {
+			s.opts.OnToken(report)
+		}
+	}()
+
+	if !slices.Contains(s.accounts, email) {
+		report.Err = fmt.Errorf("unknown service account %s", email)
+		http.Error(w, report.Err.Error(), http.StatusNotFound)
+		return
+	}
+
+	tok, err := token.Mint("serviceAccount:"+email, s.opts.TokenTTL, s.opts.Now())
+	if err != nil {
+		report.Err = err
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, tokenResponse{
+		AccessToken: tok,
+		ExpiresIn:   int(s.opts.TokenTTL.Seconds()),
+		TokenType:   "Bearer",
+	})
+}
+
+func (s *Server) writeIDToken(w http.ResponseWriter, r *http.Request, email, audience string) {
+	report := TokenRequest{Path: r.URL.Path, ServiceAccount: email, Kind: "identity"}
+	defer func() {
+		if s.opts.OnToken != nil {
+			s.opts.OnToken(report)
+		}
+	}()
+
+	if audience == "" {
+		report.Err = errors.New("audience parameter is required")
+		http.Error(w, "non-empty audience parameter required", http.StatusBadRequest)
+		return
+	}
+
+	tok, err := token.IDToken(email, audience, s.opts.TokenTTL, s.opts.Now())
+	if err != nil {
+		report.Err = err
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeText(w, tok)
+}
+
+// numericID derives a stable 12-digit number from s, standing in for
+// the numeric IDs GCP assigns to projects and instances
+func numericID(s string) string {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return fmt.Sprintf("%012d", h.Sum64()%1_000_000_000_000)
+}
+
+func writeText(w http.ResponseWriter, body string) {
+	w.Header().Set("Content-Type", "application/text")
+	fmt.Fprint(w, body)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
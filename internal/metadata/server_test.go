@@ -0,0 +1,160 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gcemetadata "cloud.google.com/go/compute/metadata"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/token"
+)
+
+const (
+	ci     = "ci@test-project.iam.gserviceaccount.com"
+	deploy = "deploy@test-project.iam.gserviceaccount.com"
+)
+
+func startServer(t *testing.T, opts Options) (*httptest.Server, *[]TokenRequest) {
+	t.Helper()
+
+	var requests []TokenRequest
+	opts.OnToken = func(r TokenRequest) { requests = append(requests, r) }
+
+	s, err := New(opts)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func TestClientLibrary(t *testing.T) {
+	srv, requests := startServer(t, Options{ServiceAccount: ci, ServiceAccounts: []string{deploy}})
+	t.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(srv.URL, "http://"))
+
+	ctx := context.Background()
+	client := gcemetadata.NewClient(http.DefaultClient)
+
+	if !gcemetadata.OnGCEWithContext(ctx) {
+		t.Error("Expected OnGCE with GCE_METADATA_HOST set")
+	}
+	if project, err := client.ProjectIDWithContext(ctx); err != nil || project != "test-project" {
+		t.Errorf("ProjectID() = %q, %v", project, err)
+	}
+	if email, err := client.EmailWithContext(ctx, ""); err != nil || email != ci {
+		t.Errorf("Email() = %q, %v", email, err)
+	}
+
+	body, err := client.GetWithContext(ctx, "instance/service-accounts/default/token")
+	if err != nil {
+		t.Fatalf("Get(token) error = %v", err)
+	}
+	var resp tokenResponse
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatalf("Invalid token response %q: %v", body, err)
+	}
+	if resp.TokenType != "Bearer" || resp.ExpiresIn != 3600 {
+		t.Errorf("Token response = %+v", resp)
+	}
+	claims, err := token.Parse(resp.AccessToken, time.Now())
+	if err != nil || claims.Principal != "serviceAccount:"+ci {
+		t.Errorf("Parse(access token) = %+v, %v", claims, err)
+	}
+
+	idToken, err := client.GetWithContext(ctx, "instance/service-accounts/"+deploy+"/identity?audience=https://example.com")
+	if err != nil {
+		t.Fatalf("Get(identity) error = %v", err)
+	}
+	claims, err = token.Parse(idToken, time.Now())
+	if err != nil || claims.Principal != "serviceAccount:"+deploy {
+		t.Errorf("Parse(ID token) = %+v, %v", claims, err)
+	}
+
+	if _, err := client.GetWithContext(ctx, "instance/service-accounts/mallory@test-project.iam.gserviceaccount.com/token"); err == nil {
+		t.Error("Expected an error for an unknown service account")
+	}
+
+	if len(*requests) != 2 || (*requests)[0].Kind != "access" || (*requests)[1].Kind != "identity" {
+		t.Errorf("OnToken saw %+v", *requests)
+	}
+}
+
+func TestMetadataFlavorRequired(t *testing.T) {
+	srv, _ := startServer(t, Options{ServiceAccount: ci})
+
+	resp, err := http.Get(srv.URL + "/computeMetadata/v1/project/project-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 without Metadata-Flavor, got %d", resp.StatusCode)
+	}
+}
+
+func TestTokenEndpoint(t *testing.T) {
+	srv, requests := startServer(t, Options{ServiceAccount: ci, ServiceAccounts: []string{deploy}, TokenTTL: 10 * time.Minute})
+
+	tests := []struct {
+		query      string
+		wantStatus int
+		want       string
+	}{
+		{"", http.StatusOK, "serviceAccount:" + ci},
+		{"?service_account=" + deploy, http.StatusOK, "serviceAccount:" + deploy},
+		{"?service_account=serviceAccount:" + deploy, http.StatusOK, "serviceAccount:" + deploy},
+		{"?service_account=mallory@test-project.iam.gserviceaccount.com", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			resp, err := http.Get(srv.URL + "/token" + tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if tt.want == "" {
+				return
+			}
+
+			var body tokenResponse
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if body.ExpiresIn != 600 {
+				t.Errorf("expires_in = %d, want 600", body.ExpiresIn)
+			}
+			claims, err := token.Parse(body.AccessToken, time.Now())
+			if err != nil || claims.Principal != tt.want {
+				t.Errorf("Parse() = %+v, %v, want %s", claims, err, tt.want)
+			}
+		})
+	}
+
+	if last := (*requests)[len(*requests)-1]; last.Err == nil {
+		t.Errorf("Expected the unknown service account request to report an error, got %+v", last)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(Options{}); err == nil {
+		t.Error("Expected error without a service account")
+	}
+	if _, err := New(Options{ServiceAccount: "123-compute@developer.gserviceaccount.com"}); err == nil {
+		t.Error("Expected error when the project cannot be inferred")
+	}
+
+	s, err := New(Options{ServiceAccount: "123-compute@developer.gserviceaccount.com", ProjectID: "p"})
+	if err != nil || s.ProjectID() != "p" {
+		t.Errorf("New() with explicit project = %v", err)
+	}
+}
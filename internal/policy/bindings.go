@@ -0,0 +1,87 @@
+package policy
+
+import (
+	"fmt"
+	"sort"
+)
+
+// BindingRef locates a binding within a policy
+type BindingRef struct {
+	// Project is "" for folder and organization bindings
+	Project string
+	// Resource is the resource below the project that the binding is
+	// attached to, the folder or organization it is attached to, or "" for
+	// a project binding
+	Resource string
+	Index    int
+	Binding  Binding
+}
+
+// Label identifies the binding as project[index] or resource[index]
+func (r BindingRef) Label() string {
+	return BindingLabel(r.Project, r.Resource, r.Index)
+}
+
+// Ref returns the location of the evaluated binding
+func (b BindingResult) Ref() BindingRef {
+	return BindingRef{Project: b.Project, Resource: b.Resource, Index: b.Index, Binding: b.Binding}
+}
+
+// BindingLabel identifies a binding as project[index], or resource[index]
+// for a binding attached to a resource below the project, a folder or an
+// organization
+func BindingLabel(project, resource string, index int) string {
+	if resource != "" {
+		return fmt.Sprintf("%s[%d]", resource, index)
+	}
+	return fmt.Sprintf("%s[%d]", project, index)
+}
+
+// AllBindings returns every binding in the policy: organization bindings,
+// then folder bindings, then for each project its own bindings followed by
+// those on its service accounts and those on its resources. Each level is
+// in name order. Resources whose project is not in the policy are skipped.
+func AllBindings(policy *Policy) []BindingRef {
+	var refs []BindingRef
+	for _, id := range sortedNames(policy.Organizations) {
+		for i, binding := range policy.Organizations[id].Bindings {
+			refs = append(refs, BindingRef{Resource: "organizations/" + id, Index: i, Binding: binding})
+		}
+	}
+	for _, id := range sortedNames(policy.Folders) {
+		for i, binding := range policy.Folders[id].Bindings {
+			refs = append(refs, BindingRef{Resource: "folders/" + id, Index: i, Binding: binding})
+		}
+	}
+
+	for _, name := range sortedNames(policy.Projects) {
+		project := policy.Projects[name]
+		for i, binding := range project.Bindings {
+			refs = append(refs, BindingRef{Project: name, Index: i, Binding: binding})
+		}
+
+		for _, email := range sortedNames(project.ServiceAccounts) {
+			resource := "projects/" + name + "/serviceAccounts/" + email
+			for i, binding := range project.ServiceAccounts[email].Bindings {
+				refs = append(refs, BindingRef{Project: name, Resource: resource, Index: i, Binding: binding})
+			}
+		}
+
+		for _, resource := range resourcesWithin(policy, name) {
+			for i, binding := range policy.Resources[resource].Bindings {
+				refs = append(refs, BindingRef{Project: name, Resource: resource, Index: i, Binding: binding})
+			}
+		}
+	}
+
+	return refs
+}
+
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,79 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BoundaryResult records the access boundaries a request's principal is
+// subject to
+type BoundaryResult struct {
+	// Boundaries names every boundary whose principals match, sorted
+	Boundaries []string
+	// Within reports whether the permission is allowed by at least one of
+	// them
+	Within bool
+}
+
+// evaluateBoundary returns the boundaries that cap principal and whether
+// permission is within them, or nil if the principal is not bounded
+func evaluateBoundary(policy *Policy, principal, permission string) *BoundaryResult {
+	var result *BoundaryResult
+	for _, name := range sortedNames(policy.Boundaries) {
+		boundary := policy.Boundaries[name]
+		if !IsMember(policy, boundary.Principals, principal) {
+			continue
+		}
+
+		if result == nil {
+			result = &BoundaryResult{}
+		}
+		result.Boundaries = append(result.Boundaries, name)
+		if containsString(BoundaryPermissions(policy, boundary), permission) {
+			result.Within = true
+		}
+	}
+	return result
+}
+
+// BoundaryPermissions returns the permissions a boundary allows: its own
+// plus those of its roles
+func BoundaryPermissions(policy *Policy, boundary AccessBoundary) []string {
+	perms := append([]string{}, boundary.Permissions...)
+	for _, role := range boundary.Roles {
+		rolePerms, _ := RolePermissions(policy, role)
+		perms = append(perms, rolePerms...)
+	}
+	return perms
+}
+
+// validateBoundary checks one access boundary, prefixing errors with its
+// name
+func validateBoundary(result *ValidationResult, policy *Policy, name string, boundary AccessBoundary) {
+	scope := "Boundary " + name
+
+	if len(boundary.Principals) == 0 {
+		result.addError(fmt.Sprintf("%s: no principals specified", scope))
+	}
+	for _, principal := range boundary.Principals {
+		if err := validatePrincipal(principal, policy); err != nil {
+			result.addError(fmt.Sprintf("%s: %v", scope, err))
+		}
+	}
+
+	if len(boundary.Permissions) == 0 && len(boundary.Roles) == 0 {
+		result.addWarning(fmt.Sprintf("%s allows no permissions", scope))
+	}
+	for _, perm := range boundary.Permissions {
+		if err := validatePermission(perm); err != nil {
+			result.addError(fmt.Sprintf("%s: %v", scope, err))
+		}
+	}
+	for _, role := range boundary.Roles {
+		if !strings.HasPrefix(role, "roles/") {
+			result.addError(fmt.Sprintf("%s: role must start with 'roles/': %s", scope, role))
+		} else if _, ok := RolePermissions(policy, role); !ok {
+			result.addError(fmt.Sprintf("%s: undefined role %s", scope, role))
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package policy
+
+// builtinRoles mirrors the curated role set shipped with the IAM emulator:
+// primitive roles plus the predefined Secret Manager, KMS and service
+// account roles.
+// Roles defined in the policy file take precedence over these.
+var builtinRoles = map[string][]string{
+	"roles/owner": {
+		"secretmanager.secrets.create",
+		"secretmanager.secrets.delete",
+		"secretmanager.secrets.get",
+		"secretmanager.secrets.list",
+		"secretmanager.secrets.update",
+		"secretmanager.versions.access",
+		"secretmanager.versions.add",
+		"secretmanager.versions.destroy",
+		"secretmanager.versions.disable",
+		"secretmanager.versions.enable",
+		"secretmanager.versions.get",
+		"secretmanager.versions.list",
+		"cloudkms.keyRings.create",
+		"cloudkms.keyRings.get",
+		"cloudkms.keyRings.list",
+		"cloudkms.cryptoKeys.create",
+		"cloudkms.cryptoKeys.get",
+		"cloudkms.cryptoKeys.list",
+		"cloudkms.cryptoKeys.update",
+		"cloudkms.cryptoKeyVersions.create",
+		"cloudkms.cryptoKeyVersions.destroy",
+		"cloudkms.cryptoKeyVersions.get",
+		"cloudkms.cryptoKeyVersions.list",
+		"cloudkms.cryptoKeyVersions.update",
+		"cloudkms.cryptoKeys.encrypt",
+		"cloudkms.cryptoKeys.decrypt",
+		"cloudkms.cryptoKeyVersions.useToDecrypt",
+		"cloudkms.cryptoKeyVersions.useToEncrypt",
+	},
+	"roles/editor": {
+		"secretmanager.secrets.create",
+		"secretmanager.secrets.delete",
+		"secretmanager.secrets.get",
+		"secretmanager.secrets.list",
+		"secretmanager.secrets.update",
+		"secretmanager.versions.add",
+		"secretmanager.versions.destroy",
+		"secretmanager.versions.disable",
+		"secretmanager.versions.enable",
+		"secretmanager.versions.get",
+		"secretmanager.versions.list",
+		"cloudkms.keyRings.create",
+		"cloudkms.keyRings.get",
+		"cloudkms.keyRings.list",
+		"cloudkms.cryptoKeys.create",
+		"cloudkms.cryptoKeys.get",
+		"cloudkms.cryptoKeys.list",
+		"cloudkms.cryptoKeys.update",
+		"cloudkms.cryptoKeyVersions.create",
+		"cloudkms.cryptoKeyVersions.get",
+		"cloudkms.cryptoKeyVersions.list",
+	},
+	"roles/viewer": {
+		"secretmanager.secrets.get",
+		"secretmanager.secrets.list",
+		"secretmanager.versions.get",
+		"secretmanager.versions.list",
+		"cloudkms.keyRings.get",
+		"cloudkms.keyRings.list",
+		"cloudkms.cryptoKeys.get",
+		"cloudkms.cryptoKeys.list",
+		"cloudkms.cryptoKeyVersions.get",
+		"cloudkms.cryptoKeyVersions.list",
+	},
+	"roles/secretmanager.admin": {
+		"secretmanager.secrets.create",
+		"secretmanager.secrets.delete",
+		"secretmanager.secrets.get",
+		"secretmanager.secrets.list",
+		"secretmanager.secrets.update",
+		"secretmanager.versions.access",
+		"secretmanager.versions.add",
+		"secretmanager.versions.destroy",
+		"secretmanager.versions.disable",
+		"secretmanager.versions.enable",
+		"secretmanager.versions.get",
+		"secretmanager.versions.list",
+	},
+	"roles/secretmanager.secretAccessor": {
+		"secretmanager.versions.access",
+	},
+	"roles/secretmanager.secretVersionManager": {
+		"secretmanager.versions.add",
+		"secretmanager.versions.destroy",
+		"secretmanager.versions.disable",
+		"secretmanager.versions.enable",
+		"secretmanager.versions.get",
+		"secretmanager.versions.list",
+	},
+	"roles/secretmanager.viewer": {
+		"secretmanager.secrets.get",
+		"secretmanager.secrets.list",
+		"secretmanager.versions.get",
+		"secretmanager.versions.list",
+	},
+	"roles/cloudkms.admin": {
+		"cloudkms.keyRings.create",
+		"cloudkms.keyRings.get",
+		"cloudkms.keyRings.list",
+		"cloudkms.cryptoKeys.create",
+		"cloudkms.cryptoKeys.get",
+		"cloudkms.cryptoKeys.list",
+		"cloudkms.cryptoKeys.update",
+		"cloudkms.cryptoKeyVersions.create",
+		"cloudkms.cryptoKeyVersions.destroy",
+		"cloudkms.cryptoKeyVersions.get",
+		"cloudkms.cryptoKeyVersions.list",
+		"cloudkms.cryptoKeyVersions.update",
+	},
+	"roles/cloudkms.cryptoKeyEncrypterDecrypter": {
+		"cloudkms.cryptoKeys.encrypt",
+		"cloudkms.cryptoKeys.decrypt",
+		"cloudkms.cryptoKeyVersions.useToDecrypt",
+		"cloudkms.cryptoKeyVersions.useToEncrypt",
+	},
+	"roles/cloudkms.cryptoKeyEncrypter": {
+		"cloudkms.cryptoKeys.encrypt",
+		"cloudkms.cryptoKeyVersions.useToEncrypt",
+	},
+	"roles/cloudkms.cryptoKeyDecrypter": {
+		"cloudkms.cryptoKeys.decrypt",
+		"cloudkms.cryptoKeyVersions.useToDecrypt",
+	},
+	"roles/cloudkms.viewer": {
+		"cloudkms.keyRings.get",
+		"cloudkms.keyRings.list",
+		"cloudkms.cryptoKeys.get",
+		"cloudkms.cryptoKeys.list",
+		"cloudkms.cryptoKeyVersions.get",
+		"cloudkms.cryptoKeyVersions.list",
+	},
+	"roles/iam.serviceAccountTokenCreator": {
+		"iam.serviceAccounts.get",
+		"iam.serviceAccounts.getAccessToken",
+		"iam.serviceAccounts.getOpenIdToken",
+		"iam.serviceAccounts.implicitDelegation",
+		"iam.serviceAccounts.signBlob",
+		"iam.serviceAccounts.signJwt",
+	},
+	"roles/iam.serviceAccountUser": {
+		"iam.serviceAccounts.actAs",
+		"iam.serviceAccounts.get",
+		"iam.serviceAccounts.list",
+	},
+}
+
+// RolePermissions returns the permissions granted by a role. Roles defined
+// in the policy take precedence over the built-in catalog.
+func RolePermissions(policy *Policy, role string) ([]string, bool) {
+	if r, ok := policy.Roles[role]; ok {
+		return r.Permissions, true
+	}
+
+	perms, ok := builtinRoles[role]
+	return perms, ok
+}
@@ -0,0 +1,879 @@
+package policy
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Expr is a parsed condition expression.
+//
+// Only the subset of CEL used by IAM conditions is supported: string, int,
+// bool and list literals, attribute selection (resource.name), the string
+// methods startsWith/endsWith/contains/matches, the timestamp() and
+// duration() functions, the timestamp accessors (getHours() etc.),
+// api.getAttribute(), the list method hasOnly(), comparison, "in", and the logical operators.
+type Expr struct {
+	source string
+	root   node
+}
+
+// ParseCondition parses a CEL condition expression.
+func ParseCondition(expression string) (*Expr, error) {
+	p := &parser{lex: newLexer(expression)}
+	p.next()
+
+	root, err := p.parseExpr(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at offset %d", p.tok.text, p.tok.pos)
+	}
+
+	return &Expr{source: expression, root: root}, nil
+}
+
+// String returns the original expression text.
+func (e *Expr) String() string {
+	return e.source
+}
+
+// Eval evaluates the expression against the given attributes.
+// The expression must produce a bool.
+func (e *Expr) Eval(attrs Attributes) (bool, error) {
+	v, err := e.root.eval(attrs)
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("condition must evaluate to bool, got %s", typeName(v))
+	}
+
+	return b, nil
+}
+
+// Attributes holds the variables visible to a condition expression,
+// keyed by top-level name (e.g. "resource", "request").
+type Attributes map[string]any
+
+// apiAttributes is the "api" variable: request attributes read with
+// api.getAttribute(name, default)
+type apiAttributes map[string]any
+
+// listAPIAttributes are the API attributes whose value is a list, even
+// when it holds a single element
+var listAPIAttributes = map[string]bool{
+	"iam.googleapis.com/modifiedGrantsByRole": true,
+}
+
+// IsListAPIAttribute reports whether the API attribute name has a list
+// value, such as iam.googleapis.com/modifiedGrantsByRole
+func IsListAPIAttribute(name string) bool {
+	return listAPIAttributes[name]
+}
+
+// normalizeValue converts values decoded from YAML or JSON to the types
+// conditions operate on: int64 for integers and []any for lists
+func normalizeValue(v any) any {
+	switch v := v.(type) {
+	case int:
+		return int64(v)
+	case float64:
+		if v == float64(int64(v)) {
+			return int64(v)
+		}
+	case []string:
+		list := make([]any, len(v))
+		for i, item := range v {
+			list[i] = item
+		}
+		return list
+	case []any:
+		list := make([]any, len(v))
+		for i, item := range v {
+			list[i] = normalizeValue(item)
+		}
+		return list
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, item := range v {
+			m[k] = normalizeValue(item)
+		}
+		return m
+	}
+	return v
+}
+
+// EvaluateCondition parses and evaluates a condition expression.
+func EvaluateCondition(expression string, attrs Attributes) (bool, error) {
+	expr, err := ParseCondition(expression)
+	if err != nil {
+		return false, err
+	}
+
+	return expr.Eval(attrs)
+}
+
+// --- lexer ---
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokIdent
+	tokString
+	tokInt
+	tokOp
+)
+
+type token struct {
+	kind tokKind
+	text string
+	pos  int
+}
+
+type lexer struct {
+	src string
+	pos int
+	err error
+}
+
+func newLexer(src string) *lexer {
+	return &lexer{src: src}
+}
+
+func (l *lexer) next() token {
+	for l.pos < len(l.src) && unicode.IsSpace(rune(l.src[l.pos])) {
+		l.pos++
+	}
+
+	if l.pos >= len(l.src) {
+		return token{kind: tokEOF, pos: l.pos}
+	}
+
+	start := l.pos
+	c := l.src[l.pos]
+
+	switch {
+	case c == '"' || c == '\'':
+		l.pos++
+		var sb strings.Builder
+		for l.pos < len(l.src) && l.src[l.pos] != c {
+			if l.src[l.pos] == '\\' && l.pos+1 < len(l.src) {
+				l.pos++
+				switch l.src[l.pos] {
+				case 'n':
+					sb.WriteByte('\n')
+				case 't':
+					sb.WriteByte('\t')
+				default:
+					sb.WriteByte(l.src[l.pos])
+				}
+			} else {
+				sb.WriteByte(l.src[l.pos])
+			}
+			l.pos++
+		}
+		if l.pos >= len(l.src) {
+			l.err = fmt.Errorf("unterminated string at offset %d", start)
+			return token{kind: tokEOF, pos: start}
+		}
+		l.pos++
+		return token{kind: tokString, text: sb.String(), pos: start}
+
+	case c >= '0' && c <= '9':
+		for l.pos < len(l.src) && l.src[l.pos] >= '0' && l.src[l.pos] <= '9' {
+			l.pos++
+		}
+		return token{kind: tokInt, text: l.src[start:l.pos], pos: start}
+
+	case c == '_' || unicode.IsLetter(rune(c)):
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || unicode.IsLetter(rune(l.src[l.pos])) || unicode.IsDigit(rune(l.src[l.pos]))) {
+			l.pos++
+		}
+		return token{kind: tokIdent, text: l.src[start:l.pos], pos: start}
+	}
+
+	for _, op := range []string{"&&", "||", "==", "!=", "<=", ">="} {
+		if strings.HasPrefix(l.src[l.pos:], op) {
+			l.pos += len(op)
+			return token{kind: tokOp, text: op, pos: start}
+		}
+	}
+
+	if strings.ContainsRune("!<>().,[]", rune(c)) {
+		l.pos++
+		return token{kind: tokOp, text: string(c), pos: start}
+	}
+
+	l.err = fmt.Errorf("unexpected character %q at offset %d", c, start)
+	return token{kind: tokEOF, pos: start}
+}
+
+// --- parser ---
+
+type parser struct {
+	lex *lexer
+	tok token
+}
+
+func (p *parser) next() {
+	p.tok = p.lex.next()
+}
+
+func (p *parser) expect(text string) error {
+	if p.lex.err != nil {
+		return p.lex.err
+	}
+	if p.tok.kind != tokOp || p.tok.text != text {
+		return fmt.Errorf("expected %q at offset %d", text, p.tok.pos)
+	}
+	p.next()
+	return nil
+}
+
+var binaryPrecedence = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"==": 3, "!=": 3, "<": 3, "<=": 3, ">": 3, ">=": 3, "in": 3,
+}
+
+func (p *parser) parseExpr(minPrec int) (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if p.lex.err != nil {
+			return nil, p.lex.err
+		}
+
+		op := p.tok.text
+		prec, ok := binaryPrecedence[op]
+		if !ok || (p.tok.kind != tokOp && !(p.tok.kind == tokIdent && op == "in")) || prec <= minPrec {
+			return left, nil
+		}
+		p.next()
+
+		right, err := p.parseExpr(prec)
+		if err != nil {
+			return nil, err
+		}
+
+		switch op {
+		case "&&", "||":
+			left = &logicalNode{op: op, left: left, right: right}
+		default:
+			left = &binaryNode{op: op, left: left, right: right}
+		}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.tok.kind == tokOp && p.tok.text == "!" {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (node, error) {
+	n, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.tok.kind == tokOp && p.tok.text == "." {
+		p.next()
+		if p.tok.kind != tokIdent {
+			return nil, fmt.Errorf("expected field or method name at offset %d", p.tok.pos)
+		}
+		name := p.tok.text
+		p.next()
+
+		if p.tok.kind == tokOp && p.tok.text == "(" {
+			args, err := p.parseArgs()
+			if err != nil {
+				return nil, err
+			}
+			n = &callNode{target: n, name: name, args: args}
+			continue
+		}
+
+		n = &selectNode{operand: n, field: name}
+	}
+
+	return n, nil
+}
+
+func (p *parser) parseArgs() ([]node, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+
+	var args []node
+	for !(p.tok.kind == tokOp && p.tok.text == ")") {
+		arg, err := p.parseExpr(0)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+
+		if p.tok.kind == tokOp && p.tok.text == "," {
+			p.next()
+			continue
+		}
+		break
+	}
+
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+
+	return args, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	if p.lex.err != nil {
+		return nil, p.lex.err
+	}
+
+	tok := p.tok
+	switch tok.kind {
+	case tokString:
+		p.next()
+		return &literalNode{value: tok.text}, nil
+
+	case tokInt:
+		p.next()
+		v, err := strconv.ParseInt(tok.text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %q at offset %d", tok.text, tok.pos)
+		}
+		return &literalNode{value: v}, nil
+
+	case tokIdent:
+		p.next()
+		switch tok.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		}
+
+		if p.tok.kind == tokOp && p.tok.text == "(" {
+			args, err := p.parseArgs()
+			if err != nil {
+				return nil, err
+			}
+			return &callNode{name: tok.text, args: args}, nil
+		}
+
+		return &identNode{name: tok.text}, nil
+
+	case tokOp:
+		switch tok.text {
+		case "(":
+			p.next()
+			inner, err := p.parseExpr(0)
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return inner, nil
+
+		case "[":
+			p.next()
+			var items []node
+			for !(p.tok.kind == tokOp && p.tok.text == "]") {
+				item, err := p.parseExpr(0)
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, item)
+				if p.tok.kind == tokOp && p.tok.text == "," {
+					p.next()
+					continue
+				}
+				break
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			return &listNode{items: items}, nil
+		}
+	}
+
+	if tok.kind == tokEOF {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+
+	return nil, fmt.Errorf("unexpected %q at offset %d", tok.text, tok.pos)
+}
+
+// --- evaluation ---
+
+type node interface {
+	eval(attrs Attributes) (any, error)
+}
+
+type literalNode struct {
+	value any
+}
+
+func (n *literalNode) eval(Attributes) (any, error) {
+	return n.value, nil
+}
+
+type identNode struct {
+	name string
+}
+
+func (n *identNode) eval(attrs Attributes) (any, error) {
+	v, ok := attrs[n.name]
+	if !ok {
+		return nil, fmt.Errorf("undeclared reference to %q", n.name)
+	}
+	return v, nil
+}
+
+type selectNode struct {
+	operand node
+	field   string
+}
+
+func (n *selectNode) eval(attrs Attributes) (any, error) {
+	v, err := n.operand.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("cannot select field %q from %s", n.field, typeName(v))
+	}
+
+	field, ok := m[n.field]
+	if !ok {
+		return nil, fmt.Errorf("no such attribute %q", n.field)
+	}
+
+	return field, nil
+}
+
+type listNode struct {
+	items []node
+}
+
+func (n *listNode) eval(attrs Attributes) (any, error) {
+	list := make([]any, 0, len(n.items))
+	for _, item := range n.items {
+		v, err := item.eval(attrs)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n *notNode) eval(attrs Attributes) (any, error) {
+	v, err := n.operand.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+
+	b, ok := v.(bool)
+	if !ok {
+		return nil, fmt.Errorf("operator ! requires bool, got %s", typeName(v))
+	}
+
+	return !b, nil
+}
+
+type logicalNode struct {
+	op          string
+	left, right node
+}
+
+func (n *logicalNode) eval(attrs Attributes) (any, error) {
+	left, err := evalBool(n.left, attrs, n.op)
+	if err != nil {
+		return nil, err
+	}
+
+	// Short-circuit like CEL
+	if n.op == "&&" && !left {
+		return false, nil
+	}
+	if n.op == "||" && left {
+		return true, nil
+	}
+
+	return evalBool(n.right, attrs, n.op)
+}
+
+func evalBool(n node, attrs Attributes, op string) (bool, error) {
+	v, err := n.eval(attrs)
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("operator %s requires bool operands, got %s", op, typeName(v))
+	}
+
+	return b, nil
+}
+
+type binaryNode struct {
+	op          string
+	left, right node
+}
+
+func (n *binaryNode) eval(attrs Attributes) (any, error) {
+	left, err := n.left.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+
+	right, err := n.right.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "in":
+		list, ok := right.([]any)
+		if !ok {
+			return nil, fmt.Errorf("operator in requires a list, got %s", typeName(right))
+		}
+		for _, item := range list {
+			if equal(left, item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	cmp, err := compare(left, right)
+	if err != nil {
+		return nil, fmt.Errorf("operator %s: %w", n.op, err)
+	}
+
+	switch n.op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default: // ">="
+		return cmp >= 0, nil
+	}
+}
+
+type callNode struct {
+	target node // nil for global functions
+	name   string
+	args   []node
+}
+
+func (n *callNode) eval(attrs Attributes) (any, error) {
+	args := make([]any, 0, len(n.args))
+	for _, arg := range n.args {
+		v, err := arg.eval(attrs)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+
+	if n.target == nil {
+		return callFunction(n.name, args)
+	}
+
+	target, err := n.target.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+
+	return callMethod(target, n.name, args)
+}
+
+func callFunction(name string, args []any) (any, error) {
+	switch name {
+	case "timestamp":
+		s, err := singleStringArg(name, args)
+		if err != nil {
+			return nil, err
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return nil, fmt.Errorf("timestamp(): %w", err)
+		}
+		return t, nil
+
+	case "duration":
+		s, err := singleStringArg(name, args)
+		if err != nil {
+			return nil, err
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("duration(): %w", err)
+		}
+		return d, nil
+
+	case "size":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("size() takes 1 argument")
+		}
+		return sizeOf(args[0])
+	}
+
+	return nil, fmt.Errorf("unknown function %s()", name)
+}
+
+func callMethod(target any, name string, args []any) (any, error) {
+	if name == "size" && len(args) == 0 {
+		return sizeOf(target)
+	}
+
+	switch target := target.(type) {
+	case apiAttributes:
+		if name != "getAttribute" || len(args) != 2 {
+			return nil, fmt.Errorf("unknown method %s() on api (expected getAttribute(name, default))", name)
+		}
+		key, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("getAttribute() requires a string name, got %s", typeName(args[0]))
+		}
+		if v, ok := target[key]; ok {
+			return v, nil
+		}
+		return args[1], nil
+	case time.Time:
+		return timestampMethod(target, name, args)
+	case []any:
+		return listMethod(target, name, args)
+	}
+
+	if name == "hasOnly" {
+		return nil, fmt.Errorf("hasOnly() on %s (expected a list)", typeName(target))
+	}
+
+	s, ok := target.(string)
+	if !ok {
+		return nil, fmt.Errorf("unknown method %s() on %s", name, typeName(target))
+	}
+
+	arg, err := singleStringArg(name, args)
+	if err != nil {
+		return nil, err
+	}
+
+	switch name {
+	case "startsWith":
+		return strings.HasPrefix(s, arg), nil
+	case "endsWith":
+		return strings.HasSuffix(s, arg), nil
+	case "contains":
+		return strings.Contains(s, arg), nil
+	case "matches":
+		re, err := regexp.Compile(arg)
+		if err != nil {
+			return nil, fmt.Errorf("matches(): %w", err)
+		}
+		return re.MatchString(s), nil
+	}
+
+	return nil, fmt.Errorf("unknown method %s() on string", name)
+}
+
+// listMethod implements hasOnly, which IAM uses to limit the roles in
+// iam.googleapis.com/modifiedGrantsByRole: it is true when every element of
+// the list is in the argument
+func listMethod(list []any, name string, args []any) (any, error) {
+	if name != "hasOnly" {
+		return nil, fmt.Errorf("unknown method %s() on list", name)
+	}
+	if len(args) != 1 {
+		return nil, fmt.Errorf("hasOnly() takes 1 argument")
+	}
+	allowed, ok := args[0].([]any)
+	if !ok {
+		return nil, fmt.Errorf("hasOnly() requires a list argument, got %s", typeName(args[0]))
+	}
+
+	for _, v := range list {
+		if !slices.ContainsFunc(allowed, func(a any) bool { return equal(v, a) }) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// timestampMethod implements the CEL timestamp accessors. Like CEL,
+// getMonth, getDayOfMonth and getDayOfYear are zero-based, getDate is
+// one-based and getDayOfWeek counts from Sunday. The optional argument is
+// an IANA time zone; the default is UTC.
+func timestampMethod(t time.Time, name string, args []any) (any, error) {
+	t = t.UTC()
+	if len(args) > 0 {
+		tz, err := singleStringArg(name, args)
+		if err != nil {
+			return nil, err
+		}
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("%s(): %w", name, err)
+		}
+		t = t.In(loc)
+	}
+
+	switch name {
+	case "getFullYear":
+		return int64(t.Year()), nil
+	case "getMonth":
+		return int64(t.Month()) - 1, nil
+	case "getDate":
+		return int64(t.Day()), nil
+	case "getDayOfMonth":
+		return int64(t.Day()) - 1, nil
+	case "getDayOfWeek":
+		return int64(t.Weekday()), nil
+	case "getDayOfYear":
+		return int64(t.YearDay()) - 1, nil
+	case "getHours":
+		return int64(t.Hour()), nil
+	case "getMinutes":
+		return int64(t.Minute()), nil
+	case "getSeconds":
+		return int64(t.Second()), nil
+	}
+
+	return nil, fmt.Errorf("unknown method %s() on timestamp", name)
+}
+
+func singleStringArg(name string, args []any) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("%s() takes 1 argument", name)
+	}
+
+	s, ok := args[0].(string)
+	if !ok {
+		return "", fmt.Errorf("%s() requires a string argument, got %s", name, typeName(args[0]))
+	}
+
+	return s, nil
+}
+
+func sizeOf(v any) (any, error) {
+	switch v := v.(type) {
+	case string:
+		return int64(len(v)), nil
+	case []any:
+		return int64(len(v)), nil
+	case map[string]any:
+		return int64(len(v)), nil
+	}
+	return nil, fmt.Errorf("size() not supported on %s", typeName(v))
+}
+
+func equal(a, b any) bool {
+	switch a := a.(type) {
+	case time.Time:
+		bt, ok := b.(time.Time)
+		return ok && a.Equal(bt)
+	case []any, map[string]any:
+		return false
+	}
+
+	switch b.(type) {
+	case []any, map[string]any:
+		return false
+	}
+
+	return a == b
+}
+
+func compare(a, b any) (int, error) {
+	switch a := a.(type) {
+	case int64:
+		if b, ok := b.(int64); ok {
+			return cmpOrdered(a, b), nil
+		}
+	case string:
+		if b, ok := b.(string); ok {
+			return strings.Compare(a, b), nil
+		}
+	case time.Time:
+		if b, ok := b.(time.Time); ok {
+			return a.Compare(b), nil
+		}
+	case time.Duration:
+		if b, ok := b.(time.Duration); ok {
+			return cmpOrdered(a, b), nil
+		}
+	}
+
+	return 0, fmt.Errorf("cannot compare %s with %s", typeName(a), typeName(b))
+}
+
+func cmpOrdered[T int64 | time.Duration](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func typeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case int64:
+		return "int"
+	case string:
+		return "string"
+	case time.Time:
+		return "timestamp"
+	case time.Duration:
+		return "duration"
+	case []any:
+		return "list"
+	case map[string]any, apiAttributes:
+		return "map"
+	}
+	return fmt.Sprintf("%T", v)
+}
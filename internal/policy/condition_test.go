@@ -0,0 +1,77 @@
+package policy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEvaluateCondition(t *testing.T) {
+	attrs := Attributes{
+		"resource": map[string]any{
+			"name": "projects/test-project/secrets/prod-db",
+		},
+		"request": map[string]any{
+			"time": time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC),
+		},
+		"api": apiAttributes{
+			"iam.googleapis.com/modifiedGrantsByRole": []any{"roles/viewer"},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		expression string
+		want       bool
+		wantErr    bool
+	}{
+		{name: "startsWith", expression: `resource.name.startsWith("projects/test-project/secrets/prod-")`, want: true},
+		{name: "negated startsWith", expression: `!resource.name.startsWith("projects/test-project/secrets/prod-")`, want: false},
+		{name: "endsWith", expression: `resource.name.endsWith("/prod-db")`, want: true},
+		{name: "contains", expression: `resource.name.contains("/dev-")`, want: false},
+		{name: "matches", expression: `resource.name.matches("projects/[^/]+/secrets/(prod|staging)-.*")`, want: true},
+		{name: "equality", expression: `resource.name == 'projects/test-project/secrets/prod-db'`, want: true},
+		{name: "and or precedence", expression: `resource.name.contains("/dev-") || resource.name.contains("/prod-") && true`, want: true},
+		{name: "parentheses", expression: `(resource.name.contains("/dev-") || resource.name.contains("/prod-")) && false`, want: false},
+		{name: "in list", expression: `resource.name in ["a", "projects/test-project/secrets/prod-db"]`, want: true},
+		{name: "timestamp before", expression: `request.time < timestamp("2026-12-31T23:59:59Z")`, want: true},
+		{name: "timestamp after", expression: `request.time >= timestamp("2027-01-01T00:00:00Z")`, want: false},
+		{name: "getFullYear", expression: `request.time.getFullYear() == 2026`, want: true},
+		{name: "getMonth is zero based", expression: `request.time.getMonth() == 5`, want: true},
+		{name: "getDayOfWeek", expression: `request.time.getDayOfWeek() == 1`, want: true},
+		{name: "getHours in time zone", expression: `request.time.getHours("America/New_York") == 20`, want: true},
+		{name: "getDate is one based", expression: `request.time.getDate() == 1 && request.time.getDayOfMonth() == 0`, want: true},
+		{name: "unknown time zone", expression: `request.time.getHours("Mars/Olympus") == 0`, wantErr: true},
+		{name: "getAttribute", expression: `api.getAttribute("iam.googleapis.com/modifiedGrantsByRole", []).hasOnly(["roles/viewer"])`, want: true},
+		{name: "getAttribute in list", expression: `"roles/viewer" in api.getAttribute("iam.googleapis.com/modifiedGrantsByRole", [])`, want: true},
+		{name: "getAttribute default", expression: `size(api.getAttribute("missing", [])) == 0`, want: true},
+		{name: "hasOnly", expression: `["roles/viewer", "roles/editor"].hasOnly(["roles/viewer"])`, want: false},
+		{name: "hasOnly on string", expression: `resource.name.hasOnly(["x"])`, wantErr: true},
+		{name: "getAttribute without default", expression: `api.getAttribute("missing") == ""`, wantErr: true},
+		{name: "short circuit skips error", expression: `false && unknown.attr`, want: false},
+		{name: "undeclared reference", expression: `unknown.attr == "x"`, wantErr: true},
+		{name: "non-bool result", expression: `resource.name`, wantErr: true},
+		{name: "syntax error", expression: `resource.name.startsWith("x"`, wantErr: true},
+		{name: "unterminated string", expression: `resource.name == "x`, wantErr: true},
+		{name: "type mismatch", expression: `request.time < "2026"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EvaluateCondition(tt.expression, attrs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EvaluateCondition(%q) error = %v, wantErr %v", tt.expression, err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("EvaluateCondition(%q) = %v, want %v", tt.expression, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasOnlyOnStringError(t *testing.T) {
+	attrs := Attributes{"api": apiAttributes{"iam.googleapis.com/modifiedGrantsByRole": "roles/viewer"}}
+	_, err := EvaluateCondition(`api.getAttribute("iam.googleapis.com/modifiedGrantsByRole", []).hasOnly(["roles/viewer"])`, attrs)
+	if err == nil || err.Error() != "hasOnly() on string (expected a list)" {
+		t.Errorf("error = %v, want hasOnly() on string", err)
+	}
+}
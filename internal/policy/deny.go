@@ -0,0 +1,136 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DenyResult records the deny rule that denied a request
+type DenyResult struct {
+	Policy string
+	Index  int
+	Rule   DenyRule
+	// Member is the denied principal that matched the request's principal
+	Member string
+	// ConditionError is set when the denial condition could not be
+	// evaluated; the rule then applies, as in GCP
+	ConditionError error
+}
+
+// Label identifies the rule as policy[index]
+func (d DenyResult) Label() string {
+	return fmt.Sprintf("%s[%d]", d.Policy, d.Index)
+}
+
+// evaluateDeny returns the first deny rule, in policy name order, that
+// applies to the principal and permission on resource, or nil if none does
+func evaluateDeny(policy *Policy, principal string, req Request, attrs Attributes) *DenyResult {
+	if len(policy.DenyPolicies) == 0 {
+		return nil
+	}
+
+	attached := make(map[string]bool)
+	for _, name := range attachmentPoints(policy, req.Resource) {
+		attached[name] = true
+	}
+
+	for _, name := range sortedNames(policy.DenyPolicies) {
+		deny := policy.DenyPolicies[name]
+		if !attached[deny.AttachmentPoint] {
+			continue
+		}
+
+		for i, rule := range deny.Rules {
+			if !containsString(rule.DeniedPermissions, req.Permission) || containsString(rule.ExceptionPermissions, req.Permission) {
+				continue
+			}
+			member := matchMember(policy, rule.DeniedPrincipals, principal)
+			if member == "" || IsMember(policy, rule.ExceptionPrincipals, principal) {
+				continue
+			}
+
+			result := &DenyResult{Policy: name, Index: i, Rule: rule, Member: member}
+			if rule.DenialCondition != nil {
+				met, err := EvaluateCondition(rule.DenialCondition.Expression, attrs)
+				if err == nil && !met {
+					continue
+				}
+				result.ConditionError = err
+			}
+			return result
+		}
+	}
+
+	return nil
+}
+
+// attachmentPoints returns the names a deny policy can be attached to for
+// resource: its project, then the folders and organization above it
+func attachmentPoints(policy *Policy, resource string) []string {
+	var points []string
+	var parent string
+
+	if projectID := ProjectFromResource(resource); projectID != "" {
+		points = append(points, "projects/"+projectID)
+		parent = policy.Projects[projectID].Parent
+	} else if strings.HasPrefix(resource, "folders/") || strings.HasPrefix(resource, "organizations/") {
+		parts := strings.SplitN(resource, "/", 3)
+		parent = parts[0] + "/" + parts[1]
+	}
+
+	ancestors, _ := Ancestors(policy, parent)
+	return append(points, ancestors...)
+}
+
+// validateDenyPolicy checks one deny policy, prefixing errors with its name
+func validateDenyPolicy(result *ValidationResult, policy *Policy, name string, deny DenyPolicy) {
+	scope := "Deny policy " + name
+
+	kind, id, _ := strings.Cut(deny.AttachmentPoint, "/")
+	switch {
+	case deny.AttachmentPoint == "":
+		result.addError(fmt.Sprintf("%s: no attachment point specified", scope))
+	case kind == "projects":
+		if !hasProject(policy, id) {
+			result.addError(fmt.Sprintf("%s: undefined project %s", scope, id))
+		}
+	case kind == "folders", kind == "organizations":
+		if !hasNode(policy, deny.AttachmentPoint) {
+			result.addError(fmt.Sprintf("%s: undefined attachment point %s", scope, deny.AttachmentPoint))
+		}
+	default:
+		result.addError(fmt.Sprintf("%s: invalid attachment point %s (expected projects/{id}, folders/{id} or organizations/{id})", scope, deny.AttachmentPoint))
+	}
+
+	if len(deny.Rules) == 0 {
+		result.addWarning(fmt.Sprintf("%s has no rules", scope))
+	}
+
+	for i, rule := range deny.Rules {
+		if len(rule.DeniedPrincipals) == 0 {
+			result.addError(fmt.Sprintf("%s rule %d: no denied principals specified", scope, i))
+		}
+		if len(rule.DeniedPermissions) == 0 {
+			result.addError(fmt.Sprintf("%s rule %d: no denied permissions specified", scope, i))
+		}
+
+		for _, principal := range append(append([]string{}, rule.DeniedPrincipals...), rule.ExceptionPrincipals...) {
+			if err := validatePrincipal(principal, policy); err != nil {
+				result.addError(fmt.Sprintf("%s rule %d: %v", scope, i, err))
+			}
+		}
+		for _, perm := range append(append([]string{}, rule.DeniedPermissions...), rule.ExceptionPermissions...) {
+			if err := validatePermission(perm); err != nil {
+				result.addError(fmt.Sprintf("%s rule %d: %v", scope, i, err))
+			}
+		}
+
+		if c := rule.DenialCondition; c != nil {
+			if c.Expression == "" {
+				result.addError(fmt.Sprintf("%s rule %d: denial condition has empty expression", scope, i))
+			} else if _, err := ParseCondition(c.Expression); err != nil {
+				result.addError(fmt.Sprintf("%s rule %d: invalid denial condition expression: %v", scope, i, err))
+			}
+		}
+	}
+}
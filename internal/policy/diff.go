@@ -0,0 +1,124 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Diff returns a unified diff of the YAML renderings of two policies,
+// or "" if they render identically
+func Diff(oldName string, old *Policy, newName string, new *Policy) (string, error) {
+	a, err := yaml.Marshal(old)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal policy YAML: %w", err)
+	}
+	b, err := yaml.Marshal(new)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal policy YAML: %w", err)
+	}
+
+	return DiffLines(oldName, string(a), newName, string(b)), nil
+}
+
+// DiffLines returns a unified diff (3 lines of context) between two texts
+func DiffLines(oldName, a, newName, b string) string {
+	x := splitLines(a)
+	y := splitLines(b)
+	ops := diffOps(x, y)
+
+	var changes []int
+	for k, op := range ops {
+		if op.kind != ' ' {
+			changes = append(changes, k)
+		}
+	}
+	if len(changes) == 0 {
+		return ""
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+
+	const context = 3
+	for c := 0; c < len(changes); {
+		// Merge changes separated by at most 2*context unchanged lines
+		first, last := changes[c], changes[c]
+		for c++; c < len(changes) && changes[c]-last <= 2*context+1; c++ {
+			last = changes[c]
+		}
+
+		start := max(first-context, 0)
+		end := min(last+context+1, len(ops))
+
+		oldCount, newCount := 0, 0
+		for _, op := range ops[start:end] {
+			if op.kind != '+' {
+				oldCount++
+			}
+			if op.kind != '-' {
+				newCount++
+			}
+		}
+
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", ops[start].oldLine+1, oldCount, ops[start].newLine+1, newCount)
+		for _, op := range ops[start:end] {
+			fmt.Fprintf(&out, "%c%s\n", op.kind, op.text)
+		}
+	}
+
+	return out.String()
+}
+
+type diffOp struct {
+	kind    byte // ' ', '-' or '+'
+	text    string
+	oldLine int
+	newLine int
+}
+
+// diffOps computes an edit script with a longest common subsequence table;
+// policy files are small enough that O(n*m) is fine
+func diffOps(x, y []string) []diffOp {
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			ops = append(ops, diffOp{' ', x[i], i, j})
+			i++
+			j++
+		case i < len(x) && (j >= len(y) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', x[i], i, j})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', y[j], i, j})
+			j++
+		}
+	}
+
+	return ops
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
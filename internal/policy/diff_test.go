@@ -0,0 +1,73 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	a := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	b := "a\nb\nc\nD\ne\nf\ng\nh\ni\nj\nk\n"
+
+	want := `--- old
++++ new
+@@ -1,10 +1,11 @@
+ a
+ b
+ c
+-d
++D
+ e
+ f
+ g
+ h
+ i
+ j
++k
+`
+	if got := DiffLines("old", a, "new", b); got != want {
+		t.Errorf("DiffLines() =\n%s\nwant\n%s", got, want)
+	}
+
+	if got := DiffLines("old", a, "new", a); got != "" {
+		t.Errorf("DiffLines() of identical texts = %q, want empty", got)
+	}
+}
+
+func TestDiffLinesSeparateHunks(t *testing.T) {
+	var lines []string
+	for i := 0; i < 20; i++ {
+		lines = append(lines, string(rune('a'+i)))
+	}
+	a := strings.Join(lines, "\n")
+
+	changed := append([]string(nil), lines...)
+	changed[1] = "B"
+	changed[18] = "S"
+	b := strings.Join(changed, "\n")
+
+	got := DiffLines("old", a, "new", b)
+	if n := strings.Count(got, "@@ -"); n != 2 {
+		t.Errorf("got %d hunks, want 2:\n%s", n, got)
+	}
+	if !strings.Contains(got, "@@ -1,5 +1,5 @@") || !strings.Contains(got, "@@ -16,5 +16,5 @@") {
+		t.Errorf("unexpected hunk headers:\n%s", got)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	old := &Policy{
+		Roles: map[string]Role{"roles/custom.r": {Permissions: []string{"secretmanager.secrets.get", "secretmanager.secrets.list"}}},
+	}
+	trimmed := &Policy{
+		Roles: map[string]Role{"roles/custom.r": {Permissions: []string{"secretmanager.secrets.get"}}},
+	}
+
+	got, err := Diff("policy.yaml", old, "trimmed", trimmed)
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	if !strings.Contains(got, "-            - secretmanager.secrets.list") {
+		t.Errorf("Diff() missing removed permission:\n%s", got)
+	}
+}
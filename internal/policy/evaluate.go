@@ -0,0 +1,365 @@
+package policy
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Request describes a single permission check
+type Request struct {
+	Principal  string
+	Resource   string
+	Permission string
+	// Time is exposed to conditions as request.time (defaults to now)
+	Time time.Time
+	// APIAttributes are the request's API attributes, read by conditions
+	// with api.getAttribute(name, default)
+	APIAttributes map[string]any
+	// Delegates is an impersonation chain: Principal impersonates the
+	// first service account, which impersonates the next, and so on. The
+	// request is made as the last one. Entries are emails, optionally
+	// prefixed with serviceAccount:.
+	Delegates []string
+}
+
+// Decision is the outcome of evaluating a Request against a Policy
+type Decision struct {
+	Allowed bool
+	Request Request
+	// Reason summarizes why the request was allowed or denied
+	Reason string
+	// Grant is the binding that allowed the request (nil when denied)
+	Grant *BindingResult
+	// Denial is the deny rule that denied the request; bindings are not
+	// evaluated when it is set
+	Denial *DenyResult
+	// Boundary is set when the principal is subject to access boundaries;
+	// a grant outside them is denied
+	Boundary *BoundaryResult
+	// Checked lists every binding considered, in evaluation order
+	Checked []BindingResult
+	// Delegation holds the impersonation check for each delegate, in chain
+	// order; if one is denied, later delegates and the request itself are
+	// not evaluated
+	Delegation []*Decision
+}
+
+// BindingResult records how a single binding was evaluated
+type BindingResult struct {
+	// Project is "" for bindings inherited from folders and organizations
+	Project string
+	// Resource is the resource below the project that the binding is
+	// attached to, the folder or organization it is inherited from, or ""
+	// for a project binding
+	Resource string
+	Index    int
+	Binding  Binding
+	// Member is the binding member that matched the principal ("" if none)
+	Member string
+	// RoleFound reports whether the role is defined in the policy or built in
+	RoleFound     bool
+	HasPermission bool
+	// ConditionMet is nil when the binding has no condition
+	ConditionMet   *bool
+	ConditionError error
+	Granted        bool
+}
+
+// Outcome returns a short human-readable explanation of the binding result
+func (b BindingResult) Outcome() string {
+	switch {
+	case b.Granted:
+		return "granted"
+	case b.Member == "":
+		return "principal not a member"
+	case !b.RoleFound:
+		return "role not defined"
+	case !b.HasPermission:
+		return "permission not in role"
+	case b.ConditionError != nil:
+		return fmt.Sprintf("condition error: %v", b.ConditionError)
+	default:
+		return "condition not satisfied"
+	}
+}
+
+// Evaluate decides whether the request is allowed by the policy.
+//
+// Bindings are taken from the project named in the resource path
+// (projects/{project}/...), from the service account for service accounts
+// and their sub-resources, from the resources section for the resource and
+// any resource containing it, and from the folders and organization above
+// the project, which are inherited. A binding grants access
+// when the principal is a member (directly, via group expansion, or via
+// allUsers / allAuthenticatedUsers), its role contains the permission, and
+// its condition, if any, evaluates to true.
+//
+// Deny policies attached to the resource's project, folders or organization
+// are checked before any binding: a matching deny rule denies the request
+// regardless of grants. A principal covered by access boundaries is denied
+// any permission outside them, even when a binding grants it.
+//
+// With Delegates set, each step of the impersonation chain must be allowed
+// ImpersonationPermission first; the request is then evaluated as the last
+// delegate.
+func Evaluate(policy *Policy, req Request) *Decision {
+	if req.Time.IsZero() {
+		req.Time = time.Now()
+	}
+
+	decision := &Decision{Request: req}
+
+	principal := req.Principal
+	if len(req.Delegates) > 0 {
+		if !evaluateDelegation(policy, req, decision) {
+			return decision
+		}
+		principal = "serviceAccount:" + strings.TrimPrefix(req.Delegates[len(req.Delegates)-1], "serviceAccount:")
+	}
+
+	refs, reason := applicableBindings(policy, req.Resource)
+	if reason != "" {
+		decision.Reason = reason
+		return decision
+	}
+
+	attrs := conditionAttributes(req)
+
+	// Deny policies are checked first and override any grant
+	if denial := evaluateDeny(policy, principal, req, attrs); denial != nil {
+		decision.Denial = denial
+		decision.Reason = fmt.Sprintf("denied by deny policy %s (via %s)", denial.Label(), denial.Member)
+		if len(req.Delegates) > 0 {
+			decision.Reason += fmt.Sprintf(" as %s, impersonated by %s", principal, req.Principal)
+		}
+		return decision
+	}
+
+	for _, ref := range refs {
+		binding := ref.Binding
+		result := BindingResult{
+			Project:  ref.Project,
+			Resource: ref.Resource,
+			Index:    ref.Index,
+			Binding:  binding,
+			Member:   matchMember(policy, binding.Members, principal),
+		}
+
+		perms, found := RolePermissions(policy, binding.Role)
+		result.RoleFound = found
+		result.HasPermission = containsString(perms, req.Permission)
+
+		if result.Member != "" && result.HasPermission {
+			result.Granted = true
+			if binding.Condition != nil {
+				met, err := EvaluateCondition(binding.Condition.Expression, attrs)
+				result.ConditionMet = &met
+				result.ConditionError = err
+				result.Granted = err == nil && met
+			}
+		}
+
+		decision.Checked = append(decision.Checked, result)
+
+		if result.Granted && decision.Grant == nil {
+			grant := result
+			decision.Grant = &grant
+			decision.Allowed = true
+		}
+	}
+
+	decision.Boundary = evaluateBoundary(policy, principal, req.Permission)
+	outside := decision.Boundary != nil && !decision.Boundary.Within
+
+	switch {
+	case decision.Allowed && outside:
+		decision.Reason = fmt.Sprintf("granted by %s (via %s) but %s is outside the access boundary (%s)",
+			decision.Grant.Binding.Role, decision.Grant.Member, req.Permission, strings.Join(decision.Boundary.Boundaries, ", "))
+		decision.Allowed = false
+		decision.Grant = nil
+	case decision.Allowed:
+		decision.Reason = fmt.Sprintf("granted by %s (via %s)", decision.Grant.Binding.Role, decision.Grant.Member)
+	default:
+		decision.Reason = "no matching bindings found"
+	}
+	if len(req.Delegates) > 0 {
+		decision.Reason += fmt.Sprintf(" as %s, impersonated by %s", principal, req.Principal)
+	}
+
+	return decision
+}
+
+// ProjectFromResource extracts the project ID from a canonical resource name
+// (projects/{project}/...). It returns "" if the name is not project-scoped.
+// Service account names with a "-" project resolve to the email's project.
+func ProjectFromResource(resource string) string {
+	if project, _, ok := ParseServiceAccountResource(resource); ok {
+		return project
+	}
+
+	parts := strings.SplitN(resource, "/", 3)
+	if len(parts) < 2 || parts[0] != "projects" || parts[1] == "-" {
+		return ""
+	}
+	return parts[1]
+}
+
+// MatchPermission reports whether permission matches pattern: exactly, or
+// as a glob when pattern contains '*' (e.g. secretmanager.*)
+func MatchPermission(pattern, permission string) bool {
+	if !strings.Contains(pattern, "*") {
+		return pattern == permission
+	}
+
+	ok, err := path.Match(pattern, permission)
+	return err == nil && ok
+}
+
+// resourceBindings returns the bindings that apply to resource within its
+// project: the project's, then those on the service account it names, then
+// those in the resources section on it or on a resource containing it
+func resourceBindings(policy *Policy, projectID string, project Project, resource string) []BindingRef {
+	var refs []BindingRef
+	for i, binding := range project.Bindings {
+		refs = append(refs, BindingRef{Project: projectID, Index: i, Binding: binding})
+	}
+
+	if _, email, ok := ParseServiceAccountResource(resource); ok {
+		if sa, ok := project.ServiceAccounts[email]; ok {
+			for i, binding := range sa.Bindings {
+				refs = append(refs, BindingRef{
+					Project:  projectID,
+					Resource: "projects/" + projectID + "/serviceAccounts/" + email,
+					Index:    i,
+					Binding:  binding,
+				})
+			}
+		}
+	}
+
+	for _, name := range enclosingResources(policy, resource) {
+		for i, binding := range policy.Resources[name].Bindings {
+			refs = append(refs, BindingRef{Project: projectID, Resource: name, Index: i, Binding: binding})
+		}
+	}
+
+	return refs
+}
+
+// IsMember reports whether the principal matches any of the members,
+// expanding groups defined in the policy
+func IsMember(policy *Policy, members []string, principal string) bool {
+	return matchMember(policy, members, principal) != ""
+}
+
+// ServiceAccounts returns the emails of the service accounts named in the
+// policy's bindings and groups or holding bindings themselves, sorted
+func ServiceAccounts(policy *Policy) []string {
+	seen := make(map[string]bool)
+	add := func(members []string) {
+		for _, member := range members {
+			if email, ok := strings.CutPrefix(member, "serviceAccount:"); ok {
+				seen[email] = true
+			}
+		}
+	}
+
+	for _, group := range policy.Groups {
+		add(group.Members)
+	}
+	for _, ref := range AllBindings(policy) {
+		add(ref.Binding.Members)
+	}
+	for _, project := range policy.Projects {
+		for email := range project.ServiceAccounts {
+			seen[email] = true
+		}
+	}
+
+	emails := make([]string, 0, len(seen))
+	for email := range seen {
+		emails = append(emails, email)
+	}
+	sort.Strings(emails)
+	return emails
+}
+
+// matchMember returns the first member that matches the principal
+func matchMember(policy *Policy, members []string, principal string) string {
+	for _, member := range members {
+		if memberMatches(policy, member, principal, map[string]bool{}) {
+			return member
+		}
+	}
+	return ""
+}
+
+func memberMatches(policy *Policy, member, principal string, visited map[string]bool) bool {
+	if member == principal {
+		return true
+	}
+
+	switch member {
+	case "allUsers":
+		return true
+	case "allAuthenticatedUsers":
+		return principal != "" && principal != "allUsers"
+	}
+
+	switch kind, id, _ := strings.Cut(member, ":"); kind {
+	case "domain":
+		return inDomain(principal, id)
+	case "deleted":
+		// A deleted principal never matches, even if the email is reused
+		return false
+	case "principalSet":
+		set, err := ParsePrincipal(member)
+		return err == nil && matchesPrincipalSet(policy, set, principal)
+	}
+
+	name, ok := strings.CutPrefix(member, "group:")
+	if !ok || visited[name] {
+		return false
+	}
+	visited[name] = true
+
+	group, ok := policy.Groups[name]
+	if !ok {
+		return false
+	}
+
+	for _, nested := range group.Members {
+		if memberMatches(policy, nested, principal, visited) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func conditionAttributes(req Request) Attributes {
+	service, kind := ResourceType(req.Resource)
+	return Attributes{
+		"resource": map[string]any{
+			"name":    req.Resource,
+			"type":    kind,
+			"service": service,
+		},
+		"request": map[string]any{
+			"time": req.Time,
+		},
+		"api": apiAttributes(normalizeValue(req.APIAttributes).(map[string]any)),
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,627 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEvaluate(t *testing.T) {
+	policy, err := Load("../../testdata/policy.yaml")
+	if err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		principal  string
+		resource   string
+		permission string
+		want       bool
+	}{
+		{
+			name:       "group member granted",
+			principal:  "user:alice@example.com",
+			resource:   "projects/test-project/secrets/db-password",
+			permission: "secretmanager.secrets.get",
+			want:       true,
+		},
+		{
+			name:       "group member missing permission",
+			principal:  "user:alice@example.com",
+			resource:   "projects/test-project/secrets/db-password",
+			permission: "secretmanager.secrets.delete",
+			want:       false,
+		},
+		{
+			name:       "non-member denied",
+			principal:  "user:mallory@example.com",
+			resource:   "projects/test-project/secrets/db-password",
+			permission: "secretmanager.secrets.get",
+			want:       false,
+		},
+		{
+			name:       "condition satisfied",
+			principal:  "serviceAccount:ci@test-project.iam.gserviceaccount.com",
+			resource:   "projects/test-project/secrets/prod-api-key",
+			permission: "secretmanager.versions.access",
+			want:       true,
+		},
+		{
+			name:       "condition not satisfied",
+			principal:  "serviceAccount:ci@test-project.iam.gserviceaccount.com",
+			resource:   "projects/test-project/secrets/dev-api-key",
+			permission: "secretmanager.versions.access",
+			want:       false,
+		},
+		{
+			name:       "unknown project",
+			principal:  "user:alice@example.com",
+			resource:   "projects/other-project/secrets/db-password",
+			permission: "secretmanager.secrets.get",
+			want:       false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := Evaluate(policy, Request{
+				Principal:  tt.principal,
+				Resource:   tt.resource,
+				Permission: tt.permission,
+			})
+			if decision.Allowed != tt.want {
+				t.Errorf("Evaluate() allowed = %v, want %v (%s)", decision.Allowed, tt.want, decision.Reason)
+			}
+		})
+	}
+}
+
+func TestEvaluateNestedGroupsAndBuiltinRoles(t *testing.T) {
+	policy := &Policy{
+		Groups: map[string]Group{
+			"developers": {Members: []string{"user:bob@example.com"}},
+			"admins":     {Members: []string{"group:developers", "group:admins"}},
+		},
+		Projects: map[string]Project{
+			"test-project": {
+				Bindings: []Binding{
+					{Role: "roles/secretmanager.secretAccessor", Members: []string{"group:admins"}},
+				},
+			},
+		},
+	}
+
+	decision := Evaluate(policy, Request{
+		Principal:  "user:bob@example.com",
+		Resource:   "projects/test-project/secrets/s/versions/1",
+		Permission: "secretmanager.versions.access",
+	})
+	if !decision.Allowed {
+		t.Fatalf("Expected nested group member to be allowed: %s", decision.Reason)
+	}
+	if decision.Grant.Member != "group:admins" {
+		t.Errorf("Expected grant via group:admins, got %s", decision.Grant.Member)
+	}
+}
+
+func TestBindingResultOutcome(t *testing.T) {
+	policy, err := Load("../../testdata/policy.yaml")
+	if err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+
+	decision := Evaluate(policy, Request{
+		Principal:  "serviceAccount:ci@test-project.iam.gserviceaccount.com",
+		Resource:   "projects/test-project/secrets/dev-api-key",
+		Permission: "secretmanager.secrets.get",
+	})
+
+	if len(decision.Checked) != 2 {
+		t.Fatalf("Expected 2 checked bindings, got %d", len(decision.Checked))
+	}
+	if got := decision.Checked[0].Outcome(); got != "principal not a member" {
+		t.Errorf("Unexpected outcome for binding 0: %s", got)
+	}
+	if got := decision.Checked[1].Outcome(); got != "condition not satisfied" {
+		t.Errorf("Unexpected outcome for binding 1: %s", got)
+	}
+}
+
+func TestServiceAccounts(t *testing.T) {
+	policy := &Policy{
+		Groups: map[string]Group{
+			"robots": {Members: []string{"serviceAccount:b@p.iam.gserviceaccount.com", "user:alice@example.com"}},
+		},
+		Projects: map[string]Project{
+			"p": {
+				Bindings: []Binding{
+					{Role: "roles/viewer", Members: []string{"serviceAccount:a@p.iam.gserviceaccount.com", "group:robots"}},
+					{Role: "roles/editor", Members: []string{"serviceAccount:b@p.iam.gserviceaccount.com"}},
+				},
+			},
+		},
+	}
+
+	got := ServiceAccounts(policy)
+	want := []string{"a@p.iam.gserviceaccount.com", "b@p.iam.gserviceaccount.com"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("ServiceAccounts() = %v, want %v", got, want)
+	}
+}
+
+func TestEvaluateImpersonation(t *testing.T) {
+	policy, err := Load("../../testdata/impersonation.yaml")
+	if err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	if result := Validate(policy); !result.Valid {
+		t.Fatalf("Expected valid policy, got %v", result.Errors)
+	}
+
+	const (
+		ci       = "serviceAccount:ci@test-project.iam.gserviceaccount.com"
+		deployer = "deployer@test-project.iam.gserviceaccount.com"
+		builder  = "builder@test-project.iam.gserviceaccount.com"
+		secret   = "projects/test-project/secrets/db-password"
+	)
+
+	tests := []struct {
+		name       string
+		principal  string
+		delegates  []string
+		resource   string
+		permission string
+		want       bool
+		steps      int
+	}{
+		{"token creator on service account", ci, nil, "projects/-/serviceAccounts/" + deployer, ImpersonationPermission, true, 0},
+		{"binding is scoped to its service account", ci, nil, "projects/test-project/serviceAccounts/" + builder, ImpersonationPermission, false, 0},
+		{"caller without the grant", ci, nil, secret, "secretmanager.versions.access", false, 0},
+		{"impersonating the grantee", ci, []string{deployer}, secret, "secretmanager.versions.access", true, 1},
+		{"delegate chain", "user:alice@example.com", []string{builder, "serviceAccount:" + deployer}, secret, "secretmanager.versions.access", true, 2},
+		{"broken chain", "user:alice@example.com", []string{deployer}, secret, "secretmanager.versions.access", false, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := Evaluate(policy, Request{
+				Principal:  tt.principal,
+				Resource:   tt.resource,
+				Permission: tt.permission,
+				Delegates:  tt.delegates,
+			})
+			if decision.Allowed != tt.want {
+				t.Errorf("Allowed = %v, want %v (%s)", decision.Allowed, tt.want, decision.Reason)
+			}
+			if len(decision.Delegation) != tt.steps {
+				t.Errorf("Expected %d delegation steps, got %d", tt.steps, len(decision.Delegation))
+			}
+		})
+	}
+
+	decision := Evaluate(policy, Request{Principal: ci, Resource: "projects/-/serviceAccounts/" + deployer, Permission: ImpersonationPermission})
+	if got := decision.Grant.Ref().Label(); got != "projects/test-project/serviceAccounts/"+deployer+"[0]" {
+		t.Errorf("Grant label = %s", got)
+	}
+
+	decision = Evaluate(policy, Request{Principal: "user:alice@example.com", Resource: secret, Permission: "secretmanager.versions.access", Delegates: []string{deployer}})
+	if !strings.Contains(decision.Reason, "cannot impersonate "+deployer) || len(decision.Checked) != 0 {
+		t.Errorf("Expected the request to stop at the denied step, got %q with %d checked bindings", decision.Reason, len(decision.Checked))
+	}
+}
+
+func TestValidateServiceAccounts(t *testing.T) {
+	policy := &Policy{
+		Projects: map[string]Project{
+			"p": {
+				Bindings: []Binding{{Role: "roles/viewer", Members: []string{"user:alice@example.com"}}},
+				ServiceAccounts: map[string]ServiceAccount{
+					"sa@other.iam.gserviceaccount.com": {
+						Bindings: []Binding{{Role: "roles/iam.serviceAccountTokenCreator", Members: []string{"user:alice@example.com"}}},
+					},
+				},
+			},
+		},
+	}
+
+	result := Validate(policy)
+	if result.Valid || !strings.Contains(strings.Join(result.Errors, "\n"), "belongs to project other") {
+		t.Errorf("Expected a project mismatch error, got %v", result.Errors)
+	}
+}
+
+func TestEvaluateInheritance(t *testing.T) {
+	policy, err := Load("../../testdata/hierarchy.yaml")
+	if err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	if result := Validate(policy); !result.Valid {
+		t.Fatalf("Expected valid policy, got %v", result.Errors)
+	}
+
+	const secret = "projects/test-project/secrets/db-password"
+
+	tests := []struct {
+		name       string
+		principal  string
+		resource   string
+		permission string
+		want       bool
+		from       string
+	}{
+		{"direct binding", "user:alice@example.com", secret, "secretmanager.secrets.delete", true, ""},
+		{"inherited from grandparent folder", "user:bob@example.com", secret, "secretmanager.versions.access", true, "folders/engineering"},
+		{"inherited from organization", "user:carol@example.com", secret, "secretmanager.secrets.get", true, "organizations/123456789"},
+		{"folder grant limited to its role", "user:bob@example.com", secret, "secretmanager.secrets.delete", false, ""},
+		{"folder resource", "user:bob@example.com", "folders/engineering", "secretmanager.secrets.get", true, "folders/engineering"},
+		{"not inherited downwards", "user:alice@example.com", "folders/team-a", "secretmanager.secrets.get", false, ""},
+		{"undefined folder", "user:bob@example.com", "folders/unknown", "secretmanager.secrets.get", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := Evaluate(policy, Request{Principal: tt.principal, Resource: tt.resource, Permission: tt.permission})
+			if decision.Allowed != tt.want {
+				t.Fatalf("Allowed = %v, want %v (%s)", decision.Allowed, tt.want, decision.Reason)
+			}
+			if tt.want && decision.Grant.Resource != tt.from {
+				t.Errorf("Grant resource = %q, want %q", decision.Grant.Resource, tt.from)
+			}
+		})
+	}
+}
+
+func TestValidateHierarchy(t *testing.T) {
+	binding := []Binding{{Role: "roles/viewer", Members: []string{"user:alice@example.com"}}}
+
+	tests := []struct {
+		name    string
+		folders map[string]Folder
+		parent  string
+		want    string
+	}{
+		{"undefined folder", nil, "folders/missing", "undefined folder: folders/missing"},
+		{"invalid parent", nil, "projects/other", "invalid parent projects/other"},
+		{"folder without parent", map[string]Folder{"f": {}}, "folders/f", "Folder f: no parent specified"},
+		{"cycle", map[string]Folder{"a": {Parent: "folders/b"}, "b": {Parent: "folders/a"}}, "folders/a", "cycle in resource hierarchy"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := &Policy{
+				Folders:  tt.folders,
+				Projects: map[string]Project{"p": {Parent: tt.parent, Bindings: binding}},
+			}
+			result := Validate(policy)
+			if result.Valid || !strings.Contains(strings.Join(result.Errors, "\n"), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, result.Errors)
+			}
+		})
+	}
+}
+
+func TestEvaluateResourceBindings(t *testing.T) {
+	policy, err := Load("../../testdata/resources.yaml")
+	if err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	if result := Validate(policy); !result.Valid {
+		t.Fatalf("Expected valid policy, got %v", result.Errors)
+	}
+
+	const (
+		app     = "serviceAccount:app@test-project.iam.gserviceaccount.com"
+		keyRing = "projects/test-project/locations/global/keyRings/app"
+		signing = keyRing + "/cryptoKeys/signing"
+	)
+
+	tests := []struct {
+		name       string
+		principal  string
+		resource   string
+		permission string
+		want       bool
+		from       string
+	}{
+		{"secret binding", app, "projects/test-project/secrets/db-password", "secretmanager.versions.access", true, "projects/test-project/secrets/db-password"},
+		{"secret binding covers versions", app, "projects/test-project/secrets/db-password/versions/latest", "secretmanager.versions.access", true, "projects/test-project/secrets/db-password"},
+		{"secret binding scoped to its secret", app, "projects/test-project/secrets/api-key", "secretmanager.versions.access", false, ""},
+		{"no prefix match on sibling names", app, "projects/test-project/secrets/db-password-old", "secretmanager.versions.access", false, ""},
+		{"key ring binding covers its keys", app, signing, "cloudkms.cryptoKeys.encrypt", true, keyRing},
+		{"crypto key binding", "user:bob@example.com", signing, "cloudkms.cryptoKeys.get", true, signing},
+		{"crypto key binding not on key ring", "user:bob@example.com", keyRing, "cloudkms.keyRings.get", false, ""},
+		{"project binding still applies", "user:alice@example.com", signing, "cloudkms.cryptoKeys.get", true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := Evaluate(policy, Request{Principal: tt.principal, Resource: tt.resource, Permission: tt.permission})
+			if decision.Allowed != tt.want {
+				t.Fatalf("Allowed = %v, want %v (%s)", decision.Allowed, tt.want, decision.Reason)
+			}
+			if tt.want && decision.Grant.Resource != tt.from {
+				t.Errorf("Grant resource = %q, want %q", decision.Grant.Resource, tt.from)
+			}
+		})
+	}
+}
+
+func TestValidateResources(t *testing.T) {
+	binding := []Binding{{Role: "roles/viewer", Members: []string{"user:alice@example.com"}}}
+
+	tests := []struct {
+		name     string
+		resource string
+		want     string
+	}{
+		{"project is not a resource", "projects/p", "invalid resource name projects/p"},
+		{"secret version", "projects/p/secrets/s/versions/1", "invalid resource name"},
+		{"key ring without location", "projects/p/keyRings/r", "invalid resource name"},
+		{"undefined project", "projects/other/secrets/s", "undefined project other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := &Policy{
+				Projects:  map[string]Project{"p": {Bindings: binding}},
+				Resources: map[string]Resource{tt.resource: {Bindings: binding}},
+			}
+			result := Validate(policy)
+			if result.Valid || !strings.Contains(strings.Join(result.Errors, "\n"), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, result.Errors)
+			}
+		})
+	}
+}
+
+func TestEvaluateDenyPolicies(t *testing.T) {
+	policy, err := Load("../../testdata/deny.yaml")
+	if err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	if result := Validate(policy); !result.Valid {
+		t.Fatalf("Expected valid policy, got %v", result.Errors)
+	}
+
+	const (
+		secret  = "projects/test-project/secrets/db-password"
+		version = secret + "/versions/1"
+		mallory = "user:mallory@contractor.example.com"
+	)
+
+	tests := []struct {
+		name       string
+		principal  string
+		resource   string
+		permission string
+		want       bool
+		rule       string
+	}{
+		{"deny overrides inherited owner", "user:alice@example.com", version, "secretmanager.versions.destroy", false, "protect-secret-versions[0]"},
+		{"exception principal", "user:oncall@example.com", version, "secretmanager.versions.destroy", true, ""},
+		{"permission not denied", "user:alice@example.com", version, "secretmanager.versions.disable", true, ""},
+		{"deny with condition", mallory, secret, "secretmanager.versions.access", false, "no-contractor-secrets[0]"},
+		{"condition not met", mallory, "projects/test-project/locations/global/keyRings/app", "cloudkms.keyRings.get", true, ""},
+		{"deny applies at the attachment point itself", "user:bob@example.com", "organizations/123456789", "secretmanager.versions.destroy", false, "protect-secret-versions[0]"},
+		{"project deny not applied above the project", mallory, "organizations/123456789", "secretmanager.secrets.get", true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := Evaluate(policy, Request{Principal: tt.principal, Resource: tt.resource, Permission: tt.permission})
+			if decision.Allowed != tt.want {
+				t.Fatalf("Allowed = %v, want %v (%s)", decision.Allowed, tt.want, decision.Reason)
+			}
+			rule := ""
+			if decision.Denial != nil {
+				rule = decision.Denial.Label()
+			}
+			if rule != tt.rule {
+				t.Errorf("Deny rule = %q, want %q", rule, tt.rule)
+			}
+		})
+	}
+
+	decision := Evaluate(policy, Request{Principal: "user:alice@example.com", Resource: version, Permission: "secretmanager.versions.destroy"})
+	if len(decision.Checked) != 0 || !strings.Contains(decision.Reason, "deny policy protect-secret-versions[0]") {
+		t.Errorf("Expected the deny rule to stop evaluation, got %q with %d checked bindings", decision.Reason, len(decision.Checked))
+	}
+}
+
+func TestValidateDenyPolicies(t *testing.T) {
+	binding := []Binding{{Role: "roles/viewer", Members: []string{"user:alice@example.com"}}}
+
+	tests := []struct {
+		name string
+		deny DenyPolicy
+		want string
+	}{
+		{"missing attachment point", DenyPolicy{Rules: []DenyRule{{DeniedPrincipals: []string{"allUsers"}, DeniedPermissions: []string{"secretmanager.secrets.get"}}}}, "no attachment point"},
+		{"undefined project", DenyPolicy{AttachmentPoint: "projects/other"}, "undefined project other"},
+		{"undefined folder", DenyPolicy{AttachmentPoint: "folders/f"}, "undefined attachment point folders/f"},
+		{"no denied principals", DenyPolicy{AttachmentPoint: "projects/p", Rules: []DenyRule{{DeniedPermissions: []string{"secretmanager.secrets.get"}}}}, "no denied principals"},
+		{"invalid permission", DenyPolicy{AttachmentPoint: "projects/p", Rules: []DenyRule{{DeniedPrincipals: []string{"allUsers"}, DeniedPermissions: []string{"storage.objects.get"}}}}, "unknown service"},
+		{"undefined exception group", DenyPolicy{AttachmentPoint: "projects/p", Rules: []DenyRule{{DeniedPrincipals: []string{"allUsers"}, ExceptionPrincipals: []string{"group:missing"}, DeniedPermissions: []string{"secretmanager.secrets.get"}}}}, "undefined group: missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := &Policy{
+				Projects:     map[string]Project{"p": {Bindings: binding}},
+				DenyPolicies: map[string]DenyPolicy{"guardrail": tt.deny},
+			}
+			result := Validate(policy)
+			if result.Valid || !strings.Contains(strings.Join(result.Errors, "\n"), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, result.Errors)
+			}
+		})
+	}
+}
+
+func TestEvaluateBoundaries(t *testing.T) {
+	policy, err := Load("../../testdata/boundaries.yaml")
+	if err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	if result := Validate(policy); !result.Valid {
+		t.Fatalf("Expected valid policy, got %v", result.Errors)
+	}
+
+	const (
+		ci     = "serviceAccount:ci@test-project.iam.gserviceaccount.com"
+		secret = "projects/test-project/secrets/db-password"
+	)
+
+	tests := []struct {
+		name       string
+		principal  string
+		permission string
+		want       bool
+		bounded    bool
+	}{
+		{"within boundary role", ci, "secretmanager.versions.access", true, true},
+		{"within second boundary", ci, "cloudkms.cryptoKeys.encrypt", true, true},
+		{"granted but outside boundaries", ci, "secretmanager.versions.destroy", false, true},
+		{"outside boundaries and not granted", ci, "secretmanager.secrets.delete", false, true},
+		{"unbounded principal", "user:alice@example.com", "secretmanager.versions.destroy", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := Evaluate(policy, Request{Principal: tt.principal, Resource: secret, Permission: tt.permission})
+			if decision.Allowed != tt.want {
+				t.Fatalf("Allowed = %v, want %v (%s)", decision.Allowed, tt.want, decision.Reason)
+			}
+			if (decision.Boundary != nil) != tt.bounded {
+				t.Errorf("Boundary = %+v, want bounded %v", decision.Boundary, tt.bounded)
+			}
+		})
+	}
+
+	decision := Evaluate(policy, Request{Principal: ci, Resource: secret, Permission: "secretmanager.versions.destroy"})
+	if decision.Grant != nil || !strings.Contains(decision.Reason, "outside the access boundary (ci-encrypt, ci-secrets)") {
+		t.Errorf("Expected the grant to be capped by both boundaries, got %q", decision.Reason)
+	}
+}
+
+func TestValidateBoundaries(t *testing.T) {
+	tests := []struct {
+		name     string
+		boundary AccessBoundary
+		want     string
+	}{
+		{"no principals", AccessBoundary{Permissions: []string{"secretmanager.secrets.get"}}, "no principals specified"},
+		{"invalid permission", AccessBoundary{Principals: []string{"user:alice@example.com"}, Permissions: []string{"secrets.get"}}, "invalid permission format"},
+		{"undefined role", AccessBoundary{Principals: []string{"user:alice@example.com"}, Roles: []string{"roles/custom.missing"}}, "undefined role roles/custom.missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := &Policy{
+				Projects:   map[string]Project{"p": {Bindings: []Binding{{Role: "roles/viewer", Members: []string{"user:alice@example.com"}}}}},
+				Boundaries: map[string]AccessBoundary{"ci": tt.boundary},
+			}
+			result := Validate(policy)
+			if result.Valid || !strings.Contains(strings.Join(result.Errors, "\n"), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, result.Errors)
+			}
+		})
+	}
+}
+
+func TestEvaluateConditionAttributes(t *testing.T) {
+	policy, err := Load("../../testdata/conditions.yaml")
+	if err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	if result := Validate(policy); !result.Valid {
+		t.Fatalf("Expected valid policy, got %v", result.Errors)
+	}
+
+	const (
+		secret  = "projects/test-project/secrets/db-password"
+		keyRing = "projects/test-project/locations/global/keyRings/app"
+	)
+	var (
+		// A Wednesday, 10:00 in New York
+		before = time.Date(2026, 12, 30, 15, 0, 0, 0, time.UTC)
+		after  = time.Date(2027, 1, 2, 15, 0, 0, 0, time.UTC)
+		night  = time.Date(2026, 12, 30, 3, 0, 0, 0, time.UTC)
+	)
+	grants := func(roles ...string) map[string]any {
+		list := make([]any, len(roles))
+		for i, role := range roles {
+			list[i] = role
+		}
+		return map[string]any{"iam.googleapis.com/modifiedGrantsByRole": list}
+	}
+
+	tests := []struct {
+		name       string
+		principal  string
+		resource   string
+		permission string
+		time       time.Time
+		api        map[string]any
+		want       bool
+	}{
+		{"temporary grant before expiry", "user:oncall@example.com", secret, "secretmanager.versions.access", before, nil, true},
+		{"temporary grant after expiry", "user:oncall@example.com", secret, "secretmanager.versions.access", after, nil, false},
+		{"business hours", "user:alice@example.com", secret, "secretmanager.versions.access", before, nil, true},
+		{"outside business hours", "user:alice@example.com", secret, "secretmanager.versions.access", night, nil, false},
+		{"weekend", "user:alice@example.com", secret, "secretmanager.versions.access", after, nil, false},
+		{"resource type matches", "user:bob@example.com", keyRing + "/cryptoKeys/signing", "cloudkms.cryptoKeys.get", before, nil, true},
+		{"resource type differs", "user:bob@example.com", keyRing, "cloudkms.keyRings.get", before, nil, false},
+		{"granting allowed role", "user:admin@example.com", secret, "secretmanager.secrets.setIamPolicy", before, grants("roles/secretmanager.secretAccessor"), true},
+		{"granting other role", "user:admin@example.com", secret, "secretmanager.secrets.setIamPolicy", before, grants("roles/secretmanager.secretAccessor", "roles/owner"), false},
+		{"no modified grants", "user:admin@example.com", secret, "secretmanager.secrets.getIamPolicy", before, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := Evaluate(policy, Request{
+				Principal:     tt.principal,
+				Resource:      tt.resource,
+				Permission:    tt.permission,
+				Time:          tt.time,
+				APIAttributes: tt.api,
+			})
+			if decision.Allowed != tt.want {
+				t.Errorf("Allowed = %v, want %v (%s)", decision.Allowed, tt.want, decision.Reason)
+			}
+		})
+	}
+}
+
+func TestResourceType(t *testing.T) {
+	tests := []struct {
+		name    string
+		service string
+		kind    string
+	}{
+		{"projects/p", "cloudresourcemanager.googleapis.com", "cloudresourcemanager.googleapis.com/Project"},
+		{"folders/123", "cloudresourcemanager.googleapis.com", "cloudresourcemanager.googleapis.com/Folder"},
+		{"projects/p/secrets/s", "secretmanager.googleapis.com", "secretmanager.googleapis.com/Secret"},
+		{"projects/p/secrets/s/versions/1", "secretmanager.googleapis.com", "secretmanager.googleapis.com/SecretVersion"},
+		{"projects/p/locations/global/keyRings/r/cryptoKeys/k", "cloudkms.googleapis.com", "cloudkms.googleapis.com/CryptoKey"},
+		{"projects/p/serviceAccounts/sa@p.iam.gserviceaccount.com", "iam.googleapis.com", "iam.googleapis.com/ServiceAccount"},
+		{"projects/p/unknown/x", "", ""},
+	}
+	for _, tt := range tests {
+		service, kind := ResourceType(tt.name)
+		if service != tt.service || kind != tt.kind {
+			t.Errorf("ResourceType(%q) = %q, %q, want %q, %q", tt.name, service, kind, tt.service, tt.kind)
+		}
+	}
+}
+
+func TestMatchPermission(t *testing.T) {
+	tests := []struct {
+		pattern, permission string
+		want                bool
+	}{
+		{"secretmanager.secrets.get", "secretmanager.secrets.get", true},
+		{"secretmanager.secrets.get", "secretmanager.secrets.getIamPolicy", false},
+		{"secretmanager.*", "secretmanager.versions.access", true},
+		{"*.versions.access", "secretmanager.versions.access", true},
+		{"cloudkms.*", "secretmanager.versions.access", false},
+		{"[", "[", true},
+		{"[*", "[x", false},
+	}
+	for _, tt := range tests {
+		if got := MatchPermission(tt.pattern, tt.permission); got != tt.want {
+			t.Errorf("MatchPermission(%q, %q) = %v, want %v", tt.pattern, tt.permission, got, tt.want)
+		}
+	}
+}
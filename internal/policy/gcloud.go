@@ -0,0 +1,311 @@
+package policy
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// IAMPolicy is an IAM policy as exported by gcloud get-iam-policy
+// --format=json (or YAML), for projects, folders, organizations and
+// resources alike
+type IAMPolicy struct {
+	Bindings []Binding `yaml:"bindings" json:"bindings"`
+	Etag     string    `yaml:"etag,omitempty" json:"etag,omitempty"`
+	Version  int       `yaml:"version,omitempty" json:"version,omitempty"`
+	// AuditConfigs are not imported; they are only detected to report them
+	AuditConfigs []any `yaml:"auditConfigs,omitempty" json:"auditConfigs,omitempty"`
+}
+
+// CustomRole is a custom role as exported by gcloud iam roles describe
+// --format=json (or YAML)
+type CustomRole struct {
+	// Name is projects/{project}/roles/{id} or
+	// organizations/{org}/roles/{id}
+	Name                string   `yaml:"name" json:"name"`
+	Title               string   `yaml:"title,omitempty" json:"title,omitempty"`
+	Description         string   `yaml:"description,omitempty" json:"description,omitempty"`
+	IncludedPermissions []string `yaml:"includedPermissions" json:"includedPermissions"`
+	Stage               string   `yaml:"stage,omitempty" json:"stage,omitempty"`
+	Deleted             bool     `yaml:"deleted,omitempty" json:"deleted,omitempty"`
+}
+
+// Importer builds a policy from production IAM exports, recording what
+// could not be translated as warnings
+type Importer struct {
+	Policy   *Policy
+	Warnings []string
+
+	// roleSources remembers which export defined each custom role, to
+	// report conflicting definitions
+	roleSources map[string]string
+}
+
+// NewImporter returns an importer with an empty policy
+func NewImporter() *Importer {
+	return &Importer{
+		Policy: &Policy{
+			Roles:    map[string]Role{},
+			Groups:   map[string]Group{},
+			Projects: map[string]Project{},
+		},
+		roleSources: make(map[string]string),
+	}
+}
+
+func (im *Importer) warn(source, format string, args ...any) {
+	im.Warnings = append(im.Warnings, source+": "+fmt.Sprintf(format, args...))
+}
+
+// ImportRoles adds the custom roles in data, a gcloud iam roles describe
+// export or a list of them. source names the export in warnings.
+func (im *Importer) ImportRoles(source string, data []byte) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("%s: failed to parse role: %w", source, err)
+	}
+	if doc.Kind == 0 {
+		return fmt.Errorf("%s: empty role export", source)
+	}
+
+	var roles []CustomRole
+	if doc.Content[0].Kind == yaml.SequenceNode {
+		if err := doc.Decode(&roles); err != nil {
+			return fmt.Errorf("%s: failed to parse roles: %w", source, err)
+		}
+	} else {
+		var role CustomRole
+		if err := doc.Decode(&role); err != nil {
+			return fmt.Errorf("%s: failed to parse role: %w", source, err)
+		}
+		roles = append(roles, role)
+	}
+
+	for _, role := range roles {
+		if err := im.AddRole(source, role); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddRole adds a custom role under its emulator name (see
+// ImportedRoleName). Permissions of services the emulator does not cover
+// are dropped; disabled and deleted roles are added without permissions,
+// as they grant nothing in GCP.
+func (im *Importer) AddRole(source string, role CustomRole) error {
+	name, ok := ImportedRoleName(role.Name)
+	if !ok || !strings.HasPrefix(name, "roles/custom.") {
+		return fmt.Errorf("%s: invalid custom role name %q (expected projects/{project}/roles/{id} or organizations/{org}/roles/{id})", source, role.Name)
+	}
+
+	var perms, dropped []string
+	for _, perm := range role.IncludedPermissions {
+		if validatePermission(perm) != nil {
+			dropped = append(dropped, perm)
+			continue
+		}
+		perms = append(perms, perm)
+	}
+	if len(dropped) > 0 {
+		im.warn(source, "role %s: dropped %d permissions of services the emulator does not cover (%s)", role.Name, len(dropped), summarize(dropped, 3))
+	}
+	if role.Stage == "DISABLED" || role.Deleted {
+		im.warn(source, "role %s is disabled or deleted; imported without permissions", role.Name)
+		perms = nil
+	}
+
+	if prev, ok := im.roleSources[name]; ok && !slices.Equal(im.Policy.Roles[name].Permissions, perms) {
+		im.warn(source, "role %s conflicts with the role of the same ID from %s; keeping this one", role.Name, prev)
+	}
+	im.roleSources[name] = source
+	im.Policy.Roles[name] = Role{Permissions: perms}
+	return nil
+}
+
+// ImportIAMPolicy adds the bindings in data, a get-iam-policy export, to
+// target: a project ID, or the canonical name of a project, folder,
+// organization, service account (projects/p/serviceAccounts/email) or
+// resource (projects/p/secrets/s). A project keeps the policy's etag and
+// version.
+func (im *Importer) ImportIAMPolicy(source, target string, data []byte) error {
+	var iamPolicy IAMPolicy
+	if err := yaml.Unmarshal(data, &iamPolicy); err != nil {
+		return fmt.Errorf("%s: failed to parse IAM policy: %w", source, err)
+	}
+
+	if len(iamPolicy.AuditConfigs) > 0 {
+		im.warn(source, "audit configs are not imported")
+	}
+	if iamPolicy.Version == 1 {
+		for _, b := range iamPolicy.Bindings {
+			if b.Condition != nil {
+				im.warn(source, "policy version 1 has conditional bindings; GCP requires version 3")
+				break
+			}
+		}
+	}
+
+	var bindings []Binding
+	for _, b := range iamPolicy.Bindings {
+		if binding, ok := im.translateBinding(source, b); ok {
+			bindings = append(bindings, binding)
+		}
+	}
+
+	return im.addBindings(source, target, bindings, iamPolicy.Etag, iamPolicy.Version)
+}
+
+// translateBinding maps a production binding's role to its emulator name
+// and drops members the emulator cannot represent. It reports false if no
+// members are left.
+func (im *Importer) translateBinding(source string, b Binding) (Binding, bool) {
+	role, ok := ImportedRoleName(b.Role)
+	if !ok {
+		im.warn(source, "binding on %s: invalid role name; skipped", b.Role)
+		return Binding{}, false
+	}
+	if !strings.HasPrefix(role, "roles/custom.") {
+		if _, ok := builtinRoles[role]; !ok {
+			im.warn(source, "role %s is not a built-in role of the emulator; its binding grants nothing until the role is defined", role)
+		}
+	}
+
+	out := Binding{Role: role, Condition: b.Condition}
+	for _, member := range b.Members {
+		p, err := ParsePrincipal(member)
+		if err != nil {
+			im.warn(source, "binding on %s: member %s skipped: %v", b.Role, member, err)
+			continue
+		}
+		if p.Type == "group" {
+			if _, ok := im.Policy.Groups[p.ID]; !ok {
+				im.Policy.Groups[p.ID] = Group{}
+			}
+		}
+		out.Members = append(out.Members, member)
+	}
+
+	if len(out.Members) == 0 {
+		im.warn(source, "binding on %s has no members the emulator supports; skipped", b.Role)
+		return Binding{}, false
+	}
+	return out, true
+}
+
+// addBindings appends bindings to target, creating it if needed
+func (im *Importer) addBindings(source, target string, bindings []Binding, etag string, version int) error {
+	pol := im.Policy
+	kind, id, _ := strings.Cut(target, "/")
+	parts := strings.Split(target, "/")
+
+	switch {
+	case !strings.Contains(target, "/") || (kind == "projects" && !strings.Contains(id, "/")):
+		if kind == "projects" {
+			target = id
+		}
+		project := pol.Projects[target]
+		project.Bindings = append(project.Bindings, bindings...)
+		if etag != "" {
+			project.Etag = etag
+		}
+		if version != 0 {
+			project.Version = version
+		}
+		pol.Projects[target] = project
+	case kind == "organizations" && !strings.Contains(id, "/"):
+		if pol.Organizations == nil {
+			pol.Organizations = map[string]Organization{}
+		}
+		org := pol.Organizations[id]
+		org.Bindings = append(org.Bindings, bindings...)
+		pol.Organizations[id] = org
+	case kind == "folders" && !strings.Contains(id, "/"):
+		if pol.Folders == nil {
+			pol.Folders = map[string]Folder{}
+		}
+		folder := pol.Folders[id]
+		folder.Bindings = append(folder.Bindings, bindings...)
+		pol.Folders[id] = folder
+	case kind == "projects" && len(parts) == 4 && parts[2] == "serviceAccounts" && parts[3] != "":
+		project := pol.Projects[parts[1]]
+		if project.ServiceAccounts == nil {
+			project.ServiceAccounts = map[string]ServiceAccount{}
+		}
+		account := project.ServiceAccounts[parts[3]]
+		account.Bindings = append(account.Bindings, bindings...)
+		project.ServiceAccounts[parts[3]] = account
+		pol.Projects[parts[1]] = project
+	default:
+		if err := validateResourceName(target); err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+		if pol.Resources == nil {
+			pol.Resources = map[string]Resource{}
+		}
+		resource := pol.Resources[target]
+		resource.Bindings = append(resource.Bindings, bindings...)
+		pol.Resources[target] = resource
+		if projectID := ProjectFromResource(target); !hasProject(pol, projectID) {
+			pol.Projects[projectID] = Project{}
+		}
+	}
+	return nil
+}
+
+// Finish reports what the imported policy still lacks: groups without
+// members, custom roles that are bound but were not imported, and folders
+// without a parent
+func (im *Importer) Finish() *Policy {
+	for _, name := range sortedNames(im.Policy.Groups) {
+		if len(im.Policy.Groups[name].Members) == 0 {
+			im.warn("policy", "group %s has no members; add them to grant its bindings", name)
+		}
+	}
+
+	missing := make(map[string]bool)
+	for _, ref := range AllBindings(im.Policy) {
+		if strings.HasPrefix(ref.Binding.Role, "roles/custom.") {
+			if _, ok := im.Policy.Roles[ref.Binding.Role]; !ok {
+				missing[ref.Binding.Role] = true
+			}
+		}
+	}
+	for _, role := range sortedNames(missing) {
+		im.warn("policy", "role %s is bound but was not imported; import its gcloud iam roles describe export", role)
+	}
+
+	for _, id := range sortedNames(im.Policy.Folders) {
+		if im.Policy.Folders[id].Parent == "" {
+			im.warn("policy", "folder %s has no parent; set it to place the folder in the hierarchy", id)
+		}
+	}
+
+	return im.Policy
+}
+
+// ImportedRoleName returns the emulator name of a production role:
+// predefined roles keep their name and custom roles
+// (projects/{project}/roles/{id}, organizations/{org}/roles/{id}) become
+// roles/custom.{id}. It reports false for anything else.
+func ImportedRoleName(role string) (string, bool) {
+	if strings.HasPrefix(role, "roles/") {
+		return role, true
+	}
+
+	parts := strings.Split(role, "/")
+	if len(parts) == 4 && (parts[0] == "projects" || parts[0] == "organizations") && parts[1] != "" && parts[2] == "roles" && parts[3] != "" {
+		return "roles/custom." + parts[3], true
+	}
+	return "", false
+}
+
+// summarize lists the first n items, noting how many more there are
+func summarize(items []string, n int) string {
+	if len(items) <= n {
+		return strings.Join(items, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(items[:n], ", "), len(items)-n)
+}
@@ -0,0 +1,127 @@
+package policy
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func importFile(t *testing.T, im *Importer, target, path string) {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target == "" {
+		err = im.ImportRoles(path, data)
+	} else {
+		err = im.ImportIAMPolicy(path, target, data)
+	}
+	if err != nil {
+		t.Fatalf("import %s: %v", path, err)
+	}
+}
+
+func TestImporter(t *testing.T) {
+	im := NewImporter()
+	importFile(t, im, "", "../../testdata/gcloud/app-deployer-role.json")
+	importFile(t, im, "prod-project", "../../testdata/gcloud/prod-iam-policy.json")
+	importFile(t, im, "projects/prod-project/secrets/db-password", "../../testdata/gcloud/db-password-iam-policy.json")
+	pol := im.Finish()
+
+	if result := Validate(pol); !result.Valid {
+		t.Fatalf("Expected valid policy, got %v", result.Errors)
+	}
+
+	role, ok := pol.Roles["roles/custom.appDeployer"]
+	if !ok || strings.Join(role.Permissions, ",") != "cloudkms.cryptoKeys.encrypt,secretmanager.versions.access" {
+		t.Errorf("roles/custom.appDeployer = %+v, want the emulated permissions only", role)
+	}
+
+	project := pol.Projects["prod-project"]
+	if project.Etag != "BwYRbPL7uMc=" || project.Version != 3 {
+		t.Errorf("etag, version = %q, %d", project.Etag, project.Version)
+	}
+	if len(project.Bindings) != 4 {
+		t.Fatalf("got %d project bindings, want 4 (the projectOwner-only binding skipped)", len(project.Bindings))
+	}
+	if got := project.Bindings[2].Role; got != "roles/custom.appDeployer" {
+		t.Errorf("custom role binding = %s, want roles/custom.appDeployer", got)
+	}
+	if c := project.Bindings[1].Condition; c == nil || c.Title != "Expires 2026-12-31" {
+		t.Errorf("condition = %+v", c)
+	}
+	if _, ok := pol.Groups["platform-admins@example.com"]; !ok {
+		t.Error("referenced group not created")
+	}
+	if len(pol.Resources["projects/prod-project/secrets/db-password"].Bindings) != 1 {
+		t.Error("resource bindings not imported")
+	}
+
+	warnings := strings.Join(im.Warnings, "\n")
+	for _, want := range []string{
+		"dropped 2 permissions",
+		"audit configs are not imported",
+		"roles/logging.serviceAgent is not a built-in role",
+		"member projectOwner:prod-project skipped",
+		"group platform-admins@example.com has no members",
+	} {
+		if !strings.Contains(warnings, want) {
+			t.Errorf("warnings missing %q:\n%s", want, warnings)
+		}
+	}
+
+	decision := Evaluate(pol, Request{
+		Principal:  "serviceAccount:deployer@prod-project.iam.gserviceaccount.com",
+		Resource:   "projects/prod-project/secrets/api-key",
+		Permission: "secretmanager.versions.access",
+	})
+	if !decision.Allowed {
+		t.Errorf("imported custom role binding denied: %s", decision.Reason)
+	}
+}
+
+func TestImporterErrors(t *testing.T) {
+	im := NewImporter()
+	if err := im.ImportRoles("role.json", []byte(`{"name": "roles/viewer"}`)); err == nil || !strings.Contains(err.Error(), "invalid custom role name") {
+		t.Errorf("predefined role export: error = %v", err)
+	}
+	if err := im.ImportIAMPolicy("p.json", "projects/p/buckets/b", []byte(`{"bindings": []}`)); err == nil || !strings.Contains(err.Error(), "invalid resource name") {
+		t.Errorf("unsupported target: error = %v", err)
+	}
+
+	roles := `[{"name": "projects/p/roles/a", "includedPermissions": ["iam.serviceAccounts.get"]},
+	           {"name": "organizations/1/roles/a", "includedPermissions": ["iam.serviceAccounts.list"], "stage": "DISABLED"}]`
+	if err := im.ImportRoles("roles.json", []byte(roles)); err != nil {
+		t.Fatalf("ImportRoles() error = %v", err)
+	}
+	warnings := strings.Join(im.Warnings, "\n")
+	if !strings.Contains(warnings, "disabled or deleted") || !strings.Contains(warnings, "conflicts with the role of the same ID") {
+		t.Errorf("warnings = %s", warnings)
+	}
+
+	if err := im.ImportIAMPolicy("p.json", "p", []byte(`{"bindings": [{"role": "projects/p/roles/missing", "members": ["user:a@example.com"]}]}`)); err != nil {
+		t.Fatalf("ImportIAMPolicy() error = %v", err)
+	}
+	im.Finish()
+	if !strings.Contains(strings.Join(im.Warnings, "\n"), "roles/custom.missing is bound but was not imported") {
+		t.Errorf("missing role not reported: %v", im.Warnings)
+	}
+}
+
+func TestImportedRoleName(t *testing.T) {
+	tests := map[string]string{
+		"roles/viewer":                        "roles/viewer",
+		"projects/my-prod/roles/appDeployer":  "roles/custom.appDeployer",
+		"organizations/123/roles/auditReader": "roles/custom.auditReader",
+		"projects/my-prod/roles/":             "",
+		"folders/1/roles/x":                   "",
+	}
+	for role, want := range tests {
+		got, ok := ImportedRoleName(role)
+		if got != want || ok != (want != "") {
+			t.Errorf("ImportedRoleName(%q) = %q, %v, want %q", role, got, ok, want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Ancestors returns the resource names above parent, starting with parent
+// itself and ending at the organization (or the first node without a
+// parent). It fails on a parent that is not in the policy, or on a cycle.
+func Ancestors(policy *Policy, parent string) ([]string, error) {
+	var chain []string
+	seen := make(map[string]bool)
+
+	for parent != "" {
+		if seen[parent] {
+			return chain, fmt.Errorf("cycle in resource hierarchy at %s", parent)
+		}
+		seen[parent] = true
+		chain = append(chain, parent)
+
+		kind, id, _ := strings.Cut(parent, "/")
+		switch kind {
+		case "organizations":
+			if _, ok := policy.Organizations[id]; !ok {
+				return chain, fmt.Errorf("undefined organization: %s", parent)
+			}
+			return chain, nil
+		case "folders":
+			folder, ok := policy.Folders[id]
+			if !ok {
+				return chain, fmt.Errorf("undefined folder: %s", parent)
+			}
+			parent = folder.Parent
+		default:
+			return chain, fmt.Errorf("invalid parent %s (expected organizations/{id} or folders/{id})", parent)
+		}
+	}
+
+	return chain, nil
+}
+
+// nodeBindings returns the bindings attached to a folder or organization
+func nodeBindings(policy *Policy, name string) []Binding {
+	kind, id, _ := strings.Cut(name, "/")
+	switch kind {
+	case "organizations":
+		return policy.Organizations[id].Bindings
+	case "folders":
+		return policy.Folders[id].Bindings
+	}
+	return nil
+}
+
+// hasNode reports whether a folder or organization is defined
+func hasNode(policy *Policy, name string) bool {
+	kind, id, _ := strings.Cut(name, "/")
+	switch kind {
+	case "organizations":
+		_, ok := policy.Organizations[id]
+		return ok
+	case "folders":
+		_, ok := policy.Folders[id]
+		return ok
+	}
+	return false
+}
+
+// applicableBindings returns the bindings that apply to resource: those on
+// its project (and service account or resource), then those inherited from the
+// project's folders and organization, nearest first. Folders and
+// organizations can be resources themselves. If nothing in the policy
+// contains the resource, it returns a reason instead.
+func applicableBindings(policy *Policy, resource string) ([]BindingRef, string) {
+	var refs []BindingRef
+	var parent string
+
+	switch {
+	case ProjectFromResource(resource) != "":
+		projectID := ProjectFromResource(resource)
+		project, ok := policy.Projects[projectID]
+		if !ok {
+			return nil, fmt.Sprintf("project %s not found in policy", projectID)
+		}
+		refs = resourceBindings(policy, projectID, project, resource)
+		parent = project.Parent
+	case strings.HasPrefix(resource, "folders/"), strings.HasPrefix(resource, "organizations/"):
+		parts := strings.SplitN(resource, "/", 3)
+		parent = parts[0] + "/" + parts[1]
+		if !hasNode(policy, parent) {
+			return nil, fmt.Sprintf("%s not found in policy", parent)
+		}
+	default:
+		return nil, "resource is not within a project"
+	}
+
+	// Evaluate whatever part of the hierarchy is well formed; Validate
+	// reports the rest
+	ancestors, _ := Ancestors(policy, parent)
+	for _, name := range ancestors {
+		for i, binding := range nodeBindings(policy, name) {
+			refs = append(refs, BindingRef{Resource: name, Index: i, Binding: binding})
+		}
+	}
+
+	return refs, ""
+}
@@ -19,9 +19,21 @@ import (
 
 // Policy represents the policy file structure
 type Policy struct {
-	Roles    map[string]Role       `yaml:"roles" json:"roles"`
-	Groups   map[string]Group      `yaml:"groups" json:"groups"`
-	Projects map[string]Project    `yaml:"projects" json:"projects"`
+	Roles         map[string]Role         `yaml:"roles" json:"roles"`
+	Groups        map[string]Group        `yaml:"groups" json:"groups"`
+	Organizations map[string]Organization `yaml:"organizations,omitempty" json:"organizations,omitempty"`
+	Folders       map[string]Folder       `yaml:"folders,omitempty" json:"folders,omitempty"`
+	Projects      map[string]Project      `yaml:"projects" json:"projects"`
+	// Resources holds bindings on individual resources within projects,
+	// keyed by canonical resource name
+	Resources map[string]Resource `yaml:"resources,omitempty" json:"resources,omitempty"`
+	// DenyPolicies are keyed by name and override any allow binding
+	DenyPolicies map[string]DenyPolicy `yaml:"denyPolicies,omitempty" json:"denyPolicies,omitempty"`
+	// Boundaries cap the permissions of their principals, keyed by name
+	Boundaries map[string]AccessBoundary `yaml:"boundaries,omitempty" json:"boundaries,omitempty"`
+	// WorkloadIdentities describes federated principals (principal://...)
+	// for matching against principalSet:// members, keyed by principal
+	WorkloadIdentities map[string]WorkloadIdentity `yaml:"workloadIdentities,omitempty" json:"workloadIdentities,omitempty"`
 }
 
 // Role represents a custom role with permissions
@@ -34,11 +46,84 @@ type Group struct {
 	Members []string `yaml:"members" json:"members"`
 }
 
+// Organization represents an organization (organizations/{id}) with IAM
+// bindings inherited by everything below it
+type Organization struct {
+	Bindings []Binding `yaml:"bindings" json:"bindings"`
+}
+
+// Folder represents a folder (folders/{id}) with IAM bindings inherited by
+// everything below it
+type Folder struct {
+	// Parent is organizations/{id} or folders/{id}
+	Parent   string    `yaml:"parent,omitempty" json:"parent,omitempty"`
+	Bindings []Binding `yaml:"bindings" json:"bindings"`
+}
+
 // Project represents a project with IAM bindings
 type Project struct {
+	// Parent is organizations/{id} or folders/{id}
+	Parent   string    `yaml:"parent,omitempty" json:"parent,omitempty"`
+	Bindings []Binding `yaml:"bindings" json:"bindings"`
+	// ServiceAccounts holds bindings on the project's service accounts,
+	// keyed by email
+	ServiceAccounts map[string]ServiceAccount `yaml:"serviceAccounts,omitempty" json:"serviceAccounts,omitempty"`
+	// Etag and Version are those of an imported production IAM policy;
+	// GetIamPolicy returns them for the project
+	Etag    string `yaml:"etag,omitempty" json:"etag,omitempty"`
+	Version int    `yaml:"version,omitempty" json:"version,omitempty"`
+}
+
+// ServiceAccount represents a service account with IAM bindings on it
+// (projects/{project}/serviceAccounts/{email})
+type ServiceAccount struct {
 	Bindings []Binding `yaml:"bindings" json:"bindings"`
 }
 
+// Resource represents a secret, key ring or crypto key with IAM bindings on
+// it, in addition to those of its project
+type Resource struct {
+	Bindings []Binding `yaml:"bindings" json:"bindings"`
+}
+
+// DenyPolicy represents an IAM deny policy attached to a project, folder or
+// organization. It applies to every resource at or below its attachment
+// point.
+type DenyPolicy struct {
+	// AttachmentPoint is projects/{id}, folders/{id} or organizations/{id}
+	AttachmentPoint string     `yaml:"attachmentPoint" json:"attachmentPoint"`
+	Rules           []DenyRule `yaml:"rules" json:"rules"`
+}
+
+// DenyRule denies permissions to principals, unless the principal or
+// permission is excepted or the denial condition is false
+type DenyRule struct {
+	DeniedPrincipals     []string   `yaml:"deniedPrincipals" json:"deniedPrincipals"`
+	ExceptionPrincipals  []string   `yaml:"exceptionPrincipals,omitempty" json:"exceptionPrincipals,omitempty"`
+	DeniedPermissions    []string   `yaml:"deniedPermissions" json:"deniedPermissions"`
+	ExceptionPermissions []string   `yaml:"exceptionPermissions,omitempty" json:"exceptionPermissions,omitempty"`
+	DenialCondition      *Condition `yaml:"denialCondition,omitempty" json:"denialCondition,omitempty"`
+}
+
+// AccessBoundary caps the permissions its principals can be granted: a
+// principal covered by one or more boundaries is denied any permission
+// outside all of them, whatever its bindings grant
+type AccessBoundary struct {
+	Principals  []string `yaml:"principals" json:"principals"`
+	Permissions []string `yaml:"permissions,omitempty" json:"permissions,omitempty"`
+	// Roles adds the permissions of each role to the boundary
+	Roles []string `yaml:"roles,omitempty" json:"roles,omitempty"`
+}
+
+// WorkloadIdentity holds the mapped attributes and groups of a federated
+// principal, as its identity pool would assert them
+type WorkloadIdentity struct {
+	// Attributes are matched by principalSet://.../attribute.{name}/{value}
+	Attributes map[string]string `yaml:"attributes,omitempty" json:"attributes,omitempty"`
+	// Groups are matched by principalSet://.../group/{group}
+	Groups []string `yaml:"groups,omitempty" json:"groups,omitempty"`
+}
+
 // Binding represents an IAM binding
 type Binding struct {
 	Role      string     `yaml:"role" json:"role"`
@@ -53,34 +138,14 @@ type Condition struct {
 	Description string `yaml:"description,omitempty" json:"description,omitempty"`
 }
 
-// Load loads and parses a policy file (supports .yaml, .yml, and .json)
+// Load loads and parses a policy file (supports .yaml, .yml, and .json),
+// resolving the variables it declares (see Render)
 func Load(path string) (*Policy, error) {
-	data, err := os.ReadFile(path)
+	rendered, err := Render(path, "", nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read policy file: %w", err)
-	}
-
-	var policy Policy
-	
-	// Detect format by file extension
-	ext := strings.ToLower(filepath.Ext(path))
-	switch ext {
-	case ".json":
-		if err := json.Unmarshal(data, &policy); err != nil {
-			return nil, fmt.Errorf("failed to parse policy JSON: %w", err)
-		}
-	case ".yaml", ".yml":
-		if err := yaml.Unmarshal(data, &policy); err != nil {
-			return nil, fmt.Errorf("failed to parse policy YAML: %w", err)
-		}
-	default:
-		// Try YAML as fallback for backwards compatibility
-		if err := yaml.Unmarshal(data, &policy); err != nil {
-			return nil, fmt.Errorf("failed to parse policy (unknown extension %s, tried YAML): %w", ext, err)
-		}
+		return nil, err
 	}
-
-	return &policy, nil
+	return rendered.Policy, nil
 }
 
 // Save saves policy to file (format determined by file extension)
@@ -42,6 +42,16 @@ func TestValidatePermissionFormat(t *testing.T) {
 			permission: "cloudkms.cryptoKeyVersions.useToDecrypt",
 			wantErr:    false,
 		},
+		{
+			name:       "valid iam permission",
+			permission: "iam.serviceAccounts.getAccessToken",
+			wantErr:    false,
+		},
+		{
+			name:       "invalid - unknown service",
+			permission: "storage.objects.get",
+			wantErr:    true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,179 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// iamHost prefixes workload and workforce identity pool names in principal://
+// and principalSet:// identifiers
+const iamHost = "iam.googleapis.com/"
+
+// publicPrincipalSet is the principalSet form of allUsers, as used by deny
+// policies
+const publicPrincipalSet = "principalSet://goog/public:all"
+
+// poolPatterns are the workload and workforce identity pool names that
+// principal:// and principalSet:// identifiers can refer to
+var poolPatterns = []string{
+	"projects/*/locations/global/workloadIdentityPools/*",
+	"locations/global/workforcePools/*",
+}
+
+// Principal is a parsed principal or binding member
+type Principal struct {
+	// Type is user, serviceAccount, group, domain, principal, principalSet,
+	// deleted, allUsers or allAuthenticatedUsers
+	Type string
+	// ID is the email, group name or domain; for a deleted principal, the
+	// member it was (type:email)
+	ID string
+	// Pool is the identity pool of a principal:// or principalSet://
+	// identifier
+	Pool string
+	// Subject is the subject of a principal:// identifier
+	Subject string
+	// Set selects the members of a principalSet:// pool: "*", group/{group}
+	// or attribute.{name}/{value}
+	Set string
+	// UID is the unique ID of a deleted principal
+	UID string
+}
+
+// ParsePrincipal parses a principal or binding member:
+//
+//	user:{email}, serviceAccount:{email}, group:{name}, domain:{domain}
+//	allUsers, allAuthenticatedUsers
+//	principal://iam.googleapis.com/{pool}/subject/{subject}
+//	principalSet://iam.googleapis.com/{pool}/{* | group/{group} | attribute.{name}/{value}}
+//	principalSet://goog/public:all
+//	deleted:{user|serviceAccount|group}:{email}?uid={uid}
+//
+// where {pool} is projects/{number}/locations/global/workloadIdentityPools/{pool}
+// or locations/global/workforcePools/{pool}.
+func ParsePrincipal(s string) (Principal, error) {
+	switch {
+	case s == "allUsers" || s == "allAuthenticatedUsers":
+		return Principal{Type: s}, nil
+	case s == publicPrincipalSet:
+		return Principal{Type: "principalSet", Set: "public:all"}, nil
+	case strings.HasPrefix(s, "principal://"):
+		return parsePrincipalIdentifier(s)
+	case strings.HasPrefix(s, "principalSet://"):
+		return parsePrincipalSet(s)
+	}
+
+	kind, id, ok := strings.Cut(s, ":")
+	if !ok || id == "" {
+		return Principal{}, fmt.Errorf("invalid principal format: %s (expected type:identifier)", s)
+	}
+
+	switch kind {
+	case "user", "serviceAccount":
+		if !strings.Contains(id, "@") {
+			return Principal{}, fmt.Errorf("invalid %s: %s (expected email format)", kind, id)
+		}
+	case "group":
+	case "domain":
+		if strings.Contains(id, "@") || !strings.Contains(id, ".") {
+			return Principal{}, fmt.Errorf("invalid domain: %s (expected a domain such as example.com)", id)
+		}
+	case "deleted":
+		member, uid, _ := strings.Cut(id, "?uid=")
+		deletedKind, email, _ := strings.Cut(member, ":")
+		if uid == "" || !strings.Contains(email, "@") || (deletedKind != "user" && deletedKind != "serviceAccount" && deletedKind != "group") {
+			return Principal{}, fmt.Errorf("invalid deleted principal: %s (expected deleted:{user|serviceAccount|group}:{email}?uid={uid})", s)
+		}
+		return Principal{Type: kind, ID: member, UID: uid}, nil
+	default:
+		return Principal{}, fmt.Errorf("unknown principal type: %s (expected user, serviceAccount, group, domain, principal://, principalSet:// or deleted)", kind)
+	}
+
+	return Principal{Type: kind, ID: id}, nil
+}
+
+func parsePrincipalIdentifier(s string) (Principal, error) {
+	rest, ok := strings.CutPrefix(s, "principal://"+iamHost)
+	pool, subject, found := strings.Cut(rest, "/subject/")
+	if !ok || !found || subject == "" || !isPool(pool) {
+		return Principal{}, fmt.Errorf("invalid principal: %s (expected principal://%s{pool}/subject/{subject})", s, iamHost)
+	}
+	return Principal{Type: "principal", Pool: pool, Subject: subject}, nil
+}
+
+func parsePrincipalSet(s string) (Principal, error) {
+	invalid := fmt.Errorf("invalid principalSet: %s (expected principalSet://%s{pool}/* or .../group/{group} or .../attribute.{name}/{value})", s, iamHost)
+
+	rest, ok := strings.CutPrefix(s, "principalSet://"+iamHost)
+	if !ok {
+		return Principal{}, invalid
+	}
+
+	// Workload identity pool names have six segments, workforce pools four
+	segments := strings.Split(rest, "/")
+	n := 4
+	if segments[0] == "projects" {
+		n = 6
+	}
+	if len(segments) <= n {
+		return Principal{}, invalid
+	}
+	pool := strings.Join(segments[:n], "/")
+	set := strings.Join(segments[n:], "/")
+
+	name, value, _ := strings.Cut(set, "/")
+	validSet := set == "*" ||
+		(name == "group" && value != "") ||
+		(strings.HasPrefix(name, "attribute.") && name != "attribute." && value != "")
+	if !isPool(pool) || !validSet {
+		return Principal{}, invalid
+	}
+
+	return Principal{Type: "principalSet", Pool: pool, Set: set}, nil
+}
+
+func isPool(name string) bool {
+	segments := strings.Split(name, "/")
+	for _, pattern := range poolPatterns {
+		if matchSegments(strings.Split(pattern, "/"), segments) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesPrincipalSet reports whether principal, a principal:// identifier,
+// is in the principalSet member: in the same pool and, for group and
+// attribute sets, with that group or attribute in the policy's
+// workloadIdentities
+func matchesPrincipalSet(policy *Policy, set Principal, principal string) bool {
+	if set.Set == "public:all" {
+		return true
+	}
+
+	p, err := ParsePrincipal(principal)
+	if err != nil || p.Type != "principal" || p.Pool != set.Pool {
+		return false
+	}
+
+	identity := policy.WorkloadIdentities[principal]
+	name, value, _ := strings.Cut(set.Set, "/")
+	switch {
+	case set.Set == "*":
+		return true
+	case name == "group":
+		return containsString(identity.Groups, value)
+	default:
+		return identity.Attributes[strings.TrimPrefix(name, "attribute.")] == value
+	}
+}
+
+// inDomain reports whether principal is a user whose email is in domain
+func inDomain(principal, domain string) bool {
+	email, ok := strings.CutPrefix(principal, "user:")
+	if !ok {
+		return false
+	}
+	_, emailDomain, _ := strings.Cut(email, "@")
+	return strings.EqualFold(emailDomain, domain)
+}
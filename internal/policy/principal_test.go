@@ -0,0 +1,94 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePrincipal(t *testing.T) {
+	const pool = "projects/123456/locations/global/workloadIdentityPools/github"
+
+	tests := []struct {
+		name    string
+		input   string
+		want    Principal
+		wantErr string
+	}{
+		{"user", "user:alice@example.com", Principal{Type: "user", ID: "alice@example.com"}, ""},
+		{"domain", "domain:example.com", Principal{Type: "domain", ID: "example.com"}, ""},
+		{"allUsers", "allUsers", Principal{Type: "allUsers"}, ""},
+		{"workload identity subject", "principal://iam.googleapis.com/" + pool + "/subject/repo:acme/api:ref:refs/heads/main", Principal{Type: "principal", Pool: pool, Subject: "repo:acme/api:ref:refs/heads/main"}, ""},
+		{"workforce identity subject", "principal://iam.googleapis.com/locations/global/workforcePools/staff/subject/alice", Principal{Type: "principal", Pool: "locations/global/workforcePools/staff", Subject: "alice"}, ""},
+		{"whole pool", "principalSet://iam.googleapis.com/" + pool + "/*", Principal{Type: "principalSet", Pool: pool, Set: "*"}, ""},
+		{"attribute set", "principalSet://iam.googleapis.com/" + pool + "/attribute.repository/acme/api", Principal{Type: "principalSet", Pool: pool, Set: "attribute.repository/acme/api"}, ""},
+		{"public set", "principalSet://goog/public:all", Principal{Type: "principalSet", Set: "public:all"}, ""},
+		{"deleted", "deleted:serviceAccount:ci@p.iam.gserviceaccount.com?uid=42", Principal{Type: "deleted", ID: "serviceAccount:ci@p.iam.gserviceaccount.com", UID: "42"}, ""},
+		{"domain with email", "domain:alice@example.com", Principal{}, "invalid domain"},
+		{"subject without pool", "principal://iam.googleapis.com/subject/alice", Principal{}, "invalid principal"},
+		{"set without selector", "principalSet://iam.googleapis.com/" + pool, Principal{}, "invalid principalSet"},
+		{"empty attribute name", "principalSet://iam.googleapis.com/" + pool + "/attribute./x", Principal{}, "invalid principalSet"},
+		{"deleted without uid", "deleted:user:alice@example.com", Principal{}, "invalid deleted principal"},
+		{"unknown type", "robot:r2d2", Principal{}, "unknown principal type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePrincipal(tt.input)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParsePrincipal(%q) error = %v, want %q", tt.input, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParsePrincipal(%q) error = %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParsePrincipal(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateExtendedPrincipals(t *testing.T) {
+	policy, err := Load("../../testdata/workload_identity.yaml")
+	if err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	if result := Validate(policy); !result.Valid {
+		t.Fatalf("Expected valid policy, got %v", result.Errors)
+	}
+
+	const (
+		subject = "principal://iam.googleapis.com/projects/123456/locations/global/workloadIdentityPools/github/subject/"
+		api     = subject + "repo:acme/api:ref:refs/heads/main"
+		web     = subject + "repo:acme/web:ref:refs/heads/main"
+		other   = "principal://iam.googleapis.com/projects/999/locations/global/workloadIdentityPools/github/subject/repo:acme/api:ref:refs/heads/main"
+		secret  = "projects/test-project/secrets/db-password"
+	)
+
+	tests := []struct {
+		name       string
+		principal  string
+		permission string
+		want       bool
+	}{
+		{"domain member", "user:alice@example.com", "secretmanager.secrets.get", true},
+		{"domain is case insensitive", "user:bob@EXAMPLE.com", "secretmanager.secrets.get", true},
+		{"subdomain is not the domain", "user:eve@evil.example.com", "secretmanager.secrets.get", false},
+		{"service account is not a domain user", "serviceAccount:sa@example.com", "secretmanager.secrets.get", false},
+		{"whole pool", web, "secretmanager.secrets.get", true},
+		{"pool in another project", other, "secretmanager.secrets.get", false},
+		{"attribute set", api, "secretmanager.versions.access", true},
+		{"attribute set excludes other repositories", web, "secretmanager.versions.access", false},
+		{"group set", api, "secretmanager.secrets.delete", true},
+		{"deleted principal grants nothing", "user:former-admin@example.com", "secretmanager.secrets.delete", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := Evaluate(policy, Request{Principal: tt.principal, Resource: secret, Permission: tt.permission})
+			if decision.Allowed != tt.want {
+				t.Errorf("Allowed = %v, want %v (%s)", decision.Allowed, tt.want, decision.Reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// resourcePatterns are the canonical names of the resources that can hold
+// bindings in the resources section; "*" matches any single segment
+var resourcePatterns = []string{
+	"projects/*/secrets/*",
+	"projects/*/locations/*/keyRings/*",
+	"projects/*/locations/*/keyRings/*/cryptoKeys/*",
+}
+
+// validateResourceName checks that name is the canonical name of a secret,
+// key ring or crypto key
+func validateResourceName(name string) error {
+	segments := strings.Split(name, "/")
+	for _, pattern := range resourcePatterns {
+		if matchSegments(strings.Split(pattern, "/"), segments) {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid resource name %s (expected projects/{project}/secrets/{secret}, projects/{project}/locations/{location}/keyRings/{keyring} or .../keyRings/{keyring}/cryptoKeys/{key})", name)
+}
+
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) != len(segments) {
+		return false
+	}
+	for i, p := range pattern {
+		if segments[i] == "" || (p != "*" && p != segments[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasProject reports whether a project is defined
+func hasProject(policy *Policy, id string) bool {
+	_, ok := policy.Projects[id]
+	return ok
+}
+
+// resourcesWithin returns the names in the resources section that belong to
+// project, sorted
+func resourcesWithin(policy *Policy, project string) []string {
+	var names []string
+	for _, name := range sortedNames(policy.Resources) {
+		if ProjectFromResource(name) == project {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// enclosingResources returns the names in the resources section that are
+// resource itself or contain it (a crypto key's key ring, a version's
+// secret), outermost first
+func enclosingResources(policy *Policy, resource string) []string {
+	var names []string
+	for _, name := range sortedNames(policy.Resources) {
+		if resource == name || strings.HasPrefix(resource, name+"/") {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// resourceTypes maps resource name patterns to the service and type exposed
+// to conditions as resource.service and resource.type
+var resourceTypes = []struct {
+	pattern string
+	service string
+	kind    string
+}{
+	{"organizations/*", "cloudresourcemanager.googleapis.com", "Organization"},
+	{"folders/*", "cloudresourcemanager.googleapis.com", "Folder"},
+	{"projects/*", "cloudresourcemanager.googleapis.com", "Project"},
+	{"projects/*/secrets/*", "secretmanager.googleapis.com", "Secret"},
+	{"projects/*/secrets/*/versions/*", "secretmanager.googleapis.com", "SecretVersion"},
+	{"projects/*/locations/*/keyRings/*", "cloudkms.googleapis.com", "KeyRing"},
+	{"projects/*/locations/*/keyRings/*/cryptoKeys/*", "cloudkms.googleapis.com", "CryptoKey"},
+	{"projects/*/locations/*/keyRings/*/cryptoKeys/*/cryptoKeyVersions/*", "cloudkms.googleapis.com", "CryptoKeyVersion"},
+	{"projects/*/serviceAccounts/*", "iam.googleapis.com", "ServiceAccount"},
+	{"projects/*/serviceAccounts/*/keys/*", "iam.googleapis.com", "ServiceAccountKey"},
+}
+
+// ResourceType returns the service (e.g. secretmanager.googleapis.com) and
+// type (e.g. secretmanager.googleapis.com/Secret) of a canonical resource
+// name, or empty strings if the name is not recognized
+func ResourceType(name string) (service, kind string) {
+	segments := strings.Split(name, "/")
+	for _, t := range resourceTypes {
+		if matchSegments(strings.Split(t.pattern, "/"), segments) {
+			return t.service, t.service + "/" + t.kind
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,78 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ImpersonationPermission is the permission each principal in a delegation
+// chain needs on the next service account (granted by
+// roles/iam.serviceAccountTokenCreator)
+const ImpersonationPermission = "iam.serviceAccounts.getAccessToken"
+
+// ServiceAccountResource returns the canonical resource name of a service
+// account: projects/{project}/serviceAccounts/{email}, with the project
+// taken from the email or "-" if the email does not name one
+func ServiceAccountResource(email string) string {
+	project := ProjectFromEmail(email)
+	if project == "" {
+		project = "-"
+	}
+	return "projects/" + project + "/serviceAccounts/" + email
+}
+
+// ParseServiceAccountResource splits a service account resource name
+// (projects/{project}/serviceAccounts/{email}, optionally followed by a
+// sub-resource such as /keys/{key}) into its project and email. A "-"
+// project, as used by the IAM Credentials API, is resolved from the email.
+func ParseServiceAccountResource(resource string) (project, email string, ok bool) {
+	parts := strings.SplitN(resource, "/", 5)
+	if len(parts) < 4 || parts[0] != "projects" || parts[2] != "serviceAccounts" || parts[3] == "" {
+		return "", "", false
+	}
+
+	project, email = parts[1], parts[3]
+	if project == "-" {
+		project = ProjectFromEmail(email)
+	}
+	return project, email, project != ""
+}
+
+// ProjectFromEmail returns the project of a user-managed service account
+// email (name@project.iam.gserviceaccount.com), or "" for any other email
+func ProjectFromEmail(email string) string {
+	_, domain, ok := strings.Cut(email, "@")
+	if !ok {
+		return ""
+	}
+	project, ok := strings.CutSuffix(domain, ".iam.gserviceaccount.com")
+	if !ok {
+		return ""
+	}
+	return project
+}
+
+// evaluateDelegation checks that req.Principal may impersonate each of
+// req.Delegates in turn, recording every step in decision.Delegation. It
+// returns false, with decision.Reason set, at the first step denied.
+func evaluateDelegation(policy *Policy, req Request, decision *Decision) bool {
+	caller := req.Principal
+	for _, delegate := range req.Delegates {
+		email := strings.TrimPrefix(delegate, "serviceAccount:")
+
+		step := Evaluate(policy, Request{
+			Principal:  caller,
+			Resource:   ServiceAccountResource(email),
+			Permission: ImpersonationPermission,
+			Time:       req.Time,
+		})
+		decision.Delegation = append(decision.Delegation, step)
+
+		if !step.Allowed {
+			decision.Reason = fmt.Sprintf("%s cannot impersonate %s: %s", caller, email, step.Reason)
+			return false
+		}
+		caller = "serviceAccount:" + email
+	}
+	return true
+}
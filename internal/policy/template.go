@@ -0,0 +1,388 @@
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// variablesKey is the top-level key declaring a policy file's variables.
+// It is removed before the file is decoded.
+const variablesKey = "variables"
+
+// variablePattern matches a ${name} reference, or its $${name} escape
+var variablePattern = regexp.MustCompile(`\$?\$\{([^}]*)\}`)
+
+// variableName is the syntax of a variable name
+var variableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Rendered is a policy file resolved against its variables and, optionally,
+// an environment overlay
+type Rendered struct {
+	Policy *Policy
+	// Files are the files rendered: the base, then the overlay if any
+	Files []string
+	// Variables are the values substituted for ${name} references
+	Variables map[string]string
+
+	// sources locates validation scopes ("Project test-project binding 0")
+	// in Files
+	sources map[string]source
+}
+
+// source is a position in a policy file
+type source struct {
+	File string
+	Line int
+}
+
+func (s source) String() string {
+	return fmt.Sprintf("%s:%d", s.File, s.Line)
+}
+
+// Render loads the policy file at path and, if env is set, the overlay file
+// next to it named after the environment (policy.yaml → policy.ci.yaml),
+// then resolves variables and merges the overlay onto the base.
+//
+// Variables are declared under a top-level variables key and referenced as
+// ${name} anywhere in either file, keys included. The overlay's variables
+// override the base's, and vars override both. References are only
+// resolved in templates: when a file declares variables, or env or vars is
+// set. There, $${ is a literal ${; elsewhere ${ is kept as written.
+//
+// The overlay merges key by key: mappings (roles, projects, a project's
+// fields) merge recursively, other values, lists included, replace the
+// base's, and a null value removes the key.
+func Render(path, env string, vars map[string]string) (*Rendered, error) {
+	files := []string{path}
+	if env != "" {
+		files = append(files, OverlayPath(path, env))
+	}
+
+	roots := make([]*yaml.Node, len(files))
+	origins := make(map[*yaml.Node]string)
+	variables := make(map[string]string)
+	template := env != "" || len(vars) > 0
+	for i, file := range files {
+		root, err := parseFile(file)
+		if err != nil {
+			if i > 0 && errors.Is(err, fs.ErrNotExist) {
+				return nil, fmt.Errorf("environment %s: overlay %s not found", env, file)
+			}
+			return nil, err
+		}
+		declared, err := extractVariables(root, file, variables)
+		if err != nil {
+			return nil, err
+		}
+		template = template || declared
+		recordOrigin(root, file, origins)
+		roots[i] = root
+	}
+	for name, value := range vars {
+		variables[name] = value
+	}
+
+	for i, root := range roots {
+		if !template {
+			break
+		}
+		if err := substitute(root, files[i], variables); err != nil {
+			return nil, err
+		}
+	}
+
+	merged := roots[0]
+	for _, overlay := range roots[1:] {
+		mergeNodes(merged, overlay)
+	}
+
+	var policy Policy
+	if err := decodePolicy(merged, path, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse policy %s: %w", strings.Join(files, " + "), err)
+	}
+
+	return &Rendered{
+		Policy:    &policy,
+		Files:     files,
+		Variables: variables,
+		sources:   locateScopes(merged, origins),
+	}, nil
+}
+
+// decodePolicy decodes the rendered policy. JSON policies go through
+// encoding/json, which matches keys case-insensitively ("Projects"), as
+// they always have.
+func decodePolicy(n *yaml.Node, path string, policy *Policy) error {
+	if strings.ToLower(filepath.Ext(path)) != ".json" {
+		return n.Decode(policy)
+	}
+
+	var v any
+	if err := n.Decode(&v); err != nil {
+		return err
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, policy)
+}
+
+// OverlayPath returns the overlay file for env next to a base policy file:
+// dir/policy.yaml → dir/policy.{env}.yaml
+func OverlayPath(path, env string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + env + ext
+}
+
+// Validate validates the rendered policy, prefixing each error with the
+// file and line it comes from where known
+func (r *Rendered) Validate() *ValidationResult {
+	result := Validate(r.Policy)
+	for i, msg := range result.Errors {
+		result.Errors[i] = r.locate(msg)
+	}
+	return result
+}
+
+// locate prefixes a validation message with the source of the longest
+// scope it starts with
+func (r *Rendered) locate(msg string) string {
+	prefix := ""
+	text := msg
+	if rest, ok := strings.CutPrefix(msg, "WARNING: "); ok {
+		prefix, text = "WARNING: ", rest
+	}
+
+	best := ""
+	for scope := range r.sources {
+		if len(scope) > len(best) && (text == scope || strings.HasPrefix(text, scope+":") || strings.HasPrefix(text, scope+" ")) {
+			best = scope
+		}
+	}
+	if best == "" {
+		return msg
+	}
+	return fmt.Sprintf("%s%s: %s", prefix, r.sources[best], text)
+}
+
+// parseFile reads a policy file into a YAML mapping node. JSON files are
+// checked as JSON first, so YAML-only syntax is rejected as before.
+func parseFile(path string) (*yaml.Node, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+
+	var doc yaml.Node
+	ext := strings.ToLower(filepath.Ext(path))
+	switch ext {
+	case ".json":
+		var v any
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, fmt.Errorf("failed to parse policy JSON: %w", err)
+		}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse policy JSON: %w", err)
+		}
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse policy YAML: %w", err)
+		}
+	default:
+		// Try YAML as fallback for backwards compatibility
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse policy (unknown extension %s, tried YAML): %w", ext, err)
+		}
+	}
+
+	// An empty file is an empty policy
+	if doc.Kind == 0 {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("failed to parse policy %s: line %d: expected a mapping of sections", path, root.Line)
+	}
+	return root, nil
+}
+
+// extractVariables removes the variables key from root, adding its entries
+// to variables. It reports whether the key was present.
+func extractVariables(root *yaml.Node, file string, variables map[string]string) (bool, error) {
+	for i := 0; i < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if key.Value != variablesKey {
+			continue
+		}
+		root.Content = append(root.Content[:i:i], root.Content[i+2:]...)
+
+		if value.Tag == "!!null" {
+			return true, nil
+		}
+		if value.Kind != yaml.MappingNode {
+			return true, fmt.Errorf("%s:%d: variables must be a mapping of names to values", file, value.Line)
+		}
+		for j := 0; j < len(value.Content); j += 2 {
+			name, v := value.Content[j], value.Content[j+1]
+			if !variableName.MatchString(name.Value) {
+				return true, fmt.Errorf("%s:%d: invalid variable name %q (expected letters, digits and underscores)", file, name.Line, name.Value)
+			}
+			if v.Kind != yaml.ScalarNode {
+				return true, fmt.Errorf("%s:%d: variable %s must be a string", file, v.Line, name.Value)
+			}
+			variables[name.Value] = v.Value
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// recordOrigin maps every node under n to the file it was read from
+func recordOrigin(n *yaml.Node, file string, origins map[*yaml.Node]string) {
+	origins[n] = file
+	for _, child := range n.Content {
+		recordOrigin(child, file, origins)
+	}
+}
+
+// substitute replaces ${name} references in every scalar under n, keys
+// included, and unescapes $${
+func substitute(n *yaml.Node, file string, variables map[string]string) error {
+	if n.Kind == yaml.ScalarNode {
+		var err error
+		value := variablePattern.ReplaceAllStringFunc(n.Value, func(ref string) string {
+			if strings.HasPrefix(ref, "$$") {
+				return ref[1:]
+			}
+			name := ref[2 : len(ref)-1]
+			value, ok := variables[name]
+			if !ok && err == nil {
+				err = fmt.Errorf("%s:%d: undefined variable ${%s}", file, n.Line, name)
+			}
+			return value
+		})
+		// An unquoted scalar takes the type of its value: version: ${ver}
+		// is an int once substituted
+		if value != n.Value && n.Style&(yaml.TaggedStyle|yaml.SingleQuotedStyle|yaml.DoubleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+			n.Tag = ""
+		}
+		n.Value = value
+		return err
+	}
+
+	for _, child := range n.Content {
+		if err := substitute(child, file, variables); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeNodes merges the overlay mapping onto base: mappings merge key by
+// key, other values replace the base's, and null removes the key
+func mergeNodes(base, overlay *yaml.Node) {
+	for i := 0; i < len(overlay.Content); i += 2 {
+		key, value := overlay.Content[i], overlay.Content[i+1]
+
+		j := mappingIndex(base, key.Value)
+		switch {
+		case value.Tag == "!!null":
+			if j >= 0 {
+				base.Content = append(base.Content[:j:j], base.Content[j+2:]...)
+			}
+		case j < 0:
+			base.Content = append(base.Content, key, value)
+		case value.Kind == yaml.MappingNode && base.Content[j+1].Kind == yaml.MappingNode:
+			mergeNodes(base.Content[j+1], value)
+		default:
+			base.Content[j+1] = value
+		}
+	}
+}
+
+// mappingIndex returns the index of key in a mapping node's Content, or -1
+func mappingIndex(n *yaml.Node, key string) int {
+	if n.Kind != yaml.MappingNode {
+		return -1
+	}
+	for i := 0; i < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// scopeSections maps the top-level sections to the scope names the
+// validator uses for their entries
+var scopeSections = map[string]string{
+	"roles":              "Role",
+	"groups":             "Group",
+	"organizations":      "Organization",
+	"folders":            "Folder",
+	"projects":           "Project",
+	"resources":          "Resource",
+	"denyPolicies":       "Deny policy",
+	"boundaries":         "Boundary",
+	"workloadIdentities": "Workload identity",
+}
+
+// locateScopes returns where each validation scope of the merged policy is
+// defined: entries, their bindings and deny rules, and service accounts
+func locateScopes(root *yaml.Node, origins map[*yaml.Node]string) map[string]source {
+	sources := make(map[string]source)
+	add := func(scope string, n *yaml.Node) {
+		sources[scope] = source{File: origins[n], Line: n.Line}
+	}
+
+	for i := 0; i < len(root.Content); i += 2 {
+		kind, ok := scopeSections[root.Content[i].Value]
+		section := root.Content[i+1]
+		if !ok || section.Kind != yaml.MappingNode {
+			continue
+		}
+
+		for j := 0; j < len(section.Content); j += 2 {
+			key, entry := section.Content[j], section.Content[j+1]
+			scope := kind + " " + key.Value
+			add(scope, key)
+			locateItems(add, scope, "binding", entry, "bindings")
+			locateItems(add, scope, "rule", entry, "rules")
+
+			if kind != "Project" {
+				continue
+			}
+			if k := mappingIndex(entry, "serviceAccounts"); k >= 0 && entry.Content[k+1].Kind == yaml.MappingNode {
+				accounts := entry.Content[k+1]
+				for m := 0; m < len(accounts.Content); m += 2 {
+					saScope := fmt.Sprintf("%s service account %s", scope, accounts.Content[m].Value)
+					add(saScope, accounts.Content[m])
+					locateItems(add, saScope, "binding", accounts.Content[m+1], "bindings")
+				}
+			}
+		}
+	}
+
+	return sources
+}
+
+// locateItems adds "{scope} {item} {i}" for each element of entry's list
+// under key
+func locateItems(add func(string, *yaml.Node), scope, item string, entry *yaml.Node, key string) {
+	k := mappingIndex(entry, key)
+	if k < 0 || entry.Content[k+1].Kind != yaml.SequenceNode {
+		return
+	}
+	for i, n := range entry.Content[k+1].Content {
+		add(fmt.Sprintf("%s %s %d", scope, item, i), n)
+	}
+}
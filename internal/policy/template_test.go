@@ -0,0 +1,183 @@
+package policy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        string
+		vars       map[string]string
+		project    string
+		ciMember   string
+		bindings   int
+		developers bool
+	}{
+		{"base", "", nil, "dev-project", "serviceAccount:ci@dev-project.iam.gserviceaccount.com", 2, true},
+		{"ci overlay", "ci", nil, "ci-project", "serviceAccount:runner@ci-project.iam.gserviceaccount.com", 1, false},
+		{"vars override files", "ci", map[string]string{"project": "pr-42"}, "pr-42", "serviceAccount:runner@ci-project.iam.gserviceaccount.com", 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rendered, err := Render("../../testdata/template.yaml", tt.env, tt.vars)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if result := rendered.Validate(); !result.Valid {
+				t.Fatalf("Expected valid policy, got %v", result.Errors)
+			}
+
+			pol := rendered.Policy
+			project, ok := pol.Projects[tt.project]
+			if !ok || len(pol.Projects) != 1 {
+				t.Fatalf("Projects = %v, want only %s", sortedNames(pol.Projects), tt.project)
+			}
+			if len(project.Bindings) != tt.bindings {
+				t.Fatalf("got %d bindings, want %d", len(project.Bindings), tt.bindings)
+			}
+			ci := project.Bindings[len(project.Bindings)-1]
+			if ci.Members[0] != tt.ciMember {
+				t.Errorf("CI member = %s, want %s", ci.Members[0], tt.ciMember)
+			}
+			if _, ok := pol.Groups["developers"]; ok != tt.developers {
+				t.Errorf("developers group present = %v, want %v", ok, tt.developers)
+			}
+		})
+	}
+
+	pol, err := Load("../../testdata/template.yaml")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	want := `resource.name.startsWith("projects/dev-project/secrets/")`
+	if got := pol.Projects["dev-project"].Bindings[1].Condition.Expression; got != want {
+		t.Errorf("Load() condition = %s, want %s", got, want)
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		base    string
+		overlay string
+		want    string
+	}{
+		{"undefined variable", "variables: {a: b}\nprojects:\n  p:\n    bindings:\n      - role: roles/viewer\n        members:\n          - user:${who}\n", "", "policy.yaml:7: undefined variable ${who}"},
+		{"undefined in overlay", "variables: {a: b}\n", "projects:\n  ${missing}: {}\n", "policy.ci.yaml:2: undefined variable ${missing}"},
+		{"variables not a mapping", "variables: [a]\n", "", "policy.yaml:1: variables must be a mapping"},
+		{"invalid variable name", "variables:\n  a-b: c\n", "", `invalid variable name "a-b"`},
+		{"missing overlay", "roles: {}\n", "-", "environment ci: overlay"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "policy.yaml")
+			if err := os.WriteFile(path, []byte(tt.base), 0644); err != nil {
+				t.Fatal(err)
+			}
+			env := ""
+			if tt.overlay != "" {
+				env = "ci"
+			}
+			if tt.overlay != "" && tt.overlay != "-" {
+				if err := os.WriteFile(OverlayPath(path, env), []byte(tt.overlay), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, err := Render(path, env, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Render() error = %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderScalarsAndEscapes(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		vars    map[string]string
+		check   func(*Policy) string
+	}{
+		{"int variable", "policy.yaml", "variables:\n  ver: \"3\"\nprojects:\n  p:\n    version: ${ver}\n    etag: \"${ver}\"\n", nil, func(pol *Policy) string {
+			if p := pol.Projects["p"]; p.Version != 3 || p.Etag != "3" {
+				return fmt.Sprintf("version, etag = %d, %q, want 3, \"3\"", p.Version, p.Etag)
+			}
+			return ""
+		}},
+		{"literal without variables", "policy.yaml", "projects:\n  p:\n    bindings:\n      - role: roles/viewer\n        members: [user:a@example.com]\n        condition:\n          expression: 'resource.name == \"${x}\"'\n", nil, func(pol *Policy) string {
+			if got := pol.Projects["p"].Bindings[0].Condition.Expression; got != `resource.name == "${x}"` {
+				return "expression = " + got
+			}
+			return ""
+		}},
+		{"escape in template", "policy.yaml", "projects:\n  ${project}:\n    bindings:\n      - role: roles/viewer\n        members: [user:a@example.com]\n        condition:\n          expression: 'resource.name == \"$${x}\"'\n", map[string]string{"project": "p"}, func(pol *Policy) string {
+			if got := pol.Projects["p"].Bindings[0].Condition.Expression; got != `resource.name == "${x}"` {
+				return "expression = " + got
+			}
+			return ""
+		}},
+		{"JSON keys are case-insensitive", "policy.json", `{"Projects": {"p": {"Bindings": [{"Role": "roles/viewer", "Members": ["user:a@example.com"]}]}}}`, nil, func(pol *Policy) string {
+			if len(pol.Projects["p"].Bindings) != 1 {
+				return fmt.Sprintf("Projects = %+v", pol.Projects)
+			}
+			return ""
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			rendered, err := Render(path, "", tt.vars)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if msg := tt.check(rendered.Policy); msg != "" {
+				t.Error(msg)
+			}
+		})
+	}
+}
+
+func TestRenderedValidateLocatesErrors(t *testing.T) {
+	rendered, err := Render("../../testdata/template.yaml", "ci", map[string]string{"ci_sa": "not-an-email"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	result := rendered.Validate()
+	want := "../../testdata/template.ci.yaml:13: Project ci-project binding 0: invalid serviceAccount"
+	if result.Valid || len(result.Errors) != 1 || !strings.HasPrefix(result.Errors[0], want) {
+		t.Errorf("Errors = %v, want one starting with %q", result.Errors, want)
+	}
+
+	if got := rendered.locate("WARNING: Role roles/custom.ciRunner has no permissions"); got != "WARNING: ../../testdata/template.yaml:8: Role roles/custom.ciRunner has no permissions" {
+		t.Errorf("locate() = %q", got)
+	}
+	if got := rendered.locate("No roles defined"); got != "No roles defined" {
+		t.Errorf("locate() = %q, want message unchanged", got)
+	}
+}
+
+func TestOverlayPath(t *testing.T) {
+	tests := map[string]string{
+		"policy.yaml":          "policy.staging.yaml",
+		"config/policy.json":   "config/policy.staging.json",
+		"policies/base.yml":    "policies/base.staging.yml",
+		"policy.d/policy.yaml": "policy.d/policy.staging.yaml",
+	}
+	for path, want := range tests {
+		if got := OverlayPath(path, "staging"); got != want {
+			t.Errorf("OverlayPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,493 @@
+package policy
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// TerraformOptions controls how ExportTerraform renders a policy
+type TerraformOptions struct {
+	// Members renders one google_*_iam_member per member instead of one
+	// authoritative google_*_iam_binding per role and condition
+	Members bool
+}
+
+// TerraformExport is a policy rendered as Terraform configuration for the
+// Google provider
+type TerraformExport struct {
+	Config []byte
+	// Roles and Bindings count the custom role and IAM resources in Config
+	Roles    int
+	Bindings int
+	// Warnings describe what could not be exported
+	Warnings []string
+}
+
+// customRoleID matches the role IDs GCP accepts for custom roles
+var customRoleID = regexp.MustCompile(`^[a-zA-Z0-9_.]{3,64}$`)
+
+// ExportTerraform renders the policy's bindings as Terraform resources.
+//
+// Each binding becomes a google_*_iam_binding (or, with opts.Members, one
+// google_*_iam_member per member) on its project, service account, secret,
+// key ring, crypto key, folder or organization. Bindings of the same role
+// and condition on the same target are merged, since binding resources are
+// authoritative for their role. Groups defined in the policy are expanded to
+// their members, nested groups included; other groups are kept as Google
+// groups if they are named by email.
+//
+// Custom roles become a google_project_iam_custom_role in each project that
+// binds them, or a google_organization_iam_custom_role for bindings on an
+// organization or a folder below one, and bindings refer to them by name.
+// Deny policies and access boundaries are not exported.
+func ExportTerraform(policy *Policy, opts TerraformOptions) *TerraformExport {
+	e := &tfExporter{
+		policy:   policy,
+		opts:     opts,
+		export:   &TerraformExport{},
+		roles:    make(map[string]*tfRole),
+		bindings: make(map[string]*tfBinding),
+		bound:    make(map[string]bool),
+		names:    make(map[string]bool),
+	}
+
+	for _, ref := range AllBindings(policy) {
+		e.addBinding(ref)
+	}
+
+	for _, role := range sortedNames(policy.Roles) {
+		if _, builtin := builtinRoles[role]; !builtin && !e.bound[role] {
+			e.warn("role %s is not bound anywhere; not exported, as custom roles are created where they are bound", role)
+		}
+	}
+	if len(policy.DenyPolicies) > 0 {
+		e.warn("deny policies are not exported (%s)", summarize(sortedNames(policy.DenyPolicies), 3))
+	}
+	if len(policy.Boundaries) > 0 {
+		e.warn("access boundaries are not exported (%s)", summarize(sortedNames(policy.Boundaries), 3))
+	}
+
+	e.write()
+	return e.export
+}
+
+type tfExporter struct {
+	policy *Policy
+	opts   TerraformOptions
+	export *TerraformExport
+
+	// roles and bindings are keyed by what makes them distinct in GCP, and
+	// kept in the order they were first needed
+	roles        map[string]*tfRole
+	roleOrder    []*tfRole
+	bindings     map[string]*tfBinding
+	bindingOrder []*tfBinding
+	bound        map[string]bool
+	// names holds the Terraform addresses in use, to keep them unique
+	names map[string]bool
+}
+
+type tfRole struct {
+	block hclBlock
+	// address is the Terraform address of the role resource
+	address string
+}
+
+type tfBinding struct {
+	block   hclBlock
+	members []string
+}
+
+// tfTarget is what a binding is attached to, as a Terraform resource type
+// prefix (google_project_iam) and the arguments that identify it
+type tfTarget struct {
+	prefix string
+	name   string
+	args   []hclAttr
+	// project and org are where custom roles bound on the target are
+	// created; project takes precedence
+	project string
+	org     string
+}
+
+func (e *tfExporter) warn(format string, args ...any) {
+	e.export.Warnings = append(e.export.Warnings, fmt.Sprintf(format, args...))
+}
+
+func (e *tfExporter) addBinding(ref BindingRef) {
+	target, err := e.target(ref)
+	if err != nil {
+		e.warn("%s: %v; skipped", ref.Label(), err)
+		return
+	}
+	role, err := e.roleReference(target, ref.Binding.Role)
+	if err != nil {
+		e.warn("%s: %v; skipped", ref.Label(), err)
+		return
+	}
+
+	members := e.expandMembers(ref)
+	if len(members) == 0 {
+		e.warn("%s: no members left to grant %s; skipped", ref.Label(), ref.Binding.Role)
+		return
+	}
+
+	var condition *hclBlock
+	conditionKey := ""
+	if c := ref.Binding.Condition; c != nil {
+		title := c.Title
+		if title == "" {
+			// GCP requires a title
+			title = ref.Label()
+		}
+		condition = &hclBlock{header: "condition", attrs: []hclAttr{{name: "title", value: hclString(title)}}}
+		if c.Description != "" {
+			condition.attrs = append(condition.attrs, hclAttr{name: "description", value: hclString(c.Description)})
+		}
+		condition.attrs = append(condition.attrs, hclAttr{name: "expression", value: hclString(c.Expression)})
+		conditionKey = c.Title + "\x00" + c.Description + "\x00" + c.Expression
+	}
+
+	roleName := strings.TrimPrefix(strings.TrimPrefix(ref.Binding.Role, "roles/"), "custom.")
+	args := append(slices.Clone(target.args), hclAttr{name: "role", value: role})
+
+	if !e.opts.Members {
+		key := strings.Join([]string{target.prefix, target.name, ref.Binding.Role, conditionKey}, "\x00")
+		binding, ok := e.bindings[key]
+		if !ok {
+			binding = &tfBinding{block: hclBlock{
+				header: e.resourceHeader(target.prefix+"_binding", target.name+"_"+roleName),
+				attrs:  args,
+			}}
+			if condition != nil {
+				binding.block.blocks = append(binding.block.blocks, *condition)
+			}
+			e.bindings[key] = binding
+			e.bindingOrder = append(e.bindingOrder, binding)
+		}
+		for _, member := range members {
+			if !slices.Contains(binding.members, member) {
+				binding.members = append(binding.members, member)
+			}
+		}
+		return
+	}
+
+	for _, member := range members {
+		key := strings.Join([]string{target.prefix, target.name, ref.Binding.Role, conditionKey, member}, "\x00")
+		if _, ok := e.bindings[key]; ok {
+			continue
+		}
+		binding := &tfBinding{block: hclBlock{
+			header: e.resourceHeader(target.prefix+"_member", target.name+"_"+roleName+"_"+member),
+			attrs:  append(slices.Clone(args), hclAttr{name: "member", value: hclString(member)}),
+		}}
+		if condition != nil {
+			binding.block.blocks = append(binding.block.blocks, *condition)
+		}
+		e.bindings[key] = binding
+		e.bindingOrder = append(e.bindingOrder, binding)
+	}
+}
+
+// target maps the binding's location to the Terraform resource that holds it
+func (e *tfExporter) target(ref BindingRef) (tfTarget, error) {
+	resource := ref.Resource
+	switch {
+	case resource == "":
+		return tfTarget{
+			prefix:  "google_project_iam",
+			name:    ref.Project,
+			args:    []hclAttr{{name: "project", value: hclString(ref.Project)}},
+			project: ref.Project,
+		}, nil
+	case strings.HasPrefix(resource, "organizations/"):
+		id := strings.TrimPrefix(resource, "organizations/")
+		return tfTarget{
+			prefix: "google_organization_iam",
+			name:   "org_" + id,
+			args:   []hclAttr{{name: "org_id", value: hclString(id)}},
+			org:    id,
+		}, nil
+	case strings.HasPrefix(resource, "folders/"):
+		target := tfTarget{
+			prefix: "google_folder_iam",
+			name:   "folder_" + strings.TrimPrefix(resource, "folders/"),
+			args:   []hclAttr{{name: "folder", value: hclString(resource)}},
+		}
+		ancestors, _ := Ancestors(e.policy, resource)
+		if root := ancestors[len(ancestors)-1]; strings.HasPrefix(root, "organizations/") {
+			target.org = strings.TrimPrefix(root, "organizations/")
+		}
+		return target, nil
+	}
+
+	segments := strings.Split(resource, "/")
+	_, kind := ResourceType(resource)
+	switch kind {
+	case "iam.googleapis.com/ServiceAccount":
+		local, _, _ := strings.Cut(segments[3], "@")
+		return tfTarget{
+			prefix:  "google_service_account_iam",
+			name:    ref.Project + "_" + local,
+			args:    []hclAttr{{name: "service_account_id", value: hclString(resource)}},
+			project: ref.Project,
+		}, nil
+	case "secretmanager.googleapis.com/Secret":
+		return tfTarget{
+			prefix: "google_secret_manager_secret_iam",
+			name:   ref.Project + "_" + segments[3],
+			args: []hclAttr{
+				{name: "project", value: hclString(ref.Project)},
+				{name: "secret_id", value: hclString(segments[3])},
+			},
+			project: ref.Project,
+		}, nil
+	case "cloudkms.googleapis.com/KeyRing":
+		return tfTarget{
+			prefix:  "google_kms_key_ring_iam",
+			name:    ref.Project + "_" + segments[5],
+			args:    []hclAttr{{name: "key_ring_id", value: hclString(resource)}},
+			project: ref.Project,
+		}, nil
+	case "cloudkms.googleapis.com/CryptoKey":
+		return tfTarget{
+			prefix:  "google_kms_crypto_key_iam",
+			name:    ref.Project + "_" + segments[5] + "_" + segments[7],
+			args:    []hclAttr{{name: "crypto_key_id", value: hclString(resource)}},
+			project: ref.Project,
+		}, nil
+	}
+	return tfTarget{}, fmt.Errorf("no Terraform resource for bindings on %s", resource)
+}
+
+// roleReference returns the HCL expression for role: the name of a custom
+// role resource created for the target, or the role itself if it is
+// predefined
+func (e *tfExporter) roleReference(target tfTarget, role string) (string, error) {
+	def, defined := e.policy.Roles[role]
+	if _, builtin := builtinRoles[role]; builtin || (!defined && !strings.HasPrefix(role, "roles/custom.")) {
+		return hclString(role), nil
+	}
+	if !defined {
+		return "", fmt.Errorf("undefined role %s", role)
+	}
+
+	id := strings.TrimPrefix(strings.TrimPrefix(role, "roles/"), "custom.")
+	var kind, scope, stem string
+	var args []hclAttr
+	switch {
+	case target.project != "":
+		kind, scope, stem = "google_project_iam_custom_role", target.project, target.project
+		args = []hclAttr{{name: "project", value: hclString(target.project)}}
+	case target.org != "":
+		kind, scope, stem = "google_organization_iam_custom_role", target.org, "org_"+target.org
+		args = []hclAttr{{name: "org_id", value: hclString(target.org)}}
+	default:
+		return "", fmt.Errorf("custom role %s needs an organization to be created in; set the folder's parent", role)
+	}
+
+	key := kind + "\x00" + scope + "\x00" + role
+	if r, ok := e.roles[key]; ok {
+		return r.address + ".name", nil
+	}
+
+	if !customRoleID.MatchString(id) {
+		e.warn("role %s: GCP rejects role ID %s (3 to 64 letters, digits, underscores and periods)", role, id)
+	}
+	if len(def.Permissions) == 0 {
+		e.warn("role %s has no permissions; GCP requires at least one", role)
+	}
+	e.bound[role] = true
+
+	header := e.resourceHeader(kind, stem+"_"+id)
+	args = append(args,
+		hclAttr{name: "role_id", value: hclString(id)},
+		hclAttr{name: "title", value: hclString(id)},
+		hclAttr{name: "permissions", list: quoteAll(def.Permissions)},
+	)
+	r := &tfRole{block: hclBlock{header: header, attrs: args}, address: kind + "." + resourceName(header)}
+	e.roles[key] = r
+	e.roleOrder = append(e.roleOrder, r)
+	return r.address + ".name", nil
+}
+
+// expandMembers replaces groups defined in the policy with their members,
+// recursively, and drops groups GCP cannot resolve
+func (e *tfExporter) expandMembers(ref BindingRef) []string {
+	var members []string
+	var expand func(list []string, visited map[string]bool)
+	expand = func(list []string, visited map[string]bool) {
+		for _, member := range list {
+			name, isGroup := strings.CutPrefix(member, "group:")
+			group, defined := e.policy.Groups[name]
+			switch {
+			case !isGroup:
+			case defined && len(group.Members) > 0:
+				if !visited[name] {
+					visited[name] = true
+					expand(group.Members, visited)
+				}
+				continue
+			case !strings.Contains(name, "@"):
+				e.warn("%s: group %s has no members to expand and is not a Google group email; dropped", ref.Label(), name)
+				continue
+			}
+			if !slices.Contains(members, member) {
+				members = append(members, member)
+			}
+		}
+	}
+	expand(ref.Binding.Members, map[string]bool{})
+	return members
+}
+
+// resourceHeader returns a resource block header with a Terraform name
+// derived from stem, unique for the resource type
+func (e *tfExporter) resourceHeader(kind, stem string) string {
+	name := terraformName(stem)
+	for i := 2; e.names[kind+"."+name]; i++ {
+		name = fmt.Sprintf("%s_%d", terraformName(stem), i)
+	}
+	e.names[kind+"."+name] = true
+	return fmt.Sprintf("resource %q %q", kind, name)
+}
+
+func (e *tfExporter) write() {
+	var b strings.Builder
+	b.WriteString("# IAM for the projects in this control plane policy, generated by\n")
+	b.WriteString("# gcp-emulator policy export. Groups are expanded to their members.\n")
+	if !e.opts.Members {
+		b.WriteString("#\n")
+		b.WriteString("# google_*_iam_binding resources are authoritative for their role:\n")
+		b.WriteString("# applying them removes members of the role that are not listed here.\n")
+	}
+
+	for _, role := range e.roleOrder {
+		b.WriteString("\n")
+		role.block.write(&b, "")
+	}
+	for _, binding := range e.bindingOrder {
+		if !e.opts.Members {
+			binding.block.attrs = append(binding.block.attrs, hclAttr{name: "members", list: quoteAll(binding.members)})
+		}
+		b.WriteString("\n")
+		binding.block.write(&b, "")
+	}
+
+	e.export.Config = []byte(b.String())
+	e.export.Roles = len(e.roleOrder)
+	e.export.Bindings = len(e.bindingOrder)
+}
+
+// hclBlock is an HCL block: attributes, aligned as terraform fmt does, then
+// nested blocks
+type hclBlock struct {
+	header string
+	attrs  []hclAttr
+	blocks []hclBlock
+}
+
+// hclAttr is an attribute with a single-line expression, or a list of them
+type hclAttr struct {
+	name  string
+	value string
+	list  []string
+}
+
+func (h *hclBlock) write(b *strings.Builder, indent string) {
+	width := 0
+	for _, attr := range h.attrs {
+		width = max(width, len(attr.name))
+	}
+
+	b.WriteString(indent + h.header + " {\n")
+	for _, attr := range h.attrs {
+		fmt.Fprintf(b, "%s  %-*s = ", indent, width, attr.name)
+		switch {
+		case attr.list == nil:
+			b.WriteString(attr.value)
+		case len(attr.list) == 0:
+			b.WriteString("[]")
+		default:
+			b.WriteString("[\n")
+			for _, item := range attr.list {
+				b.WriteString(indent + "    " + item + ",\n")
+			}
+			b.WriteString(indent + "  ]")
+		}
+		b.WriteString("\n")
+	}
+	for _, block := range h.blocks {
+		b.WriteString("\n")
+		block.write(b, indent+"  ")
+	}
+	b.WriteString(indent + "}\n")
+}
+
+// hclString quotes s as an HCL string literal, escaping template sequences
+// so that ${...} in a CEL expression stays literal
+func hclString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20:
+			fmt.Fprintf(&b, `\u%04x`, r)
+		case (r == '$' || r == '%') && strings.HasPrefix(s[i+1:], "{"):
+			// $${ and %%{ are literal ${ and %{
+			b.WriteRune(r)
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func quoteAll(items []string) []string {
+	quoted := make([]string, len(items))
+	for i, item := range items {
+		quoted[i] = hclString(item)
+	}
+	return quoted
+}
+
+// terraformName turns s into a Terraform resource name: lowercase letters,
+// digits and underscores, starting with a letter or underscore
+func terraformName(s string) string {
+	var b strings.Builder
+	underscore := false
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			underscore = false
+		} else if !underscore && b.Len() > 0 {
+			b.WriteByte('_')
+			underscore = true
+		}
+	}
+	name := strings.TrimSuffix(b.String(), "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+// resourceName returns the name in a resource block header
+func resourceName(header string) string {
+	fields := strings.Fields(header)
+	return strings.Trim(fields[len(fields)-1], `"`)
+}
@@ -0,0 +1,337 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// terraformShow is the part of terraform show -json output that is
+// imported: a state's values, or a plan's planned values and the
+// configuration that resolves references still unknown in them
+type terraformShow struct {
+	FormatVersion string           `json:"format_version"`
+	Values        *terraformValues `json:"values"`
+	PlannedValues *terraformValues `json:"planned_values"`
+	Configuration *terraformConfig `json:"configuration"`
+}
+
+type terraformValues struct {
+	RootModule terraformModule `json:"root_module"`
+}
+
+type terraformModule struct {
+	Resources    []terraformResource `json:"resources"`
+	ChildModules []terraformModule   `json:"child_modules"`
+}
+
+type terraformResource struct {
+	Address string          `json:"address"`
+	Mode    string          `json:"mode"`
+	Type    string          `json:"type"`
+	Values  json.RawMessage `json:"values"`
+}
+
+// terraformIAM holds the arguments of the Google provider's IAM resources
+// that the import reads
+type terraformIAM struct {
+	Project          string      `json:"project"`
+	OrgID            string      `json:"org_id"`
+	Folder           string      `json:"folder"`
+	ServiceAccountID string      `json:"service_account_id"`
+	SecretID         string      `json:"secret_id"`
+	KeyRingID        string      `json:"key_ring_id"`
+	CryptoKeyID      string      `json:"crypto_key_id"`
+	Role             string      `json:"role"`
+	Members          []string    `json:"members"`
+	Member           string      `json:"member"`
+	Condition        []Condition `json:"condition"`
+	PolicyData       string      `json:"policy_data"`
+
+	// Custom roles
+	RoleID      string   `json:"role_id"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+	Stage       string   `json:"stage"`
+	Deleted     bool     `json:"deleted"`
+}
+
+type terraformConfig struct {
+	RootModule terraformConfigModule `json:"root_module"`
+}
+
+type terraformConfigModule struct {
+	Resources   []terraformConfigResource `json:"resources"`
+	ModuleCalls map[string]struct {
+		Module terraformConfigModule `json:"module"`
+	} `json:"module_calls"`
+}
+
+type terraformConfigResource struct {
+	Address     string `json:"address"`
+	Expressions struct {
+		Role struct {
+			References []string `json:"references"`
+		} `json:"role"`
+	} `json:"expressions"`
+}
+
+// terraformIndex matches the instance keys in a resource address
+// (google_project_iam_member.m["key"], module.iam[0])
+var terraformIndex = regexp.MustCompile(`\[[^\]]*\]`)
+
+// ImportTerraform adds the custom roles and bindings of the Google
+// provider's IAM resources in data, the output of terraform show -json for
+// a state or a saved plan (its planned values). source names the file in
+// warnings.
+//
+// Custom roles (google_project_iam_custom_role,
+// google_organization_iam_custom_role) are added as by AddRole. The
+// google_*_iam_binding, _member and _policy resources of projects, folders,
+// organizations, service accounts, secrets, key rings and crypto keys add
+// bindings, members of the same role and condition merged. In a plan, a
+// role that refers to a custom role created by the same plan is resolved
+// through the configuration. Other IAM resources, and values not known
+// until apply, are reported and skipped; resources unrelated to IAM are
+// ignored.
+func (im *Importer) ImportTerraform(source string, data []byte) error {
+	var show terraformShow
+	if err := json.Unmarshal(data, &show); err != nil {
+		return fmt.Errorf("%s: failed to parse terraform show -json output: %w", source, err)
+	}
+
+	values := show.Values
+	if show.PlannedValues != nil {
+		values = show.PlannedValues
+	}
+	if show.FormatVersion == "" || values == nil {
+		return fmt.Errorf("%s: not terraform show -json output of a state or plan", source)
+	}
+
+	var resources []terraformResource
+	var collect func(m terraformModule)
+	collect = func(m terraformModule) {
+		for _, r := range m.Resources {
+			if r.Mode == "managed" && strings.Contains(r.Type, "_iam_") {
+				resources = append(resources, r)
+			}
+		}
+		for _, child := range m.ChildModules {
+			collect(child)
+		}
+	}
+	collect(values.RootModule)
+
+	args := make(map[string]terraformIAM, len(resources))
+	for _, r := range resources {
+		var v terraformIAM
+		if err := json.Unmarshal(r.Values, &v); err != nil {
+			return fmt.Errorf("%s: %s: failed to parse values: %w", source, r.Address, err)
+		}
+		args[r.Address] = v
+	}
+
+	roleRefs := make(map[string]string)
+	if show.Configuration != nil {
+		collectRoleReferences(show.Configuration.RootModule, "", roleRefs)
+	}
+
+	var targets []string
+	bindings := make(map[string][]Binding)
+	for _, r := range resources {
+		where := source + ": " + r.Address
+		v := args[r.Address]
+		owner, kind, _ := strings.Cut(r.Type, "_iam_")
+
+		if kind == "custom_role" {
+			im.importTerraformRole(where, owner, v)
+			continue
+		}
+		if kind != "binding" && kind != "member" && kind != "policy" {
+			im.warn(where, "%s resources are not imported; skipped", r.Type)
+			continue
+		}
+
+		target, err := terraformTarget(owner, v)
+		if err != nil {
+			im.warn(where, "%v; skipped", err)
+			continue
+		}
+
+		if kind == "policy" {
+			if v.PolicyData == "" {
+				im.warn(where, "policy_data is not known until apply; skipped")
+				continue
+			}
+			if err := im.ImportIAMPolicy(where, target, []byte(v.PolicyData)); err != nil {
+				im.Warnings = append(im.Warnings, err.Error()+"; skipped")
+			}
+			continue
+		}
+
+		role := v.Role
+		if ref, ok := roleRefs[terraformIndex.ReplaceAllString(r.Address, "")]; role == "" && ok {
+			role = terraformRoleName(args[ref], ref)
+		}
+		if role == "" {
+			im.warn(where, "role is not known until apply; skipped")
+			continue
+		}
+
+		binding := Binding{Role: role, Members: v.Members}
+		if v.Member != "" {
+			binding.Members = []string{v.Member}
+		}
+		if len(binding.Members) == 0 {
+			im.warn(where, "no members, or members not known until apply; skipped")
+			continue
+		}
+		if len(v.Condition) > 0 {
+			binding.Condition = &v.Condition[0]
+		}
+
+		binding, ok := im.translateBinding(where, binding)
+		if !ok {
+			continue
+		}
+		if _, ok := bindings[target]; !ok {
+			targets = append(targets, target)
+		}
+		bindings[target] = mergeBinding(bindings[target], binding)
+	}
+
+	for _, target := range targets {
+		if err := im.addBindings(source, target, bindings[target], "", 0); err != nil {
+			im.Warnings = append(im.Warnings, err.Error()+"; skipped")
+		}
+	}
+	return nil
+}
+
+// importTerraformRole adds a google_project_iam_custom_role or
+// google_organization_iam_custom_role
+func (im *Importer) importTerraformRole(where, owner string, v terraformIAM) {
+	name := terraformRoleName(v, owner+"_iam_custom_role")
+	if name == "" {
+		im.warn(where, "role_id or its project or organization is not known until apply; skipped")
+		return
+	}
+	role := CustomRole{
+		Name:                name,
+		Title:               v.Title,
+		Description:         v.Description,
+		IncludedPermissions: v.Permissions,
+		Stage:               v.Stage,
+		Deleted:             v.Deleted,
+	}
+	if err := im.AddRole(where, role); err != nil {
+		im.Warnings = append(im.Warnings, err.Error()+"; skipped")
+	}
+}
+
+// terraformRoleName returns the GCP name of the custom role with arguments
+// v, given its address or resource type, or "" if it is not known
+func terraformRoleName(v terraformIAM, address string) string {
+	switch {
+	case v.RoleID == "":
+		return ""
+	case strings.Contains(address, "google_project_iam_custom_role") && v.Project != "":
+		return "projects/" + strings.TrimPrefix(v.Project, "projects/") + "/roles/" + v.RoleID
+	case strings.Contains(address, "google_organization_iam_custom_role") && v.OrgID != "":
+		return "organizations/" + strings.TrimPrefix(v.OrgID, "organizations/") + "/roles/" + v.RoleID
+	}
+	return ""
+}
+
+// collectRoleReferences maps the address of each IAM resource in the
+// configuration whose role refers to a custom role resource to that
+// resource's address, instance keys omitted
+func collectRoleReferences(m terraformConfigModule, prefix string, refs map[string]string) {
+	for _, r := range m.Resources {
+		for _, ref := range r.Expressions.Role.References {
+			parts := strings.Split(ref, ".")
+			if len(parts) >= 2 && strings.HasSuffix(parts[0], "_iam_custom_role") {
+				refs[prefix+r.Address] = prefix + parts[0] + "." + terraformIndex.ReplaceAllString(parts[1], "")
+				break
+			}
+		}
+	}
+	for name, call := range m.ModuleCalls {
+		collectRoleReferences(call.Module, prefix+"module."+name+".", refs)
+	}
+}
+
+// terraformTarget returns the canonical name of what an IAM resource of the
+// owner type (google_project, google_kms_key_ring, ...) is attached to
+func terraformTarget(owner string, v terraformIAM) (string, error) {
+	var id string
+	switch owner {
+	case "google_project":
+		id = strings.TrimPrefix(v.Project, "projects/")
+	case "google_folder":
+		if v.Folder != "" {
+			id = "folders/" + strings.TrimPrefix(v.Folder, "folders/")
+		}
+	case "google_organization":
+		if v.OrgID != "" {
+			id = "organizations/" + strings.TrimPrefix(v.OrgID, "organizations/")
+		}
+	case "google_service_account":
+		id = v.ServiceAccountID
+	case "google_secret_manager_secret":
+		id = v.SecretID
+		if id != "" && !strings.HasPrefix(id, "projects/") {
+			id = "projects/" + strings.TrimPrefix(v.Project, "projects/") + "/secrets/" + id
+		}
+	case "google_kms_key_ring":
+		id = expandKMSID(v.KeyRingID, "keyRings")
+	case "google_kms_crypto_key":
+		id = expandKMSID(v.CryptoKeyID, "keyRings", "cryptoKeys")
+	default:
+		return "", fmt.Errorf("%s IAM resources are not imported", owner)
+	}
+	if id == "" {
+		return "", fmt.Errorf("%s IAM resource target is not known until apply", owner)
+	}
+	return id, nil
+}
+
+// expandKMSID expands the provider's short KMS IDs
+// ({project}/{location}/{keyring}[/{key}]) to canonical names
+func expandKMSID(id string, collections ...string) string {
+	parts := strings.Split(id, "/")
+	if strings.HasPrefix(id, "projects/") || len(parts) != 2+len(collections) {
+		return id
+	}
+	name := "projects/" + parts[0] + "/locations/" + parts[1]
+	for i, collection := range collections {
+		name += "/" + collection + "/" + parts[2+i]
+	}
+	return name
+}
+
+// mergeBinding adds binding to bindings, merging its members into a
+// binding of the same role and condition
+func mergeBinding(bindings []Binding, binding Binding) []Binding {
+	for i, b := range bindings {
+		if b.Role != binding.Role || !sameCondition(b.Condition, binding.Condition) {
+			continue
+		}
+		for _, member := range binding.Members {
+			if !containsString(b.Members, member) {
+				bindings[i].Members = append(bindings[i].Members, member)
+			}
+		}
+		return bindings
+	}
+	return append(bindings, binding)
+}
+
+func sameCondition(a, b *Condition) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,127 @@
+package policy
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func importTerraformFile(t *testing.T, im *Importer, path string) {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := im.ImportTerraform(path, data); err != nil {
+		t.Fatalf("import %s: %v", path, err)
+	}
+}
+
+func TestImportTerraformState(t *testing.T) {
+	im := NewImporter()
+	importTerraformFile(t, im, "../../testdata/terraform/state.json")
+	pol := im.Finish()
+
+	if result := Validate(pol); !result.Valid {
+		t.Fatalf("Expected valid policy, got %v", result.Errors)
+	}
+
+	role := pol.Roles["roles/custom.appDeployer"]
+	if strings.Join(role.Permissions, ",") != "cloudkms.cryptoKeys.encrypt,secretmanager.versions.access" {
+		t.Errorf("roles/custom.appDeployer = %+v, want the emulated permissions only", role)
+	}
+
+	project := pol.Projects["prod-project"]
+	if len(project.Bindings) != 3 {
+		t.Fatalf("got %d project bindings, want 3: %+v", len(project.Bindings), project.Bindings)
+	}
+	if b := project.Bindings[1]; b.Role != "roles/custom.appDeployer" {
+		t.Errorf("member binding role = %s, want roles/custom.appDeployer", b.Role)
+	}
+	oncall := project.Bindings[2]
+	if strings.Join(oncall.Members, ",") != "user:bob@example.com,user:carol@example.com" || oncall.Condition == nil || oncall.Condition.Title != "Expires 2026-12-31" {
+		t.Errorf("for_each members not merged into one conditional binding: %+v", oncall)
+	}
+	for _, name := range []string{"projects/prod-project/secrets/db-password", "projects/prod-project/locations/global/keyRings/app/cryptoKeys/signing"} {
+		if len(pol.Resources[name].Bindings) != 1 {
+			t.Errorf("%s bindings = %+v, want 1", name, pol.Resources[name].Bindings)
+		}
+	}
+
+	warnings := strings.Join(im.Warnings, "\n")
+	for _, want := range []string{
+		"google_project_iam_custom_role.app_deployer: role projects/prod-project/roles/appDeployer: dropped 1 permissions",
+		"google_project_iam_audit_config.all: google_project_iam_audit_config resources are not imported",
+		"module.app.google_storage_bucket_iam_member.assets: google_storage_bucket IAM resources are not imported",
+		"group platform-admins@example.com has no members",
+	} {
+		if !strings.Contains(warnings, want) {
+			t.Errorf("warnings missing %q:\n%s", want, warnings)
+		}
+	}
+	if strings.Contains(warnings, "google_secret_manager_secret.db_password") {
+		t.Errorf("resource unrelated to IAM reported:\n%s", warnings)
+	}
+}
+
+func TestImportTerraformPlan(t *testing.T) {
+	im := NewImporter()
+	importTerraformFile(t, im, "../../testdata/terraform/plan.json")
+	pol := im.Finish()
+
+	project := pol.Projects["ci-project"]
+	if len(project.Bindings) != 1 || project.Bindings[0].Role != "roles/custom.ciRunner" {
+		t.Errorf("role unknown until apply not resolved through the configuration: %+v", project.Bindings)
+	}
+	if _, ok := pol.Roles["roles/custom.ciRunner"]; !ok {
+		t.Error("planned custom role not imported")
+	}
+	if sa := project.ServiceAccounts["ci@ci-project.iam.gserviceaccount.com"]; len(sa.Bindings) != 1 {
+		t.Errorf("service account bindings = %+v, want 1", sa.Bindings)
+	}
+	if folder := pol.Folders["1234"]; len(folder.Bindings) != 1 || folder.Bindings[0].Members[0] != "user:erin@example.com" {
+		t.Errorf("folder policy_data not imported: %+v", folder)
+	}
+
+	warnings := strings.Join(im.Warnings, "\n")
+	if !strings.Contains(warnings, "google_project_iam_member.new_sa: no members, or members not known until apply") {
+		t.Errorf("unknown member not reported:\n%s", warnings)
+	}
+
+	decision := Evaluate(pol, Request{
+		Principal:  "serviceAccount:ci@ci-project.iam.gserviceaccount.com",
+		Resource:   "projects/ci-project/secrets/token",
+		Permission: "secretmanager.versions.access",
+	})
+	if !decision.Allowed {
+		t.Errorf("planned custom role binding denied: %s", decision.Reason)
+	}
+}
+
+func TestImportTerraformErrors(t *testing.T) {
+	im := NewImporter()
+	if err := im.ImportTerraform("x.json", []byte(`not json`)); err == nil || !strings.Contains(err.Error(), "failed to parse") {
+		t.Errorf("invalid JSON: error = %v", err)
+	}
+	if err := im.ImportTerraform("x.json", []byte(`{"bindings": []}`)); err == nil || !strings.Contains(err.Error(), "not terraform show -json output") {
+		t.Errorf("IAM policy instead of terraform output: error = %v", err)
+	}
+}
+
+func TestExpandKMSID(t *testing.T) {
+	tests := []struct {
+		id          string
+		collections []string
+		want        string
+	}{
+		{"p/global/app", []string{"keyRings"}, "projects/p/locations/global/keyRings/app"},
+		{"p/global/app/signing", []string{"keyRings", "cryptoKeys"}, "projects/p/locations/global/keyRings/app/cryptoKeys/signing"},
+		{"projects/p/locations/global/keyRings/app", []string{"keyRings"}, "projects/p/locations/global/keyRings/app"},
+	}
+	for _, tt := range tests {
+		if got := expandKMSID(tt.id, tt.collections...); got != tt.want {
+			t.Errorf("expandKMSID(%q) = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+}
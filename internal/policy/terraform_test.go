@@ -0,0 +1,142 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExportTerraform(t *testing.T) {
+	pol, err := Load("../../testdata/policy.yaml")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	export := ExportTerraform(pol, TerraformOptions{})
+	if len(export.Warnings) > 0 {
+		t.Errorf("Warnings = %v", export.Warnings)
+	}
+	if export.Roles != 2 || export.Bindings != 2 {
+		t.Errorf("Roles, Bindings = %d, %d, want 2, 2", export.Roles, export.Bindings)
+	}
+
+	config := string(export.Config)
+	for _, want := range []string{
+		`resource "google_project_iam_custom_role" "test_project_cirunner" {
+  project     = "test-project"
+  role_id     = "ciRunner"
+  title       = "ciRunner"
+  permissions = [
+    "secretmanager.secrets.get",
+    "secretmanager.versions.access",
+    "cloudkms.cryptoKeys.encrypt",
+  ]
+}`,
+		`resource "google_project_iam_binding" "test_project_developer" {
+  project = "test-project"
+  role    = google_project_iam_custom_role.test_project_developer.name
+  members = [
+    "user:alice@example.com",
+    "user:bob@example.com",
+  ]
+}`,
+		`  condition {
+    title      = "CI limited to production secrets"
+    expression = "resource.name.startsWith(\"projects/test-project/secrets/prod-\")"
+  }`,
+	} {
+		if !strings.Contains(config, want) {
+			t.Errorf("config missing:\n%s\n\ngot:\n%s", want, config)
+		}
+	}
+
+	members := ExportTerraform(pol, TerraformOptions{Members: true})
+	if members.Bindings != 3 || !strings.Contains(string(members.Config), `resource "google_project_iam_member" "test_project_developer_user_bob_example_com"`) {
+		t.Errorf("member export has %d bindings:\n%s", members.Bindings, members.Config)
+	}
+}
+
+func TestExportTerraformHierarchy(t *testing.T) {
+	pol, err := Load("../../testdata/hierarchy.yaml")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	config := string(ExportTerraform(pol, TerraformOptions{}).Config)
+	for _, want := range []string{
+		`resource "google_organization_iam_custom_role" "org_123456789_secretreader" {`,
+		`resource "google_folder_iam_binding" "folder_engineering_secretreader" {
+  folder  = "folders/engineering"
+  role    = google_organization_iam_custom_role.org_123456789_secretreader.name`,
+		`resource "google_organization_iam_binding" "org_123456789_viewer" {`,
+	} {
+		if !strings.Contains(config, want) {
+			t.Errorf("config missing:\n%s\n\ngot:\n%s", want, config)
+		}
+	}
+}
+
+func TestExportTerraformMembersAndWarnings(t *testing.T) {
+	pol := &Policy{
+		Roles: map[string]Role{
+			"roles/custom.reader": {Permissions: []string{"secretmanager.versions.access"}},
+			"roles/custom.unused": {Permissions: []string{"secretmanager.secrets.get"}},
+		},
+		Groups: map[string]Group{
+			"team":  {Members: []string{"group:leads", "user:a@example.com"}},
+			"leads": {Members: []string{"user:b@example.com", "group:team"}},
+			"empty": {},
+		},
+		Projects: map[string]Project{
+			"p": {Bindings: []Binding{
+				{Role: "roles/custom.reader", Members: []string{"group:team"}},
+				{Role: "roles/custom.reader", Members: []string{"user:a@example.com", "group:eng@example.com", "group:empty"}},
+				{Role: "roles/viewer", Members: []string{"user:c@example.com"}, Condition: &Condition{Expression: `request.time < timestamp("${cutoff}")`}},
+			}},
+		},
+		DenyPolicies: map[string]DenyPolicy{"guardrails": {}},
+	}
+
+	export := ExportTerraform(pol, TerraformOptions{})
+	config := string(export.Config)
+	for _, want := range []string{
+		`  members = [
+    "user:b@example.com",
+    "user:a@example.com",
+    "group:eng@example.com",
+  ]`,
+		`title      = "p[2]"`,
+		`expression = "request.time < timestamp(\"$${cutoff}\")"`,
+	} {
+		if !strings.Contains(config, want) {
+			t.Errorf("config missing:\n%s\n\ngot:\n%s", want, config)
+		}
+	}
+	if export.Roles != 1 || export.Bindings != 2 {
+		t.Errorf("Roles, Bindings = %d, %d, want 1, 2", export.Roles, export.Bindings)
+	}
+
+	warnings := strings.Join(export.Warnings, "\n")
+	for _, want := range []string{
+		"p[1]: group empty has no members to expand",
+		"role roles/custom.unused is not bound anywhere",
+		"deny policies are not exported (guardrails)",
+	} {
+		if !strings.Contains(warnings, want) {
+			t.Errorf("warnings missing %q:\n%s", want, warnings)
+		}
+	}
+}
+
+func TestTerraformName(t *testing.T) {
+	tests := map[string]string{
+		"test-project_ciRunner":            "test_project_cirunner",
+		"p_user:alice@example.com":         "p_user_alice_example_com",
+		"123_viewer":                       "_123_viewer",
+		"p_secretmanager.secretAccessor--": "p_secretmanager_secretaccessor",
+	}
+	for s, want := range tests {
+		if got := terraformName(s); got != want {
+			t.Errorf("terraformName(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
@@ -39,50 +39,122 @@ func Validate(policy *Policy) *ValidationResult {
 		}
 	}
 
+	// Check the resource hierarchy
+	for id, org := range policy.Organizations {
+		validateBindings(result, policy, "Organization "+id, org.Bindings)
+	}
+
+	for id, folder := range policy.Folders {
+		if folder.Parent == "" {
+			result.addError(fmt.Sprintf("Folder %s: no parent specified", id))
+		} else if _, err := Ancestors(policy, folder.Parent); err != nil {
+			result.addError(fmt.Sprintf("Folder %s: %v", id, err))
+		}
+		validateBindings(result, policy, "Folder "+id, folder.Bindings)
+	}
+
 	// Check projects
 	if len(policy.Projects) == 0 {
 		result.addWarning("No projects defined")
 	}
 
 	for projectName, project := range policy.Projects {
-		if len(project.Bindings) == 0 {
+		if len(project.Bindings) == 0 && project.Parent == "" && len(resourcesWithin(policy, projectName)) == 0 {
 			result.addWarning(fmt.Sprintf("Project %s has no bindings", projectName))
 		}
 
-		for i, binding := range project.Bindings {
-			// Check if role exists
-			if !strings.HasPrefix(binding.Role, "roles/") {
-				result.addError(fmt.Sprintf("Project %s binding %d: role must start with 'roles/'", projectName, i))
+		if project.Parent != "" {
+			if _, err := Ancestors(policy, project.Parent); err != nil {
+				result.addError(fmt.Sprintf("Project %s: %v", projectName, err))
 			}
+		}
+
+		validateBindings(result, policy, "Project "+projectName, project.Bindings)
 
-			// Check if custom role is defined
-			if strings.HasPrefix(binding.Role, "roles/custom.") {
-				if _, exists := policy.Roles[binding.Role]; !exists {
-					result.addError(fmt.Sprintf("Project %s binding %d: undefined role %s", projectName, i, binding.Role))
-				}
+		for email, sa := range project.ServiceAccounts {
+			scope := fmt.Sprintf("Project %s service account %s", projectName, email)
+			if !strings.Contains(email, "@") {
+				result.addError(fmt.Sprintf("%s: expected an email", scope))
+			} else if owner := ProjectFromEmail(email); owner != "" && owner != projectName {
+				result.addError(fmt.Sprintf("%s: belongs to project %s", scope, owner))
 			}
+			if len(sa.Bindings) == 0 {
+				result.addWarning(fmt.Sprintf("%s has no bindings", scope))
+			}
+			validateBindings(result, policy, scope, sa.Bindings)
+		}
+	}
+
+	// Check resource-level bindings
+	for name, resource := range policy.Resources {
+		scope := "Resource " + name
+		if err := validateResourceName(name); err != nil {
+			result.addError(fmt.Sprintf("%s: %v", scope, err))
+		} else if projectID := ProjectFromResource(name); !hasProject(policy, projectID) {
+			result.addError(fmt.Sprintf("%s: undefined project %s", scope, projectID))
+		}
+		if len(resource.Bindings) == 0 {
+			result.addWarning(fmt.Sprintf("%s has no bindings", scope))
+		}
+		validateBindings(result, policy, scope, resource.Bindings)
+	}
+
+	// Check deny policies
+	for name, deny := range policy.DenyPolicies {
+		validateDenyPolicy(result, policy, name, deny)
+	}
+
+	// Check workload identities
+	for principal := range policy.WorkloadIdentities {
+		if p, err := ParsePrincipal(principal); err != nil || p.Type != "principal" {
+			result.addError(fmt.Sprintf("Workload identity %s: expected a principal://%s{pool}/subject/{subject} identifier", principal, iamHost))
+		}
+	}
+
+	// Check access boundaries
+	for name, boundary := range policy.Boundaries {
+		validateBoundary(result, policy, name, boundary)
+	}
 
-			// Check members
-			if len(binding.Members) == 0 {
-				result.addError(fmt.Sprintf("Project %s binding %d: no members specified", projectName, i))
+	return result
+}
+
+// validateBindings checks bindings, prefixing errors with scope (e.g.
+// "Project test-project")
+func validateBindings(result *ValidationResult, policy *Policy, scope string, bindings []Binding) {
+	for i, binding := range bindings {
+		// Check if role exists
+		if !strings.HasPrefix(binding.Role, "roles/") {
+			result.addError(fmt.Sprintf("%s binding %d: role must start with 'roles/'", scope, i))
+		}
+
+		// Check if custom role is defined
+		if strings.HasPrefix(binding.Role, "roles/custom.") {
+			if _, exists := policy.Roles[binding.Role]; !exists {
+				result.addError(fmt.Sprintf("%s binding %d: undefined role %s", scope, i, binding.Role))
 			}
+		}
 
-			for _, member := range binding.Members {
-				if err := validatePrincipal(member, policy); err != nil {
-					result.addError(fmt.Sprintf("Project %s binding %d: %v", projectName, i, err))
-				}
+		// Check members
+		if len(binding.Members) == 0 {
+			result.addError(fmt.Sprintf("%s binding %d: no members specified", scope, i))
+		}
+
+		for _, member := range binding.Members {
+			if err := validatePrincipal(member, policy); err != nil {
+				result.addError(fmt.Sprintf("%s binding %d: %v", scope, i, err))
 			}
+		}
 
-			// Check condition syntax (basic)
-			if binding.Condition != nil {
-				if binding.Condition.Expression == "" {
-					result.addError(fmt.Sprintf("Project %s binding %d: condition has empty expression", projectName, i))
-				}
+		// Check condition syntax
+		if binding.Condition != nil {
+			if binding.Condition.Expression == "" {
+				result.addError(fmt.Sprintf("%s binding %d: condition has empty expression", scope, i))
+			} else if _, err := ParseCondition(binding.Condition.Expression); err != nil {
+				result.addError(fmt.Sprintf("%s binding %d: invalid condition expression: %v", scope, i, err))
 			}
 		}
 	}
-
-	return result
 }
 
 func (r *ValidationResult) addError(msg string) {
@@ -101,37 +173,23 @@ func validatePermission(perm string) error {
 	}
 
 	service := parts[0]
-	if service != "secretmanager" && service != "cloudkms" {
-		return fmt.Errorf("unknown service in permission: %s (expected secretmanager or cloudkms)", service)
+	if service != "secretmanager" && service != "cloudkms" && service != "iam" {
+		return fmt.Errorf("unknown service in permission: %s (expected secretmanager, cloudkms, or iam)", service)
 	}
 
 	return nil
 }
 
 func validatePrincipal(principal string, policy *Policy) error {
-	if principal == "allUsers" || principal == "allAuthenticatedUsers" {
-		return nil
+	p, err := ParsePrincipal(principal)
+	if err != nil {
+		return err
 	}
 
-	parts := strings.SplitN(principal, ":", 2)
-	if len(parts) != 2 {
-		return fmt.Errorf("invalid principal format: %s (expected type:identifier)", principal)
-	}
-
-	principalType := parts[0]
-	identifier := parts[1]
-
-	switch principalType {
-	case "user", "serviceAccount":
-		if !strings.Contains(identifier, "@") {
-			return fmt.Errorf("invalid %s: %s (expected email format)", principalType, identifier)
-		}
-	case "group":
-		if _, exists := policy.Groups[identifier]; !exists {
-			return fmt.Errorf("undefined group: %s", identifier)
+	if p.Type == "group" {
+		if _, exists := policy.Groups[p.ID]; !exists {
+			return fmt.Errorf("undefined group: %s", p.ID)
 		}
-	default:
-		return fmt.Errorf("unknown principal type: %s (expected user, serviceAccount, or group)", principalType)
 	}
 
 	return nil
@@ -0,0 +1,260 @@
+package policytest
+
+import (
+	"sort"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+// Coverage records which parts of a policy were exercised by test cases.
+//
+// A binding is exercised by a case when the case's principal matches one of
+// its members and its role has the case's permission, i.e. the binding
+// could decide the case (whatever its condition says). The role permission
+// is exercised at the same time. A conditional binding has two branches,
+// true and false, each exercised when the condition evaluated that way.
+type Coverage struct {
+	PolicyFile string              `json:"policy_file"`
+	Bindings   []BindingCoverage   `json:"bindings"`
+	Roles      []RoleCoverage      `json:"roles"`
+	Conditions []ConditionCoverage `json:"conditions"`
+}
+
+// BindingCoverage is the coverage of one binding
+type BindingCoverage struct {
+	Project string `json:"project"`
+	// Resource is set for bindings on a resource below the project
+	Resource string `json:"resource,omitempty"`
+	Index    int    `json:"index"`
+	Role     string `json:"role"`
+	// Tests is the number of cases that exercised the binding
+	Tests int `json:"tests"`
+}
+
+// RoleCoverage is the coverage of a role's permissions
+type RoleCoverage struct {
+	Role        string               `json:"role"`
+	Permissions []PermissionCoverage `json:"permissions"`
+}
+
+// PermissionCoverage is the coverage of one role permission
+type PermissionCoverage struct {
+	Permission string `json:"permission"`
+	Tests      int    `json:"tests"`
+}
+
+// ConditionCoverage is the branch coverage of a conditional binding
+type ConditionCoverage struct {
+	Project    string `json:"project"`
+	Resource   string `json:"resource,omitempty"`
+	Index      int    `json:"index"`
+	Role       string `json:"role"`
+	Title      string `json:"title,omitempty"`
+	Expression string `json:"expression"`
+	// True and False count cases where the condition evaluated that way
+	True  int `json:"true"`
+	False int `json:"false"`
+	// Errors counts cases where the condition failed to evaluate
+	Errors int `json:"errors,omitempty"`
+}
+
+// Summary is a covered/total count
+type Summary struct {
+	Covered int     `json:"covered"`
+	Total   int     `json:"total"`
+	Percent float64 `json:"percent"`
+}
+
+func newSummary(covered, total int) Summary {
+	s := Summary{Covered: covered, Total: total, Percent: 100}
+	if total > 0 {
+		s.Percent = float64(covered) * 100 / float64(total)
+	}
+	return s
+}
+
+// Add combines two summaries
+func (s Summary) Add(o Summary) Summary {
+	return newSummary(s.Covered+o.Covered, s.Total+o.Total)
+}
+
+// Covered reports whether any case exercised the binding
+func (b BindingCoverage) Covered() bool {
+	return b.Tests > 0
+}
+
+// Covered reports whether any permission of the role was exercised
+func (r RoleCoverage) Covered() bool {
+	for _, p := range r.Permissions {
+		if p.Tests > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// CoverageFor computes the coverage of pol by the given results, which must
+// have been evaluated against pol. Roles are those bound in the policy plus
+// custom roles defined but never bound.
+func CoverageFor(pol *policy.Policy, results []Result) *Coverage {
+	type bindingKey struct {
+		project  string
+		resource string
+		index    int
+	}
+	bindingTests := make(map[bindingKey]int)
+	permTests := make(map[string]map[string]int)
+	branches := make(map[bindingKey]*ConditionCoverage)
+
+	for _, r := range results {
+		// Impersonation steps exercise the bindings that allow them
+		var checks []policy.BindingResult
+		var permissions []string
+		for _, d := range append(r.Decision.Delegation, r.Decision) {
+			for _, checked := range d.Checked {
+				checks = append(checks, checked)
+				permissions = append(permissions, d.Request.Permission)
+			}
+		}
+
+		for i, checked := range checks {
+			if checked.Member == "" || !checked.HasPermission {
+				continue
+			}
+
+			key := bindingKey{checked.Project, checked.Resource, checked.Index}
+			bindingTests[key]++
+
+			role := checked.Binding.Role
+			if permTests[role] == nil {
+				permTests[role] = make(map[string]int)
+			}
+			permTests[role][permissions[i]]++
+
+			if checked.Binding.Condition == nil {
+				continue
+			}
+			branch := branches[key]
+			if branch == nil {
+				branch = &ConditionCoverage{}
+				branches[key] = branch
+			}
+			switch {
+			case checked.ConditionError != nil:
+				branch.Errors++
+			case checked.ConditionMet != nil && *checked.ConditionMet:
+				branch.True++
+			default:
+				branch.False++
+			}
+		}
+	}
+
+	coverage := &Coverage{}
+	roles := make(map[string]bool)
+
+	for _, ref := range policy.AllBindings(pol) {
+		binding := ref.Binding
+		key := bindingKey{ref.Project, ref.Resource, ref.Index}
+		roles[binding.Role] = true
+
+		coverage.Bindings = append(coverage.Bindings, BindingCoverage{
+			Project:  ref.Project,
+			Resource: ref.Resource,
+			Index:    ref.Index,
+			Role:     binding.Role,
+			Tests:    bindingTests[key],
+		})
+
+		if binding.Condition == nil {
+			continue
+		}
+		cond := ConditionCoverage{}
+		if branch := branches[key]; branch != nil {
+			cond = *branch
+		}
+		cond.Project = ref.Project
+		cond.Resource = ref.Resource
+		cond.Index = ref.Index
+		cond.Role = binding.Role
+		cond.Title = binding.Condition.Title
+		cond.Expression = binding.Condition.Expression
+		coverage.Conditions = append(coverage.Conditions, cond)
+	}
+
+	for role := range pol.Roles {
+		roles[role] = true
+	}
+	names := make([]string, 0, len(roles))
+	for role := range roles {
+		names = append(names, role)
+	}
+	sort.Strings(names)
+
+	for _, role := range names {
+		rc := RoleCoverage{Role: role}
+		permissions, _ := policy.RolePermissions(pol, role)
+		for _, perm := range permissions {
+			rc.Permissions = append(rc.Permissions, PermissionCoverage{Permission: perm, Tests: permTests[role][perm]})
+		}
+		coverage.Roles = append(coverage.Roles, rc)
+	}
+
+	return coverage
+}
+
+// BindingSummary counts exercised bindings
+func (c *Coverage) BindingSummary() Summary {
+	covered := 0
+	for _, b := range c.Bindings {
+		if b.Covered() {
+			covered++
+		}
+	}
+	return newSummary(covered, len(c.Bindings))
+}
+
+// RoleSummary counts roles with at least one exercised permission
+func (c *Coverage) RoleSummary() Summary {
+	covered := 0
+	for _, r := range c.Roles {
+		if r.Covered() {
+			covered++
+		}
+	}
+	return newSummary(covered, len(c.Roles))
+}
+
+// PermissionSummary counts exercised role permissions
+func (c *Coverage) PermissionSummary() Summary {
+	covered, total := 0, 0
+	for _, r := range c.Roles {
+		for _, p := range r.Permissions {
+			total++
+			if p.Tests > 0 {
+				covered++
+			}
+		}
+	}
+	return newSummary(covered, total)
+}
+
+// ConditionSummary counts exercised condition branches (two per condition)
+func (c *Coverage) ConditionSummary() Summary {
+	covered := 0
+	for _, cond := range c.Conditions {
+		if cond.True > 0 {
+			covered++
+		}
+		if cond.False > 0 {
+			covered++
+		}
+	}
+	return newSummary(covered, 2*len(c.Conditions))
+}
+
+// Total combines bindings, permissions and condition branches. Roles are
+// left out since they are covered whenever one of their permissions is.
+func (c *Coverage) Total() Summary {
+	return c.BindingSummary().Add(c.PermissionSummary()).Add(c.ConditionSummary())
+}
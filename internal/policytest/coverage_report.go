@@ -0,0 +1,182 @@
+package policytest
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+// CoverageSummary is the per-category and total coverage
+type CoverageSummary struct {
+	Bindings    Summary `json:"bindings"`
+	Roles       Summary `json:"roles"`
+	Permissions Summary `json:"permissions"`
+	Conditions  Summary `json:"condition_branches"`
+	Total       Summary `json:"total"`
+}
+
+// Summary returns the coverage summary
+func (c *Coverage) Summary() CoverageSummary {
+	return CoverageSummary{
+		Bindings:    c.BindingSummary(),
+		Roles:       c.RoleSummary(),
+		Permissions: c.PermissionSummary(),
+		Conditions:  c.ConditionSummary(),
+		Total:       c.Total(),
+	}
+}
+
+// Untested lists everything no test case exercised, one line per item
+func (c *Coverage) Untested() []string {
+	var lines []string
+	for _, b := range c.Bindings {
+		if !b.Covered() {
+			lines = append(lines, fmt.Sprintf("binding %s %s", policy.BindingLabel(b.Project, b.Resource, b.Index), b.Role))
+		}
+	}
+	for _, r := range c.Roles {
+		for _, p := range r.Permissions {
+			if p.Tests == 0 {
+				lines = append(lines, fmt.Sprintf("permission %s in %s", p.Permission, r.Role))
+			}
+		}
+	}
+	for _, cond := range c.Conditions {
+		for _, branch := range cond.missingBranches() {
+			lines = append(lines, fmt.Sprintf("condition %s %s: %s branch", policy.BindingLabel(cond.Project, cond.Resource, cond.Index), cond.label(), branch))
+		}
+	}
+	return lines
+}
+
+func (c ConditionCoverage) missingBranches() []string {
+	var missing []string
+	if c.True == 0 {
+		missing = append(missing, "true")
+	}
+	if c.False == 0 {
+		missing = append(missing, "false")
+	}
+	return missing
+}
+
+func (c ConditionCoverage) label() string {
+	if c.Title != "" {
+		return fmt.Sprintf("%q", c.Title)
+	}
+	return c.Expression
+}
+
+// WriteCoverageText writes a plain-text coverage report
+func WriteCoverageText(w io.Writer, c *Coverage) error {
+	s := c.Summary()
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "Coverage of %s\n", c.PolicyFile)
+	fmt.Fprintf(&out, "  Bindings:           %s\n", s.Bindings)
+	fmt.Fprintf(&out, "  Roles:              %s\n", s.Roles)
+	fmt.Fprintf(&out, "  Permissions:        %s\n", s.Permissions)
+	fmt.Fprintf(&out, "  Condition branches: %s\n", s.Conditions)
+	fmt.Fprintf(&out, "  Total:              %s\n", s.Total)
+
+	if untested := c.Untested(); len(untested) > 0 {
+		fmt.Fprintf(&out, "\nNever tested:\n")
+		for _, line := range untested {
+			fmt.Fprintf(&out, "  %s\n", line)
+		}
+	}
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// String formats a summary as "covered/total (percent%)"
+func (s Summary) String() string {
+	return fmt.Sprintf("%d/%d (%.1f%%)", s.Covered, s.Total, s.Percent)
+}
+
+// WriteCoverageJSON writes the coverage details and summary as JSON
+func WriteCoverageJSON(w io.Writer, coverages []*Coverage) error {
+	type report struct {
+		*Coverage
+		Summary  CoverageSummary `json:"summary"`
+		Untested []string        `json:"untested"`
+	}
+
+	reports := make([]report, 0, len(coverages))
+	for _, c := range coverages {
+		reports = append(reports, report{Coverage: c, Summary: c.Summary(), Untested: c.Untested()})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(reports); err != nil {
+		return fmt.Errorf("failed to encode coverage: %w", err)
+	}
+	return nil
+}
+
+var coverageHTML = template.Must(template.New("coverage").Funcs(template.FuncMap{
+	"pct": func(s Summary) string { return fmt.Sprintf("%.1f%%", s.Percent) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Policy test coverage</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 10px; text-align: left; }
+th { background: #f3f3f3; }
+.covered { background: #e6f4ea; }
+.missed { background: #fce8e6; }
+code { font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>Policy test coverage</h1>
+{{range .}}
+<h2>{{.PolicyFile}}</h2>
+{{with .Summary}}
+<table>
+<tr><th>Category</th><th>Covered</th><th>Total</th><th>%</th></tr>
+<tr><td>Bindings</td><td>{{.Bindings.Covered}}</td><td>{{.Bindings.Total}}</td><td>{{pct .Bindings}}</td></tr>
+<tr><td>Roles</td><td>{{.Roles.Covered}}</td><td>{{.Roles.Total}}</td><td>{{pct .Roles}}</td></tr>
+<tr><td>Permissions</td><td>{{.Permissions.Covered}}</td><td>{{.Permissions.Total}}</td><td>{{pct .Permissions}}</td></tr>
+<tr><td>Condition branches</td><td>{{.Conditions.Covered}}</td><td>{{.Conditions.Total}}</td><td>{{pct .Conditions}}</td></tr>
+<tr><th>Total</th><th>{{.Total.Covered}}</th><th>{{.Total.Total}}</th><th>{{pct .Total}}</th></tr>
+</table>
+{{end}}
+<h3>Bindings</h3>
+<table>
+<tr><th>Project</th><th>#</th><th>Role</th><th>Tests</th></tr>
+{{range .Bindings}}<tr class="{{if .Covered}}covered{{else}}missed{{end}}"><td>{{if .Resource}}{{.Resource}}{{else}}{{.Project}}{{end}}</td><td>{{.Index}}</td><td><code>{{.Role}}</code></td><td>{{.Tests}}</td></tr>
+{{end}}</table>
+<h3>Role permissions</h3>
+<table>
+<tr><th>Role</th><th>Permission</th><th>Tests</th></tr>
+{{range $r := .Roles}}{{range .Permissions}}<tr class="{{if .Tests}}covered{{else}}missed{{end}}"><td><code>{{$r.Role}}</code></td><td><code>{{.Permission}}</code></td><td>{{.Tests}}</td></tr>
+{{end}}{{end}}</table>
+{{if .Conditions}}
+<h3>Condition branches</h3>
+<table>
+<tr><th>Project</th><th>#</th><th>Condition</th><th>True</th><th>False</th></tr>
+{{range .Conditions}}<tr><td>{{if .Resource}}{{.Resource}}{{else}}{{.Project}}{{end}}</td><td>{{.Index}}</td><td>{{if .Title}}{{.Title}}<br>{{end}}<code>{{.Expression}}</code></td><td class="{{if .True}}covered{{else}}missed{{end}}">{{.True}}</td><td class="{{if .False}}covered{{else}}missed{{end}}">{{.False}}</td></tr>
+{{end}}</table>
+{{end}}
+{{end}}
+</body>
+</html>
+`))
+
+// WriteCoverageHTML writes a standalone HTML coverage report
+func WriteCoverageHTML(w io.Writer, coverages []*Coverage) error {
+	if err := coverageHTML.Execute(w, coverages); err != nil {
+		return fmt.Errorf("failed to render coverage HTML: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+package policytest
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+func runSuite(t *testing.T) *SuiteResult {
+	t.Helper()
+
+	suite, err := Load("testdata/suite.yaml")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	pol, err := policy.Load(suite.PolicyPath())
+	if err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+
+	result := Run(pol, suite, time.Now())
+	result.PolicyFile = suite.PolicyPath()
+	return result
+}
+
+func TestLoad(t *testing.T) {
+	suite, err := Load("testdata/suite.yaml")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if suite.Name() != "suite" || len(suite.Cases) != 3 {
+		t.Errorf("suite = %s with %d cases", suite.Name(), len(suite.Cases))
+	}
+	if want := filepath.Join("..", "..", "testdata", "policy.yaml"); suite.PolicyPath() != want {
+		t.Errorf("PolicyPath() = %s, want %s", suite.PolicyPath(), want)
+	}
+	if c := suite.Cases[1]; c.Expect != ExpectAllow || !c.Request.Time.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("case 2 = %+v", c)
+	}
+
+	tests := map[string]string{
+		"missing fields": "tests:\n  - name: x\n    expect: allow\n",
+		"bad expect":     "tests:\n  - principal: user:a@example.com\n    resource: projects/p\n    permission: p.q.r\n    expect: maybe\n",
+		"no tests":       "policy: policy.yaml\n",
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "suite.yaml")
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := Load(path)
+			if err == nil || !strings.Contains(err.Error(), path) {
+				t.Errorf("Load() error = %v, want error naming %s", err, path)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	result := runSuite(t)
+
+	if len(result.Results) != 3 {
+		t.Fatalf("got %d results, want 3", len(result.Results))
+	}
+	for i, want := range []bool{true, true, false} {
+		if got := result.Results[i].Passed(); got != want {
+			t.Errorf("case %d passed = %v, want %v (%s)", i+1, got, want, result.Results[i].Decision.Reason)
+		}
+	}
+	if result.Failures() != 1 {
+		t.Errorf("Failures() = %d, want 1", result.Failures())
+	}
+	if msg := result.Results[2].FailureMessage(); msg != "expected allow, got deny" {
+		t.Errorf("FailureMessage() = %q", msg)
+	}
+}
+
+func TestRunSimulatedTime(t *testing.T) {
+	pol, err := policy.Load("../../testdata/conditions.yaml")
+	if err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+
+	suite := &Suite{Cases: []Case{
+		{
+			Name:       "on-call access before expiry",
+			Principal:  "user:oncall@example.com",
+			Resource:   "projects/test-project/secrets/db-password",
+			Permission: "secretmanager.versions.access",
+			Expect:     ExpectAllow,
+		},
+		{
+			Name:       "admin may grant secret access",
+			Principal:  "user:admin@example.com",
+			Resource:   "projects/test-project/secrets/db-password",
+			Permission: "secretmanager.secrets.setIamPolicy",
+			Expect:     ExpectAllow,
+			Request: Request{
+				Time: time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC),
+				API:  map[string]any{"iam.googleapis.com/modifiedGrantsByRole": []any{"roles/secretmanager.secretAccessor"}},
+			},
+		},
+	}}
+
+	before := Run(pol, suite, time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC))
+	after := Run(pol, suite, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	if before.Failures() != 0 {
+		t.Errorf("before expiry: %d failures (%s)", before.Failures(), before.Results[0].Decision.Reason)
+	}
+	if !after.Results[1].Passed() {
+		t.Errorf("case with its own time = %s, want pass", after.Results[1].Decision.Reason)
+	}
+	if after.Results[0].Passed() {
+		t.Error("after expiry: on-call access passed, want deny")
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, []*SuiteResult{runSuite(t)}); err != nil {
+		t.Fatalf("WriteJUnit() error = %v", err)
+	}
+
+	var report junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("invalid XML: %v\n%s", err, buf.String())
+	}
+
+	if report.Tests != 3 || report.Failures != 1 || len(report.Suites) != 1 {
+		t.Fatalf("report = %+v", report)
+	}
+	failed := report.Suites[0].Cases[2]
+	if failed.Name != "wrong expectation <&>" || failed.Failure == nil || !strings.Contains(failed.Failure.Text, "user:mallory@example.com") {
+		t.Errorf("failed case = %+v", failed)
+	}
+	if report.Suites[0].Cases[0].Failure != nil {
+		t.Error("Expected passing case without failure")
+	}
+}
+
+func TestWriteTAP(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteTAP(&buf, []*SuiteResult{runSuite(t)}); err != nil {
+		t.Fatalf("WriteTAP() error = %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		"TAP version 13\n1..3\n",
+		"ok 1 - developers can read secrets\n",
+		"ok 2 - CI can read prod secrets\n",
+		"not ok 3 - wrong expectation <&>\n  ---\n",
+		`  message: "expected allow, got deny"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("TAP output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	result := runSuite(t)
+
+	pol, err := policy.Load(result.PolicyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := CoverageFor(pol, result.Results)
+
+	// Both bindings are exercised: developers by alice, ciRunner by the CI case
+	if s := c.BindingSummary(); s.Covered != 2 || s.Total != 2 {
+		t.Errorf("BindingSummary() = %s", s)
+	}
+	// access is exercised in both roles; 4 other permissions are not
+	if s := c.PermissionSummary(); s.Covered != 2 || s.Total != 6 {
+		t.Errorf("PermissionSummary() = %s", s)
+	}
+	// Only the true branch of the CI condition is exercised
+	if s := c.ConditionSummary(); s.Covered != 1 || s.Total != 2 {
+		t.Errorf("ConditionSummary() = %s", s)
+	}
+	if s := c.Total(); s.Covered != 5 || s.Total != 10 || s.Percent != 50 {
+		t.Errorf("Total() = %s", s)
+	}
+
+	untested := strings.Join(c.Untested(), "\n")
+	for _, want := range []string{
+		"permission cloudkms.cryptoKeys.get in roles/custom.developer",
+		`condition test-project[1] "CI limited to production secrets": false branch`,
+	} {
+		if !strings.Contains(untested, want) {
+			t.Errorf("Untested() missing %q:\n%s", want, untested)
+		}
+	}
+
+	var text, js, html bytes.Buffer
+	if err := WriteCoverageText(&text, c); err != nil {
+		t.Fatalf("WriteCoverageText() error = %v", err)
+	}
+	if !strings.Contains(text.String(), "Condition branches: 1/2 (50.0%)") {
+		t.Errorf("text report:\n%s", text.String())
+	}
+
+	if err := WriteCoverageJSON(&js, []*Coverage{c}); err != nil {
+		t.Fatalf("WriteCoverageJSON() error = %v", err)
+	}
+	var decoded []struct {
+		Summary CoverageSummary `json:"summary"`
+	}
+	if err := json.Unmarshal(js.Bytes(), &decoded); err != nil || len(decoded) != 1 || decoded[0].Summary.Total.Covered != 5 {
+		t.Errorf("JSON report = %s (err %v)", js.String(), err)
+	}
+
+	if err := WriteCoverageHTML(&html, []*Coverage{c}); err != nil {
+		t.Fatalf("WriteCoverageHTML() error = %v", err)
+	}
+	if !strings.Contains(html.String(), "<td>Condition branches</td><td>1</td><td>2</td><td>50.0%</td>") {
+		t.Errorf("HTML report missing condition summary")
+	}
+}
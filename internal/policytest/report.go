@@ -0,0 +1,153 @@
+package policytest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// FailureMessage describes why a case failed
+func (r Result) FailureMessage() string {
+	return fmt.Sprintf("expected %s, got %s", r.Case.Expect, r.Got())
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	File       string          `xml:"file,attr,omitempty"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Time       string          `xml:"time,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Cases      []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes results as JUnit XML, one testsuite per suite file
+func WriteJUnit(w io.Writer, results []*SuiteResult) error {
+	report := junitTestSuites{Name: "gcp-emulator policy test"}
+	var total float64
+
+	for _, sr := range results {
+		suite := junitTestSuite{
+			Name:     sr.Suite.Name(),
+			File:     sr.Suite.Path,
+			Tests:    len(sr.Results),
+			Failures: sr.Failures(),
+			Time:     seconds(sr.Duration.Seconds()),
+		}
+		if sr.PolicyFile != "" {
+			suite.Properties = []junitProperty{{Name: "policy", Value: sr.PolicyFile}}
+		}
+
+		for _, r := range sr.Results {
+			tc := junitTestCase{
+				Name:      r.Case.DisplayName(),
+				Classname: sr.Suite.Name(),
+				Time:      seconds(r.Duration.Seconds()),
+			}
+			if !r.Passed() {
+				tc.Failure = &junitFailure{
+					Message: r.FailureMessage(),
+					Type:    "AuthorizationMismatch",
+					Text:    failureDetail(r),
+				}
+			}
+			suite.Cases = append(suite.Cases, tc)
+		}
+
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		total += sr.Duration.Seconds()
+		report.Suites = append(report.Suites, suite)
+	}
+	report.Time = seconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return fmt.Errorf("failed to encode JUnit XML: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// WriteTAP writes results in TAP version 13, numbering cases across suites
+func WriteTAP(w io.Writer, results []*SuiteResult) error {
+	total := 0
+	for _, sr := range results {
+		total += len(sr.Results)
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "TAP version 13\n1..%d\n", total)
+
+	n := 0
+	for _, sr := range results {
+		fmt.Fprintf(&out, "# %s\n", sr.Suite.Path)
+		for _, r := range sr.Results {
+			n++
+			status := "ok"
+			if !r.Passed() {
+				status = "not ok"
+			}
+			fmt.Fprintf(&out, "%s %d - %s\n", status, n, tapEscape(r.Case.DisplayName()))
+
+			if !r.Passed() {
+				fmt.Fprintf(&out, "  ---\n")
+				fmt.Fprintf(&out, "  message: %q\n", r.FailureMessage())
+				fmt.Fprintf(&out, "  principal: %q\n", r.Case.Principal)
+				fmt.Fprintf(&out, "  resource: %q\n", r.Case.Resource)
+				fmt.Fprintf(&out, "  permission: %q\n", r.Case.Permission)
+				fmt.Fprintf(&out, "  reason: %q\n", r.Decision.Reason)
+				fmt.Fprintf(&out, "  ...\n")
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+func failureDetail(r Result) string {
+	return fmt.Sprintf("principal: %s\nresource: %s\npermission: %s\nreason: %s",
+		r.Case.Principal, r.Case.Resource, r.Case.Permission, r.Decision.Reason)
+}
+
+// tapEscape escapes '#', which starts a directive in a TAP description
+func tapEscape(s string) string {
+	return strings.ReplaceAll(s, "#", `\#`)
+}
+
+func seconds(s float64) string {
+	return fmt.Sprintf("%.6f", s)
+}
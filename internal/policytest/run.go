@@ -0,0 +1,80 @@
+package policytest
+
+import (
+	"time"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+// Result is the outcome of one test case
+type Result struct {
+	Case     Case
+	Decision *policy.Decision
+	Duration time.Duration
+}
+
+// Passed reports whether the decision matched the expectation
+func (r Result) Passed() bool {
+	return r.Decision.Allowed == (r.Case.Expect == ExpectAllow)
+}
+
+// Got returns the actual outcome as "allow" or "deny"
+func (r Result) Got() string {
+	if r.Decision.Allowed {
+		return ExpectAllow
+	}
+	return ExpectDeny
+}
+
+// SuiteResult is the outcome of running a suite against a policy
+type SuiteResult struct {
+	Suite      *Suite
+	PolicyFile string
+	Results    []Result
+	Duration   time.Duration
+}
+
+// Failures returns the number of failed cases
+func (s *SuiteResult) Failures() int {
+	failures := 0
+	for _, r := range s.Results {
+		if !r.Passed() {
+			failures++
+		}
+	}
+	return failures
+}
+
+// Run evaluates every case in the suite against pol. Cases without a
+// request time are evaluated at now, so results are stable within a run;
+// pass a fixed time to simulate one.
+func Run(pol *policy.Policy, suite *Suite, now time.Time) *SuiteResult {
+	result := &SuiteResult{Suite: suite}
+	start := time.Now()
+
+	for _, c := range suite.Cases {
+		at := c.Request.Time
+		if at.IsZero() {
+			at = now
+		}
+
+		caseStart := time.Now()
+		decision := policy.Evaluate(pol, policy.Request{
+			Principal:     c.Principal,
+			Resource:      c.Resource,
+			Permission:    c.Permission,
+			Time:          at,
+			APIAttributes: c.Request.API,
+			Delegates:     c.Delegates,
+		})
+
+		result.Results = append(result.Results, Result{
+			Case:     c,
+			Decision: decision,
+			Duration: time.Since(caseStart),
+		})
+	}
+
+	result.Duration = time.Since(start)
+	return result
+}
@@ -0,0 +1,138 @@
+// Package policytest runs policy-as-code test suites: YAML files listing
+// permission checks and their expected outcome, evaluated locally against
+// a policy with policy.Evaluate.
+//
+//	policy: ../policy.yaml   # optional, relative to this file
+//	tests:
+//	  - name: developers can read secrets
+//	    principal: user:alice@example.com
+//	    resource: projects/test-project/secrets/db-password
+//	    permission: secretmanager.versions.access
+//	    expect: allow
+//	  - name: CI is limited to prod secrets
+//	    principal: serviceAccount:ci@test-project.iam.gserviceaccount.com
+//	    resource: projects/test-project/secrets/db-password
+//	    permission: secretmanager.versions.access
+//	    expect: deny
+//	    request:
+//	      time: 2026-01-01T00:00:00Z
+//	      api:
+//	        iam.googleapis.com/modifiedGrantsByRole: [roles/viewer]
+//	  - name: CI can act as the deployer
+//	    principal: serviceAccount:ci@test-project.iam.gserviceaccount.com
+//	    delegates: [deployer@test-project.iam.gserviceaccount.com]
+//	    resource: projects/test-project/secrets/prod-db
+//	    permission: secretmanager.versions.access
+//	    expect: allow
+package policytest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Expectations
+const (
+	ExpectAllow = "allow"
+	ExpectDeny  = "deny"
+)
+
+// Suite is a policy test file
+type Suite struct {
+	// Path is the file the suite was loaded from
+	Path string `yaml:"-"`
+	// Policy is the policy file to test, relative to the suite file
+	Policy string `yaml:"policy,omitempty"`
+	Cases  []Case `yaml:"tests"`
+}
+
+// Case is one expected authorization decision
+type Case struct {
+	Name       string  `yaml:"name"`
+	Principal  string  `yaml:"principal"`
+	Resource   string  `yaml:"resource"`
+	Permission string  `yaml:"permission"`
+	Expect     string  `yaml:"expect"`
+	Request    Request `yaml:"request,omitempty"`
+	// Delegates is the impersonation chain from Principal (see
+	// policy.Request)
+	Delegates []string `yaml:"delegates,omitempty"`
+}
+
+// Request holds optional request attributes for conditions
+type Request struct {
+	Time time.Time `yaml:"time,omitempty"`
+	// API holds the attributes read with api.getAttribute(name, default)
+	API map[string]any `yaml:"api,omitempty"`
+}
+
+// DisplayName returns the case name, or a summary of the check if unnamed
+func (c Case) DisplayName() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return fmt.Sprintf("%s %s on %s", c.Principal, c.Permission, c.Resource)
+}
+
+// Load reads and validates a suite file
+func Load(path string) (*Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read test suite: %w", err)
+	}
+
+	suite := &Suite{Path: path}
+	if err := yaml.Unmarshal(data, suite); err != nil {
+		return nil, fmt.Errorf("%s: failed to parse test suite: %w", path, err)
+	}
+
+	if len(suite.Cases) == 0 {
+		return nil, fmt.Errorf("%s: no tests defined", path)
+	}
+
+	for i := range suite.Cases {
+		c := &suite.Cases[i]
+		c.Expect = strings.ToLower(c.Expect)
+
+		var missing []string
+		if c.Principal == "" {
+			missing = append(missing, "principal")
+		}
+		if c.Resource == "" {
+			missing = append(missing, "resource")
+		}
+		if c.Permission == "" {
+			missing = append(missing, "permission")
+		}
+		if len(missing) > 0 {
+			return nil, fmt.Errorf("%s: test %d (%s): missing %s", path, i+1, c.DisplayName(), strings.Join(missing, ", "))
+		}
+
+		if c.Expect != ExpectAllow && c.Expect != ExpectDeny {
+			return nil, fmt.Errorf("%s: test %d (%s): expect must be allow or deny, got %q", path, i+1, c.DisplayName(), c.Expect)
+		}
+	}
+
+	return suite, nil
+}
+
+// PolicyPath returns the suite's policy file resolved against the suite's
+// directory, or "" if the suite does not name one
+func (s *Suite) PolicyPath() string {
+	if s.Policy == "" || filepath.IsAbs(s.Policy) {
+		return s.Policy
+	}
+	return filepath.Join(filepath.Dir(s.Path), s.Policy)
+}
+
+// Name returns the suite name used in reports (the file name without
+// extension)
+func (s *Suite) Name() string {
+	base := filepath.Base(s.Path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
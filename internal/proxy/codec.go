@@ -0,0 +1,34 @@
+// Package proxy forwards gRPC and HTTP traffic to an emulator without
+// knowing the APIs it carries. Callers hook in to rewrite or reject
+// requests (a Director) and to observe forwarded requests (Observe).
+package proxy
+
+import "fmt"
+
+// RawCodec passes serialized messages through untouched. Messages must be
+// *[]byte.
+type RawCodec struct{}
+
+// Marshal returns the bytes held by v
+func (RawCodec) Marshal(v any) ([]byte, error) {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return nil, fmt.Errorf("raw codec: unexpected message type %T", v)
+	}
+	return *b, nil
+}
+
+// Unmarshal copies data into v
+func (RawCodec) Unmarshal(data []byte, v any) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("raw codec: unexpected message type %T", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+// Name is "proto" so requests keep the application/grpc+proto content type
+func (RawCodec) Name() string {
+	return "proto"
+}
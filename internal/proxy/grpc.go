@@ -0,0 +1,100 @@
+package proxy
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Call is a forwarded unary gRPC call
+type Call struct {
+	Start  time.Time
+	Method string
+	// Metadata is what was sent upstream, after the director ran
+	Metadata metadata.MD
+	Request  []byte
+	// Err is the upstream error, or the director's if it rejected the call
+	Err error
+}
+
+// GRPCOptions hooks into a gRPC proxy
+type GRPCOptions struct {
+	// Director may rewrite the metadata sent upstream or reject the call by
+	// returning an error (which should be a gRPC status error). It receives
+	// the client's metadata minus transport headers.
+	Director func(ctx context.Context, method string, md metadata.MD) (metadata.MD, error)
+	// Observe is called after every call, including rejected ones
+	Observe func(Call)
+}
+
+// NewGRPC returns a gRPC server that forwards every unary call to target.
+// Metadata is forwarded both ways.
+func NewGRPC(target *grpc.ClientConn, opts GRPCOptions) *grpc.Server {
+	handler := func(_ any, stream grpc.ServerStream) error {
+		method, ok := grpc.MethodFromServerStream(stream)
+		if !ok {
+			return status.Error(codes.Internal, "proxy: missing method")
+		}
+
+		var req []byte
+		if err := stream.RecvMsg(&req); err != nil {
+			return err
+		}
+
+		call := Call{Start: time.Now(), Method: method, Request: req}
+		defer func() {
+			if opts.Observe != nil {
+				opts.Observe(call)
+			}
+		}()
+
+		in, _ := metadata.FromIncomingContext(stream.Context())
+		md := forwardedMetadata(in)
+		if opts.Director != nil {
+			var err error
+			if md, err = opts.Director(stream.Context(), method, md); err != nil {
+				call.Err = err
+				return err
+			}
+		}
+		call.Metadata = md
+
+		var resp []byte
+		var header, trailer metadata.MD
+		ctx := metadata.NewOutgoingContext(stream.Context(), md)
+		call.Err = target.Invoke(ctx, method, &req, &resp,
+			grpc.ForceCodec(RawCodec{}), grpc.Header(&header), grpc.Trailer(&trailer))
+
+		if len(header) > 0 {
+			_ = stream.SetHeader(header)
+		}
+		stream.SetTrailer(trailer)
+		if call.Err != nil {
+			return call.Err
+		}
+		return stream.SendMsg(&resp)
+	}
+
+	return grpc.NewServer(grpc.ForceServerCodec(RawCodec{}), grpc.UnknownServiceHandler(handler))
+}
+
+// forwardedMetadata drops transport headers the client connection sets itself
+func forwardedMetadata(in metadata.MD) metadata.MD {
+	out := metadata.MD{}
+	for key, values := range in {
+		if strings.HasPrefix(key, ":") || strings.HasPrefix(key, "grpc-") {
+			continue
+		}
+		switch key {
+		case "content-type", "user-agent", "te":
+			continue
+		}
+		out[key] = values
+	}
+	return out
+}
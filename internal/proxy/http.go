@@ -0,0 +1,126 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Exchange is a forwarded HTTP request and its response status
+type Exchange struct {
+	Start time.Time
+	// Request is the request as sent upstream, after the director ran
+	Request *http.Request
+	Body    []byte
+	Status  int
+}
+
+// HTTPOptions hooks into an HTTP proxy
+type HTTPOptions struct {
+	// Director may modify the request before it is forwarded, or reject it
+	// by returning an error. A gRPC status error picks the HTTP status
+	// (UNAUTHENTICATED is 401, PERMISSION_DENIED 403, anything else 400).
+	Director func(r *http.Request) error
+	// Observe is called after every request, including rejected ones
+	Observe func(Exchange)
+}
+
+// NewHTTP returns a reverse proxy to target
+func NewHTTP(target *url.URL, opts HTTPOptions) http.Handler {
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(target)
+		},
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "proxy: failed to read request body", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		exchange := Exchange{Start: time.Now(), Request: r, Body: body}
+		defer func() {
+			if opts.Observe != nil {
+				opts.Observe(exchange)
+			}
+		}()
+
+		if opts.Director != nil {
+			if err := opts.Director(r); err != nil {
+				exchange.Status = writeError(w, err)
+				return
+			}
+		}
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		proxy.ServeHTTP(sw, r)
+		exchange.Status = sw.status
+	})
+}
+
+// writeError writes a Google API style JSON error and returns its status
+func writeError(w http.ResponseWriter, err error) int {
+	st := status.Convert(err)
+
+	code := http.StatusBadRequest
+	switch st.Code() {
+	case codes.Unauthenticated:
+		code = http.StatusUnauthorized
+	case codes.PermissionDenied:
+		code = http.StatusForbidden
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]any{
+			"code":    code,
+			"message": st.Message(),
+			"status":  statusName(st.Code()),
+		},
+	})
+
+	return code
+}
+
+// statusName converts a code to the Google API status name, e.g.
+// PermissionDenied to PERMISSION_DENIED
+func statusName(code codes.Code) string {
+	var out strings.Builder
+	for i, r := range code.String() {
+		if i > 0 && unicode.IsUpper(r) {
+			out.WriteByte('_')
+		}
+		out.WriteRune(unicode.ToUpper(r))
+	}
+	return out.String()
+}
+
+// statusWriter remembers the response status code
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
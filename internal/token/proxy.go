@@ -0,0 +1,123 @@
+package token
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/emulator/authz"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/proxy"
+)
+
+// ProxyOptions configures an authenticating proxy
+type ProxyOptions struct {
+	// RequireToken rejects requests without a bearer token. By default they
+	// are forwarded unchanged, so an explicit principal header still works.
+	RequireToken bool
+	// Now is the clock used to check expiry (default time.Now)
+	Now func() time.Time
+	// OnRequest, if set, is called for every request the proxy handles
+	OnRequest func(ProxyRequest)
+}
+
+// ProxyRequest describes how one proxied request was authenticated
+type ProxyRequest struct {
+	Service string
+	Method  string
+	// Principal is the principal injected from the token, if any
+	Principal string
+	// Source is the token source, or "" if the request had no token
+	Source string
+	// Err is set if the request was rejected
+	Err error
+}
+
+func (o ProxyOptions) now() time.Time {
+	if o.Now != nil {
+		return o.Now()
+	}
+	return time.Now()
+}
+
+// authenticate maps an Authorization value to a principal. It returns a
+// nil Claims with no error if there is no token and none is required.
+func (o ProxyOptions) authenticate(authorization string) (*Claims, error) {
+	bearer, ok := FromAuthorization(authorization)
+	if !ok {
+		if o.RequireToken {
+			return nil, status.Error(codes.Unauthenticated, "request is missing a bearer token")
+		}
+		return nil, nil
+	}
+
+	claims, err := Parse(bearer, o.now())
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return claims, nil
+}
+
+// NewGRPCProxy returns a gRPC server that forwards to target, replacing
+// x-emulator-principal with the principal of the caller's bearer token.
+// Health checks are forwarded without authentication.
+func NewGRPCProxy(service string, target *grpc.ClientConn, opts ProxyOptions) *grpc.Server {
+	director := func(_ context.Context, method string, md metadata.MD) (metadata.MD, error) {
+		if strings.HasPrefix(method, "/"+healthpb.Health_ServiceDesc.ServiceName+"/") {
+			return md, nil
+		}
+
+		authorization := ""
+		if values := md.Get("authorization"); len(values) > 0 {
+			authorization = values[0]
+		}
+
+		claims, err := opts.authenticate(authorization)
+		report := ProxyRequest{Service: service, Method: method, Err: err}
+		if claims != nil {
+			md.Set(authz.PrincipalMetadataKey, claims.Principal)
+			report.Principal = claims.Principal
+			report.Source = claims.Source
+		}
+		if opts.OnRequest != nil {
+			opts.OnRequest(report)
+		}
+
+		return md, err
+	}
+
+	return proxy.NewGRPC(target, proxy.GRPCOptions{Director: director})
+}
+
+// NewHTTPProxy returns a reverse proxy to target that replaces the
+// X-Emulator-Principal header with the principal of the caller's bearer
+// token. The /health endpoint is forwarded without authentication.
+func NewHTTPProxy(service string, target *url.URL, opts ProxyOptions) http.Handler {
+	director := func(r *http.Request) error {
+		if r.URL.Path == "/health" {
+			return nil
+		}
+
+		claims, err := opts.authenticate(r.Header.Get("Authorization"))
+		report := ProxyRequest{Service: service, Method: r.Method + " " + r.URL.Path, Err: err}
+		if claims != nil {
+			r.Header.Set(authz.PrincipalHeader, claims.Principal)
+			report.Principal = claims.Principal
+			report.Source = claims.Source
+		}
+		if opts.OnRequest != nil {
+			opts.OnRequest(report)
+		}
+
+		return err
+	}
+
+	return proxy.NewHTTP(target, proxy.HTTPOptions{Director: director})
+}
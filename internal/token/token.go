@@ -0,0 +1,224 @@
+// Package token mints and parses the bearer tokens the emulator accepts in
+// place of Google OAuth credentials, and maps them to IAM principals.
+//
+// Two kinds of token are understood:
+//
+//   - Emulator access tokens, minted by Mint. They look like Google access
+//     tokens (ya29.emulator.<payload>) so client libraries pass them through
+//     unchanged; the payload is base64url JSON naming the principal.
+//   - JWTs, such as ID tokens. Signatures are NOT verified: the emulator is
+//     a test tool, and unsigned tokens (alg "none") are the common case.
+//     The principal comes from the "principal" claim, else "email", else a
+//     "sub" that is already a principal.
+package token
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+// Prefix starts every emulator access token
+const Prefix = "ya29.emulator."
+
+// Token sources
+const (
+	SourceEmulator = "emulator"
+	SourceJWT      = "jwt"
+)
+
+// ErrExpired is returned for tokens past their expiry
+var ErrExpired = errors.New("token expired")
+
+// Claims is what a token says about its bearer
+type Claims struct {
+	Principal string
+	// Expiry is zero for tokens that do not expire
+	Expiry time.Time
+	Source string
+}
+
+type emulatorPayload struct {
+	Principal string `json:"principal"`
+	IssuedAt  int64  `json:"iat"`
+	Expiry    int64  `json:"exp,omitempty"`
+}
+
+// Mint returns an emulator access token for principal, valid for ttl from
+// now (no expiry if ttl is zero)
+func Mint(principal string, ttl time.Duration, now time.Time) (string, error) {
+	if err := validatePrincipal(principal); err != nil {
+		return "", err
+	}
+
+	payload := emulatorPayload{Principal: principal, IssuedAt: now.Unix()}
+	if ttl > 0 {
+		payload.Expiry = now.Add(ttl).Unix()
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode token: %w", err)
+	}
+
+	return Prefix + base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// IDToken returns an unsigned (alg "none") OpenID Connect ID token for
+// email and audience, valid for ttl from now. Parse maps it back to the
+// principal for email.
+func IDToken(email, audience string, ttl time.Duration, now time.Time) (string, error) {
+	if err := validatePrincipal(PrincipalForEmail(email)); err != nil {
+		return "", err
+	}
+	if audience == "" {
+		return "", errors.New("ID token audience is required")
+	}
+
+	header, err := json.Marshal(map[string]string{"alg": "none", "typ": "JWT"})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode token: %w", err)
+	}
+	payload, err := json.Marshal(map[string]any{
+		"iss":            "https://accounts.google.com",
+		"aud":            audience,
+		"azp":            email,
+		"sub":            email,
+		"email":          email,
+		"email_verified": true,
+		"iat":            now.Unix(),
+		"exp":            now.Add(ttl).Unix(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode token: %w", err)
+	}
+
+	enc := base64.RawURLEncoding.EncodeToString
+	return enc(header) + "." + enc(payload) + ".", nil
+}
+
+// Parse validates a bearer token and returns its claims
+func Parse(token string, now time.Time) (*Claims, error) {
+	var claims *Claims
+	var err error
+
+	switch {
+	case strings.HasPrefix(token, Prefix):
+		claims, err = parseEmulator(strings.TrimPrefix(token, Prefix))
+	case strings.Count(token, ".") == 2:
+		claims, err = parseJWT(token)
+	default:
+		return nil, errors.New("unrecognized token (expected an emulator access token or a JWT)")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !claims.Expiry.IsZero() && now.After(claims.Expiry) {
+		return nil, fmt.Errorf("%w at %s", ErrExpired, claims.Expiry.UTC().Format(time.RFC3339))
+	}
+
+	return claims, nil
+}
+
+// FromAuthorization extracts the token from an Authorization header value
+// ("Bearer <token>"). It returns false if there is no bearer token.
+func FromAuthorization(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+func parseEmulator(encoded string) (*Claims, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid emulator token: %w", err)
+	}
+
+	var payload emulatorPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, fmt.Errorf("invalid emulator token: %w", err)
+	}
+	if err := validatePrincipal(payload.Principal); err != nil {
+		return nil, fmt.Errorf("invalid emulator token: %w", err)
+	}
+
+	claims := &Claims{Principal: payload.Principal, Source: SourceEmulator}
+	if payload.Expiry != 0 {
+		claims.Expiry = time.Unix(payload.Expiry, 0)
+	}
+
+	return claims, nil
+}
+
+func parseJWT(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT payload: %w", err)
+	}
+
+	var payload struct {
+		Principal string  `json:"principal"`
+		Email     string  `json:"email"`
+		Subject   string  `json:"sub"`
+		Expiry    float64 `json:"exp"`
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, fmt.Errorf("invalid JWT payload: %w", err)
+	}
+
+	principal := payload.Principal
+	switch {
+	case principal != "":
+	case payload.Email != "":
+		principal = PrincipalForEmail(payload.Email)
+	case validatePrincipal(payload.Subject) == nil:
+		principal = payload.Subject
+	default:
+		return nil, errors.New("JWT has no principal, email or principal-shaped sub claim")
+	}
+	if err := validatePrincipal(principal); err != nil {
+		return nil, fmt.Errorf("invalid JWT: %w", err)
+	}
+
+	claims := &Claims{Principal: principal, Source: SourceJWT}
+	if payload.Expiry != 0 {
+		claims.Expiry = time.Unix(int64(payload.Expiry), 0)
+	}
+
+	return claims, nil
+}
+
+// PrincipalForEmail maps an email to a principal: service account emails
+// become serviceAccount:, anything else user:
+func PrincipalForEmail(email string) string {
+	if strings.HasSuffix(email, ".gserviceaccount.com") {
+		return "serviceAccount:" + email
+	}
+	return "user:" + email
+}
+
+// validatePrincipal accepts the principal types that can hold a token,
+// including federated workload identities
+func validatePrincipal(principal string) error {
+	if p, err := policy.ParsePrincipal(principal); err == nil && p.Type == "principal" {
+		return nil
+	}
+
+	kind, id, ok := strings.Cut(principal, ":")
+	if !ok || id == "" || (kind != "user" && kind != "serviceAccount") {
+		return fmt.Errorf("invalid principal %q (must be user:<email>, serviceAccount:<email> or principal://...)", principal)
+	}
+	return nil
+}
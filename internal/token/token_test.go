@@ -0,0 +1,211 @@
+package token
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/native"
+)
+
+func jwt(payload string) string {
+	enc := base64.RawURLEncoding.EncodeToString
+	return enc([]byte(`{"alg":"none","typ":"JWT"}`)) + "." + enc([]byte(payload)) + "."
+}
+
+func TestMintParse(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tok, err := Mint("serviceAccount:ci@test-project.iam.gserviceaccount.com", time.Hour, now)
+	if err != nil {
+		t.Fatalf("Mint() error = %v", err)
+	}
+
+	claims, err := Parse(tok, now.Add(30*time.Minute))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if claims.Principal != "serviceAccount:ci@test-project.iam.gserviceaccount.com" || claims.Source != SourceEmulator {
+		t.Errorf("claims = %+v", claims)
+	}
+
+	if _, err := Parse(tok, now.Add(2*time.Hour)); !errors.Is(err, ErrExpired) {
+		t.Errorf("Parse() of expired token error = %v, want ErrExpired", err)
+	}
+
+	if _, err := Mint("group:devs@example.com", 0, now); err == nil {
+		t.Error("Expected error minting a token for a group")
+	}
+
+	// Federated workloads hold tokens too
+	const workload = "principal://iam.googleapis.com/projects/123456/locations/global/workloadIdentityPools/github/subject/repo:acme/api:ref:refs/heads/main"
+	tok, err = Mint(workload, 0, now)
+	if err != nil {
+		t.Fatalf("Mint() error = %v", err)
+	}
+	if claims, err := Parse(tok, now); err != nil || claims.Principal != workload {
+		t.Errorf("Parse() = %+v, %v", claims, err)
+	}
+}
+
+func TestParseJWT(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		token   string
+		want    string
+		wantErr bool
+	}{
+		{"principal claim", jwt(`{"principal":"user:alice@example.com"}`), "user:alice@example.com", false},
+		{"user email", jwt(`{"email":"alice@example.com","exp":1893456000}`), "user:alice@example.com", false},
+		{"service account email", jwt(`{"email":"ci@p.iam.gserviceaccount.com"}`), "serviceAccount:ci@p.iam.gserviceaccount.com", false},
+		{"principal sub", jwt(`{"sub":"user:bob@example.com"}`), "user:bob@example.com", false},
+		{"numeric sub", jwt(`{"sub":"1234567890"}`), "", true},
+		{"expired", jwt(`{"email":"alice@example.com","exp":1000}`), "", true},
+		{"garbage", "not-a-token", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := Parse(tt.token, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (claims.Principal != tt.want || claims.Source != SourceJWT) {
+				t.Errorf("claims = %+v, want principal %s", claims, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromAuthorization(t *testing.T) {
+	if tok, ok := FromAuthorization("Bearer abc"); !ok || tok != "abc" {
+		t.Errorf("FromAuthorization(Bearer) = %q, %v", tok, ok)
+	}
+	if tok, ok := FromAuthorization("bearer  abc "); !ok || tok != "abc" {
+		t.Errorf("FromAuthorization(lowercase) = %q, %v", tok, ok)
+	}
+	for _, header := range []string{"", "Basic abc", "Bearer"} {
+		if _, ok := FromAuthorization(header); ok {
+			t.Errorf("FromAuthorization(%q) should have no token", header)
+		}
+	}
+}
+
+func TestGRPCProxy(t *testing.T) {
+	stack, err := native.Start(native.Options{PolicyFile: "../../policy.yaml", IAMMode: "strict"})
+	if err != nil {
+		t.Fatalf("Failed to start stack: %v", err)
+	}
+	t.Cleanup(stack.Stop)
+
+	dial := func(addr string) *grpc.ClientConn {
+		conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+
+	start := func(opts ProxyOptions) secretmanagerpb.SecretManagerServiceClient {
+		srv := NewGRPCProxy("secret-manager", dial(stack.Endpoints().SecretManager), opts)
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		go srv.Serve(lis)
+		t.Cleanup(srv.Stop)
+		return secretmanagerpb.NewSecretManagerServiceClient(dial(lis.Addr().String()))
+	}
+
+	withToken := func(tok string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+tok)
+	}
+	create := func(client secretmanagerpb.SecretManagerServiceClient, ctx context.Context, id string) error {
+		_, err := client.CreateSecret(ctx, &secretmanagerpb.CreateSecretRequest{
+			Parent:   "projects/test-project",
+			SecretId: id,
+			Secret:   &secretmanagerpb.Secret{},
+		})
+		return err
+	}
+
+	var seen []ProxyRequest
+	client := start(ProxyOptions{OnRequest: func(r ProxyRequest) { seen = append(seen, r) }})
+
+	admin, _ := Mint("user:admin@example.com", time.Hour, time.Now())
+	if err := create(client, withToken(admin), "from-token"); err != nil {
+		t.Fatalf("CreateSecret with admin token failed: %v", err)
+	}
+	if len(seen) != 1 || seen[0].Principal != "user:admin@example.com" || seen[0].Source != SourceEmulator {
+		t.Errorf("OnRequest saw %+v", seen)
+	}
+
+	// The token wins over a principal header the client set itself
+	mallory, _ := Mint("user:mallory@example.com", time.Hour, time.Now())
+	spoofed := metadata.AppendToOutgoingContext(withToken(mallory), "x-emulator-principal", "user:admin@example.com")
+	if err := create(client, spoofed, "spoofed"); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied for mallory's token, got %v", err)
+	}
+
+	if err := create(client, withToken("garbage"), "bad"); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated for invalid token, got %v", err)
+	}
+
+	// Without a token the request is forwarded as-is (and strict mode denies it)
+	if err := create(client, context.Background(), "anonymous"); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied without a token, got %v", err)
+	}
+
+	strict := start(ProxyOptions{RequireToken: true})
+	if err := create(strict, context.Background(), "anonymous"); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated with RequireToken, got %v", err)
+	}
+}
+
+func TestHTTPProxy(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Seen-Principal", r.Header.Get("X-Emulator-Principal"))
+	}))
+	defer upstream.Close()
+
+	target, _ := url.Parse(upstream.URL)
+	proxy := httptest.NewServer(NewHTTPProxy("kms", target, ProxyOptions{RequireToken: true}))
+	defer proxy.Close()
+
+	do := func(authorization string) *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, proxy.URL+"/v1/projects/p/locations/global/keyRings", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := do("Bearer " + jwt(`{"email":"ci@p.iam.gserviceaccount.com"}`))
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("X-Seen-Principal") != "serviceAccount:ci@p.iam.gserviceaccount.com" {
+		t.Errorf("status %d, principal %q", resp.StatusCode, resp.Header.Get("X-Seen-Principal"))
+	}
+
+	if resp := do(""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a token, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,326 @@
+// Package native runs the emulator stack in-process, without Docker.
+//
+// It starts an IAM authorization server backed by the control plane policy
+// engine plus lightweight in-memory Secret Manager and KMS servers. The data
+// plane servers check every request against the IAM server over gRPC, using
+// the same principal propagation and off/permissive/strict semantics as the
+// containerized emulators (see docs/INTEGRATION_CONTRACT.md).
+//
+//	stack, err := native.Start(native.Options{PolicyFile: "policy.yaml", IAMMode: "strict"})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer stack.Stop()
+//
+//	conn, _ := grpc.NewClient(stack.Endpoints().SecretManager, ...)
+//
+// Secret Manager and KMS are served over gRPC only; their HTTP ports expose
+// the /health endpoint but not the REST gateway. Every gRPC port also
+// serves the standard grpc.health.v1 health service.
+package native
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	iampb "cloud.google.com/go/iam/apiv1/iampb"
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/audit"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/emulator/authz"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/emulator/iam"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/emulator/kms"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/emulator/secretmanager"
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/policy"
+)
+
+// Options configures an in-process stack.
+// A zero port selects a free port, which is what tests usually want.
+type Options struct {
+	// PolicyFile is the policy to enforce (.yaml, .yml or .json)
+	PolicyFile string
+	// IAMMode is off, permissive or strict (default permissive)
+	IAMMode string
+	// Host is the interface to listen on (default 127.0.0.1)
+	Host string
+
+	IAMPort               int
+	IAMHealthPort         int
+	SecretManagerPort     int
+	SecretManagerHTTPPort int
+	KMSPort               int
+	KMSHTTPPort           int
+
+	// Logger receives one record per authorization decision and server
+	// lifecycle events. Nil discards logs.
+	Logger *slog.Logger
+
+	// AuditFile, if set, receives every IAM decision as a JSONL audit
+	// event (appended; see internal/audit)
+	AuditFile string
+}
+
+// Endpoints are the host:port addresses the stack is listening on
+type Endpoints struct {
+	IAM               string
+	IAMHealth         string
+	SecretManager     string
+	SecretManagerHTTP string
+	KMS               string
+	KMSHTTP           string
+}
+
+// Stack is a running in-process emulator stack
+type Stack struct {
+	opts      Options
+	endpoints Endpoints
+	iam       *iam.Server
+	iamConn   *grpc.ClientConn
+	audit     *audit.Writer
+
+	grpcServers   []*grpc.Server
+	healthServers []*grpchealth.Server
+	httpServers   []*http.Server
+
+	errs     chan error
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// Start loads and validates the policy, then starts all servers.
+// Servers are listening when Start returns.
+func Start(opts Options) (*Stack, error) {
+	if opts.IAMMode == "" {
+		opts.IAMMode = authz.ModePermissive
+	}
+	if opts.IAMMode != authz.ModeOff && opts.IAMMode != authz.ModePermissive && opts.IAMMode != authz.ModeStrict {
+		return nil, fmt.Errorf("invalid iam-mode: %s (must be off, permissive, or strict)", opts.IAMMode)
+	}
+	if opts.Host == "" {
+		opts.Host = "127.0.0.1"
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.New(slog.DiscardHandler)
+	}
+
+	pol, err := loadPolicy(opts.PolicyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Stack{
+		opts: opts,
+		iam:  iam.NewServer(pol, opts.Logger.With("service", "iam")),
+		errs: make(chan error, 6),
+		done: make(chan struct{}),
+	}
+
+	if opts.AuditFile != "" {
+		if s.audit, err = audit.NewWriter(opts.AuditFile); err != nil {
+			return nil, err
+		}
+		s.iam.SetAuditLog(s.audit)
+	}
+
+	if err := s.start(); err != nil {
+		s.Stop()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *Stack) start() error {
+	var err error
+
+	// IAM (control plane)
+	iamGRPC := grpc.NewServer()
+	iampb.RegisterIAMPolicyServer(iamGRPC, s.iam)
+	if s.endpoints.IAM, err = s.serveGRPC(iamGRPC, s.opts.IAMPort); err != nil {
+		return fmt.Errorf("iam: %w", err)
+	}
+	if s.endpoints.IAMHealth, err = s.serveHealth(s.opts.IAMHealthPort); err != nil {
+		return fmt.Errorf("iam health: %w", err)
+	}
+
+	// Data planes reach IAM over gRPC, exactly like the containers do
+	s.iamConn, err = grpc.NewClient(s.endpoints.IAM, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return fmt.Errorf("failed to connect data plane to IAM: %w", err)
+	}
+	iamClient := iampb.NewIAMPolicyClient(s.iamConn)
+
+	// Secret Manager
+	smGRPC := grpc.NewServer()
+	secretmanagerpb.RegisterSecretManagerServiceServer(smGRPC, secretmanager.NewServer(&authz.Checker{
+		Mode:   s.opts.IAMMode,
+		Client: iamClient,
+		Caller: "secret-manager",
+	}))
+	if s.endpoints.SecretManager, err = s.serveGRPC(smGRPC, s.opts.SecretManagerPort); err != nil {
+		return fmt.Errorf("secret manager: %w", err)
+	}
+	if s.endpoints.SecretManagerHTTP, err = s.serveHealth(s.opts.SecretManagerHTTPPort); err != nil {
+		return fmt.Errorf("secret manager http: %w", err)
+	}
+
+	// KMS
+	kmsGRPC := grpc.NewServer()
+	kmspb.RegisterKeyManagementServiceServer(kmsGRPC, kms.NewServer(&authz.Checker{
+		Mode:   s.opts.IAMMode,
+		Client: iamClient,
+		Caller: "kms",
+	}))
+	if s.endpoints.KMS, err = s.serveGRPC(kmsGRPC, s.opts.KMSPort); err != nil {
+		return fmt.Errorf("kms: %w", err)
+	}
+	if s.endpoints.KMSHTTP, err = s.serveHealth(s.opts.KMSHTTPPort); err != nil {
+		return fmt.Errorf("kms http: %w", err)
+	}
+
+	s.opts.Logger.Info("stack started",
+		"iam_mode", s.opts.IAMMode,
+		"iam", s.endpoints.IAM,
+		"secret_manager", s.endpoints.SecretManager,
+		"kms", s.endpoints.KMS,
+	)
+
+	return nil
+}
+
+func (s *Stack) listen(port int) (net.Listener, error) {
+	return net.Listen("tcp", net.JoinHostPort(s.opts.Host, strconv.Itoa(port)))
+}
+
+// serveGRPC also registers the standard grpc.health.v1 service, so
+// clients can probe the gRPC port itself rather than the HTTP /health
+func (s *Stack) serveGRPC(srv *grpc.Server, port int) (string, error) {
+	lis, err := s.listen(port)
+	if err != nil {
+		return "", err
+	}
+
+	hs := grpchealth.NewServer()
+	healthpb.RegisterHealthServer(srv, hs)
+	s.healthServers = append(s.healthServers, hs)
+	s.grpcServers = append(s.grpcServers, srv)
+
+	go func() {
+		if err := srv.Serve(lis); err != nil {
+			s.errs <- err
+		}
+	}()
+
+	return lis.Addr().String(), nil
+}
+
+func (s *Stack) serveHealth(port int) (string, error) {
+	lis, err := s.listen(port)
+	if err != nil {
+		return "", err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"status":"ok"}`)
+	})
+
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	s.httpServers = append(s.httpServers, srv)
+
+	go func() {
+		if err := srv.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.errs <- err
+		}
+	}()
+
+	return lis.Addr().String(), nil
+}
+
+// Endpoints returns the addresses the stack is listening on
+func (s *Stack) Endpoints() Endpoints {
+	return s.endpoints
+}
+
+// ReloadPolicy re-reads and validates the policy file. The running policy
+// is left unchanged if the new file is invalid.
+func (s *Stack) ReloadPolicy() error {
+	pol, err := loadPolicy(s.opts.PolicyFile)
+	if err != nil {
+		return err
+	}
+
+	s.iam.SetPolicy(pol)
+	s.opts.Logger.Info("policy reloaded", "file", s.opts.PolicyFile)
+
+	return nil
+}
+
+// Wait blocks until Stop is called or a server fails
+func (s *Stack) Wait() error {
+	select {
+	case <-s.done:
+		return nil
+	case err := <-s.errs:
+		return err
+	}
+}
+
+// Stop gracefully shuts down all servers
+func (s *Stack) Stop() {
+	s.stopOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		// Report NOT_SERVING to health watchers before draining
+		for _, hs := range s.healthServers {
+			hs.Shutdown()
+		}
+		for _, srv := range s.httpServers {
+			_ = srv.Shutdown(ctx)
+		}
+		for _, srv := range s.grpcServers {
+			srv.GracefulStop()
+		}
+		if s.iamConn != nil {
+			_ = s.iamConn.Close()
+		}
+		if s.audit != nil {
+			_ = s.audit.Close()
+		}
+
+		s.opts.Logger.Info("stack stopped")
+		close(s.done)
+	})
+}
+
+func loadPolicy(path string) (*policy.Policy, error) {
+	if path == "" {
+		return nil, fmt.Errorf("policy file is required")
+	}
+
+	pol, err := policy.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	result := policy.Validate(pol)
+	if !result.Valid {
+		return nil, fmt.Errorf("policy validation failed: %v", result.Errors)
+	}
+
+	return pol, nil
+}
@@ -0,0 +1,205 @@
+package native
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/blackwell-systems/gcp-iam-control-plane/internal/audit"
+)
+
+func startStack(t *testing.T, mode string) *Stack {
+	t.Helper()
+
+	stack, err := Start(Options{PolicyFile: "../policy.yaml", IAMMode: mode})
+	if err != nil {
+		t.Fatalf("Failed to start stack: %v", err)
+	}
+	t.Cleanup(stack.Stop)
+
+	return stack
+}
+
+func dial(t *testing.T, addr string) *grpc.ClientConn {
+	t.Helper()
+
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to connect to %s: %v", addr, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func as(principal string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "x-emulator-principal", principal)
+}
+
+func TestSecretManagerStrict(t *testing.T) {
+	stack := startStack(t, "strict")
+	client := secretmanagerpb.NewSecretManagerServiceClient(dial(t, stack.Endpoints().SecretManager))
+
+	admin := as("user:admin@example.com")
+
+	secret, err := client.CreateSecret(admin, &secretmanagerpb.CreateSecretRequest{
+		Parent:   "projects/test-project",
+		SecretId: "db-password",
+		Secret:   &secretmanagerpb.Secret{},
+	})
+	if err != nil {
+		t.Fatalf("CreateSecret failed: %v", err)
+	}
+
+	if _, err := client.AddSecretVersion(admin, &secretmanagerpb.AddSecretVersionRequest{
+		Parent:  secret.Name,
+		Payload: &secretmanagerpb.SecretPayload{Data: []byte("hunter2")},
+	}); err != nil {
+		t.Fatalf("AddSecretVersion failed: %v", err)
+	}
+
+	// Developers (via group) can read
+	resp, err := client.AccessSecretVersion(as("user:alice@example.com"), &secretmanagerpb.AccessSecretVersionRequest{
+		Name: secret.Name + "/versions/latest",
+	})
+	if err != nil {
+		t.Fatalf("AccessSecretVersion failed: %v", err)
+	}
+	if string(resp.Payload.Data) != "hunter2" {
+		t.Errorf("Payload mismatch: got %q", resp.Payload.Data)
+	}
+
+	// Unknown principals are denied
+	_, err = client.CreateSecret(as("user:mallory@example.com"), &secretmanagerpb.CreateSecretRequest{
+		Parent:   "projects/test-project",
+		SecretId: "nope",
+		Secret:   &secretmanagerpb.Secret{},
+	})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied, got %v", err)
+	}
+
+	// Strict mode requires a principal
+	_, err = client.GetSecret(context.Background(), &secretmanagerpb.GetSecretRequest{Name: secret.Name})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied without principal, got %v", err)
+	}
+}
+
+func TestKMSRoundTrip(t *testing.T) {
+	stack := startStack(t, "strict")
+	client := kmspb.NewKeyManagementServiceClient(dial(t, stack.Endpoints().KMS))
+
+	admin := as("user:admin@example.com")
+
+	ring, err := client.CreateKeyRing(admin, &kmspb.CreateKeyRingRequest{
+		Parent:    "projects/test-project/locations/global",
+		KeyRingId: "ring",
+	})
+	if err != nil {
+		t.Fatalf("CreateKeyRing failed: %v", err)
+	}
+
+	key, err := client.CreateCryptoKey(admin, &kmspb.CreateCryptoKeyRequest{
+		Parent:      ring.Name,
+		CryptoKeyId: "key",
+		CryptoKey:   &kmspb.CryptoKey{Purpose: kmspb.CryptoKey_ENCRYPT_DECRYPT},
+	})
+	if err != nil {
+		t.Fatalf("CreateCryptoKey failed: %v", err)
+	}
+
+	enc, err := client.Encrypt(admin, &kmspb.EncryptRequest{Name: key.Name, Plaintext: []byte("sensitive")})
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+
+	dec, err := client.Decrypt(admin, &kmspb.DecryptRequest{Name: key.Name, Ciphertext: enc.Ciphertext})
+	if err != nil {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+	if string(dec.Plaintext) != "sensitive" {
+		t.Errorf("Plaintext mismatch: got %q", dec.Plaintext)
+	}
+
+	_, err = client.Encrypt(as("user:mallory@example.com"), &kmspb.EncryptRequest{Name: key.Name, Plaintext: []byte("x")})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied, got %v", err)
+	}
+}
+
+func TestOffModeSkipsChecks(t *testing.T) {
+	stack := startStack(t, "off")
+	client := secretmanagerpb.NewSecretManagerServiceClient(dial(t, stack.Endpoints().SecretManager))
+
+	if _, err := client.CreateSecret(context.Background(), &secretmanagerpb.CreateSecretRequest{
+		Parent:   "projects/any-project",
+		SecretId: "s",
+		Secret:   &secretmanagerpb.Secret{},
+	}); err != nil {
+		t.Fatalf("CreateSecret in off mode failed: %v", err)
+	}
+}
+
+func TestStartRejectsInvalidMode(t *testing.T) {
+	if _, err := Start(Options{PolicyFile: "../policy.yaml", IAMMode: "bogus"}); err == nil {
+		t.Error("Expected error for invalid mode")
+	}
+}
+
+func TestGRPCHealthService(t *testing.T) {
+	stack := startStack(t, "strict")
+
+	for _, addr := range []string{stack.Endpoints().IAM, stack.Endpoints().SecretManager, stack.Endpoints().KMS} {
+		resp, err := healthpb.NewHealthClient(dial(t, addr)).Check(context.Background(), &healthpb.HealthCheckRequest{})
+		if err != nil {
+			t.Fatalf("Health check on %s failed: %v", addr, err)
+		}
+		if resp.Status != healthpb.HealthCheckResponse_SERVING {
+			t.Errorf("%s reported %s, want SERVING", addr, resp.Status)
+		}
+	}
+}
+
+func TestAuditFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	stack, err := Start(Options{PolicyFile: "../policy.yaml", IAMMode: "strict", AuditFile: path})
+	if err != nil {
+		t.Fatalf("Failed to start stack: %v", err)
+	}
+	client := secretmanagerpb.NewSecretManagerServiceClient(dial(t, stack.Endpoints().SecretManager))
+
+	_, err = client.GetSecret(as("user:mallory@example.com"), &secretmanagerpb.GetSecretRequest{
+		Name: "projects/test-project/secrets/missing",
+	})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("Expected PermissionDenied, got %v", err)
+	}
+	stack.Stop()
+
+	events, err := audit.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read audit log: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("got %d audit events, want 1", len(events))
+	}
+
+	e := events[0]
+	if e.Allowed() || e.Actor.Principal != "user:mallory@example.com" || e.Action.Permission != "secretmanager.secrets.get" {
+		t.Errorf("Unexpected audit event: %+v", e)
+	}
+	if e.Environment.Caller != "secret-manager" {
+		t.Errorf("Caller = %q, want secret-manager", e.Environment.Caller)
+	}
+}
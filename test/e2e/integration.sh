@@ -80,10 +80,12 @@ fi
 
 # Wait for services to be ready
 log "Waiting for services to be ready..."
-sleep 20
-
-# Note: Skipping status check since IAM may not have /health endpoint yet
-# Services should be ready after 20s wait
+if "$CLI_BINARY" wait --timeout=2m; then
+    success "Services ready"
+else
+    error "Services did not become ready"
+    exit 1
+fi
 
 # Test Secret Manager
 log ""
@@ -8,7 +8,6 @@ import (
 	"os/exec"
 	"path/filepath"
 	"testing"
-	"time"
 
 	kms "cloud.google.com/go/kms/apiv1"
 	kmspb "cloud.google.com/go/kms/apiv1/kmspb"
@@ -61,14 +60,11 @@ func TestMain(m *testing.M) {
 func TestStackLifecycle(t *testing.T) {
 	// Start stack
 	t.Log("Starting stack...")
-	startCmd := exec.Command(cliBinary, "start", "--mode=permissive")
+	startCmd := exec.Command(cliBinary, "start", "--mode=permissive", "--wait", "--timeout=2m")
 	if output, err := startCmd.CombinedOutput(); err != nil {
 		t.Fatalf("Failed to start stack: %v\n%s", err, output)
 	}
 
-	// Wait for services to be ready (without health checks, need more time)
-	time.Sleep(15 * time.Second)
-
 	// Check status
 	t.Log("Checking status...")
 	statusCmd := exec.Command(cliBinary, "status")